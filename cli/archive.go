@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/logging"
+	"github.com/bazo-blockchain/bazo-miner/miner"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"os"
+)
+
+func GetExportCommand(logger *logging.Logger) cli.Command {
+	return cli.Command {
+		Name:	"export",
+		Usage:	"export closed blocks and their txs to a chain archive",
+		Action:	func(c *cli.Context) error {
+			if len(c.String("file")) == 0 {
+				return errors.New("argument missing: file")
+			}
+
+			storage.Init(c.String("database"), "")
+			defer storage.TearDown()
+
+			lastBlock := storage.ReadLastClosedBlock()
+			if lastBlock == nil {
+				return errors.New("database does not contain any blocks")
+			}
+
+			to := lastBlock.Height
+			if c.IsSet("to") {
+				to = uint32(c.Uint("to"))
+			}
+
+			file, err := os.Create(c.String("file"))
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			count, err := storage.ExportChain(file, uint32(c.Uint("from")), to)
+			if err != nil {
+				logger.Errorf("%v", err)
+				return err
+			}
+
+			fmt.Printf("Exported %v blocks to %v.\n", count, c.String("file"))
+			return nil
+		},
+		Flags:	[]cli.Flag {
+			cli.StringFlag {
+				Name: 	"database, d",
+				Usage: 	"load database of the disk-based key/value store from `FILE`",
+				Value:	"store.db",
+			},
+			cli.UintFlag {
+				Name: 	"from",
+				Usage: 	"first block `HEIGHT` to export",
+			},
+			cli.UintFlag {
+				Name: 	"to",
+				Usage: 	"last block `HEIGHT` to export (default: last closed block)",
+			},
+			cli.StringFlag {
+				Name: 	"file",
+				Usage: 	"write the chain archive to `FILE`",
+			},
+		},
+	}
+}
+
+func GetImportCommand(logger *logging.Logger) cli.Command {
+	return cli.Command {
+		Name:	"import",
+		Usage:	"validate and import a chain archive into an empty database",
+		Action:	func(c *cli.Context) error {
+			if len(c.String("file")) == 0 {
+				return errors.New("argument missing: file")
+			}
+
+			rootPrivKey, err := crypto.ExtractECDSAKeyFromFile(c.String("rootwallet"))
+			if err != nil {
+				logger.Errorf("%v", err)
+				return err
+			}
+
+			rootCommPrivKey, err := crypto.ExtractRSAKeyFromFile(c.String("rootcommitment"))
+			if err != nil {
+				logger.Errorf("%v", err)
+				return err
+			}
+
+			file, err := os.Open(c.String("file"))
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			storage.Init(c.String("database"), "")
+			defer storage.TearDown()
+
+			count, err := miner.Import(file, &rootPrivKey.PublicKey, rootCommPrivKey)
+			if err != nil {
+				logger.Errorf("Import aborted after %v blocks: %v", count, err)
+				return err
+			}
+
+			fmt.Printf("Imported %v blocks from %v.\n", count, c.String("file"))
+			return nil
+		},
+		Flags:	[]cli.Flag {
+			cli.StringFlag {
+				Name: 	"database, d",
+				Usage: 	"import into the empty database `FILE`",
+				Value:	"store.db",
+			},
+			cli.StringFlag {
+				Name: 	"file",
+				Usage: 	"read the chain archive from `FILE`",
+			},
+			cli.StringFlag {
+				Name: 	"rootwallet",
+				Usage: 	"load root's public key from `FILE`",
+				Value: 	"wallet.txt",
+			},
+			cli.StringFlag {
+				Name: 	"rootcommitment",
+				Usage: 	"load root's RSA public-private key from `FILE`",
+				Value: 	"commitment.txt",
+			},
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/logging"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+func GetDbCommand(logger *logging.Logger) cli.Command {
+	return cli.Command {
+		Name:	"db",
+		Usage:	"database maintenance",
+		Subcommands: []cli.Command {
+			{
+				Name:	"check",
+				Usage:	"check the consistency of the chain stored in the database",
+				Action:	func(c *cli.Context) error {
+					storage.Init(c.String("database"), "")
+					defer storage.TearDown()
+
+					report, err := storage.CheckConsistency(c.Bool("repair"))
+					if err != nil {
+						logger.Errorf("%v", err)
+						return err
+					}
+
+					fmt.Printf("Checked %v blocks.\n", report.Blocks)
+					for _, problem := range report.Problems {
+						fmt.Printf("- %v\n", problem)
+					}
+					for _, repaired := range report.Repaired {
+						fmt.Printf("Repaired: %v\n", repaired)
+					}
+
+					if len(report.Problems) > len(report.Repaired) {
+						return errors.New(fmt.Sprintf("found %v problems, %v repaired", len(report.Problems), len(report.Repaired)))
+					}
+
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					cli.StringFlag {
+						Name: 	"database, d",
+						Usage: 	"load database of the disk-based key/value store from `FILE`",
+						Value:	"store.db",
+					},
+					cli.BoolFlag {
+						Name: 	"repair",
+						Usage: 	"fix all problems that can be fixed with the local data",
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/logging"
+	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"sort"
+	"time"
+)
+
+//The ban list is stored in the database, changes take effect when the miner is started the next time. Bans of a
+//running miner are managed over the RPC server.
+func GetPeersCommand(logger *logging.Logger) cli.Command {
+	databaseFlag := cli.StringFlag {
+		Name: 	"database, d",
+		Usage: 	"load database of the disk-based key/value store from `FILE`",
+		Value:	"store.db",
+	}
+	peerFlag := cli.StringFlag {
+		Name: 	"peer, p",
+		Usage: 	"node `ID` (hex encoded public node key) or IP address of the peer",
+	}
+
+	return cli.Command {
+		Name:	"peers",
+		Usage:	"manage banned peers",
+		Subcommands: []cli.Command {
+			{
+				Name:	"ban",
+				Usage:	"ban a peer",
+				Action:	func(c *cli.Context) error {
+					if len(c.String("peer")) == 0 {
+						return errors.New("argument missing: peer")
+					}
+
+					storage.Init(c.String("database"), "")
+					defer storage.TearDown()
+
+					if err := p2p.BanPeer(c.String("peer"), c.Duration("duration")); err != nil {
+						logger.Errorf("%v", err)
+						return err
+					}
+
+					fmt.Printf("Banned %v.\n", c.String("peer"))
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					databaseFlag,
+					peerFlag,
+					cli.DurationFlag {
+						Name: 	"duration",
+						Usage: 	"ban the peer for `DURATION` (e.g., 24h), the ban does not expire if not set",
+					},
+				},
+			},
+			{
+				Name:	"unban",
+				Usage:	"lift the ban of a peer",
+				Action:	func(c *cli.Context) error {
+					if len(c.String("peer")) == 0 {
+						return errors.New("argument missing: peer")
+					}
+
+					storage.Init(c.String("database"), "")
+					defer storage.TearDown()
+
+					if err := p2p.UnbanPeer(c.String("peer")); err != nil {
+						logger.Errorf("%v", err)
+						return err
+					}
+
+					fmt.Printf("Unbanned %v.\n", c.String("peer"))
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					databaseFlag,
+					peerFlag,
+				},
+			},
+			{
+				Name:	"list",
+				Usage:	"list banned peers",
+				Action:	func(c *cli.Context) error {
+					storage.Init(c.String("database"), "")
+					defer storage.TearDown()
+
+					bans := storage.ReadBannedPeers()
+					var ids []string
+					for id := range bans {
+						ids = append(ids, id)
+					}
+					sort.Strings(ids)
+
+					for _, id := range ids {
+						if bans[id] == 0 {
+							fmt.Printf("%v\tpermanent\n", id)
+						} else {
+							fmt.Printf("%v\tuntil %v\n", id, time.Unix(bans[id], 0).Format(time.RFC3339))
+						}
+					}
+					return nil
+				},
+				Flags:	[]cli.Flag {
+					databaseFlag,
+				},
+			},
+		},
+	}
+}
@@ -2,14 +2,17 @@ package cli
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/logging"
+	"github.com/bazo-blockchain/bazo-miner/metrics"
 	"github.com/bazo-blockchain/bazo-miner/miner"
 	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/rpc"
 	"github.com/bazo-blockchain/bazo-miner/storage"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
-	"log"
 )
 
 type startArgs struct {
@@ -21,9 +24,18 @@ type startArgs struct {
 	commitmentFile			string
 	rootKeyFile				string
 	rootCommitmentFile		string
+	prune					bool
+	pruneDepth				uint64
+	rpcAddress				string
+	metricsAddress			string
+	logLevel				string
+	logFormat				string
+	nodeKeyFile				string
+	allowListFile			string
+	chainID					uint
 }
 
-func GetStartCommand(logger *log.Logger) cli.Command {
+func GetStartCommand(logger *logging.Logger) cli.Command {
 	return cli.Command {
 		Name:	"start",
 		Usage:	"start the miner",
@@ -37,6 +49,15 @@ func GetStartCommand(logger *log.Logger) cli.Command {
 				commitmentFile:			c.String("commitment"),
 				rootKeyFile:			c.String("rootwallet"),
 				rootCommitmentFile: 	c.String("rootcommitment"),
+				prune:					c.Bool("prune"),
+				pruneDepth:				c.Uint64("prunedepth"),
+				rpcAddress:				c.String("rpc"),
+				metricsAddress:			c.String("metrics"),
+				logLevel:				c.String("log-level"),
+				logFormat:				c.String("log-format"),
+				nodeKeyFile:			c.String("nodekey"),
+				allowListFile:			c.String("allowlist"),
+				chainID:				c.Uint("chainid"),
 			}
 
 			if !c.IsSet("bootstrap") {
@@ -96,6 +117,46 @@ func GetStartCommand(logger *log.Logger) cli.Command {
 				Usage: 	"load root's RSA public-private key from `FILE`",
 				Value: 	"commitment.txt",
 			},
+			cli.BoolFlag {
+				Name: 	"prune",
+				Usage: 	"delete block bodies and txs that are no longer needed for validation",
+			},
+			cli.Uint64Flag {
+				Name: 	"prunedepth",
+				Usage: 	"keep `N` blocks in addition to the slashing window when pruning",
+				Value: 	miner.PRUNE_DEPTH,
+			},
+			cli.StringFlag {
+				Name: 	"rpc",
+				Usage: 	"start the JSON RPC server at `IP:PORT`, disabled if not set",
+			},
+			cli.StringFlag {
+				Name: 	"metrics",
+				Usage: 	"serve Prometheus metrics at `IP:PORT`/metrics, disabled if not set",
+			},
+			cli.StringFlag {
+				Name: 	"nodekey",
+				Usage: 	"load the key identifying the miner to its peers from `FILE` (created if it does not exist), a new key is used on every start if not set",
+			},
+			cli.StringFlag {
+				Name: 	"allowlist",
+				Usage: 	"only connect to miners whose node keys are listed in `FILE` (one hex encoded public key per line)",
+			},
+			cli.UintFlag {
+				Name: 	"chainid",
+				Usage: 	"only connect to peers on the chain with `ID`",
+				Value: 	p2p.DEFAULT_CHAIN_ID,
+			},
+			cli.StringFlag {
+				Name: 	"log-level",
+				Usage: 	"log messages of at least `LEVEL` (debug, info, warn, error), single subsystems (miner, p2p, storage, vm, rpc, metrics) can be set separately, e.g., info,p2p=debug",
+				Value: 	"info",
+			},
+			cli.StringFlag {
+				Name: 	"log-format",
+				Usage: 	"log messages as `FORMAT` (text, json)",
+				Value: 	logging.FORMAT_TEXT,
+			},
 			cli.BoolFlag {
 				Name: 	"confirm",
 				Usage: 	"user must press enter before starting the miner",
@@ -104,19 +165,47 @@ func GetStartCommand(logger *log.Logger) cli.Command {
 	}
 }
 
-func Start(args *startArgs, logger *log.Logger) error {
+func Start(args *startArgs, logger *logging.Logger) error {
+	if err := logging.SetLevels(args.logLevel); err != nil {
+		return err
+	}
+	if err := logging.SetFormat(args.logFormat); err != nil {
+		return err
+	}
+
+	var nodeKey ed25519.PrivateKey
+	var allowedPeers []ed25519.PublicKey
+	var err error
+	if len(args.nodeKeyFile) > 0 {
+		if nodeKey, err = crypto.ExtractNodeKeyFromFile(args.nodeKeyFile); err != nil {
+			logger.Errorf("%v", err)
+			return err
+		}
+	}
+	if len(args.allowListFile) > 0 {
+		if allowedPeers, err = crypto.ReadNodePubKeysFromFile(args.allowListFile); err != nil {
+			logger.Errorf("%v", err)
+			return err
+		}
+	}
+	if err := p2p.SetIdentity(nodeKey, allowedPeers); err != nil {
+		logger.Errorf("%v", err)
+		return err
+	}
+	p2p.SetChainID(uint32(args.chainID))
+
 	storage.Init(args.dbname, args.bootstrapNodeAddress)
 	p2p.Init(args.myNodeAddress)
 
 	validatorPubKey, err := crypto.ExtractECDSAPublicKeyFromFile(args.walletFile)
 	if err != nil {
-		logger.Printf("%v\n", err)
+		logger.Errorf("%v", err)
 		return err
 	}
 
 	rootPrivKey, err := crypto.ExtractECDSAKeyFromFile(args.rootKeyFile)
 	if err != nil {
-		logger.Printf("%v\n", err)
+		logger.Errorf("%v", err)
 		return err
 	}
 
@@ -124,7 +213,7 @@ func Start(args *startArgs, logger *log.Logger) error {
 	if len(args.multisigFile) > 0 {
 		multisigPubKey, err = crypto.ExtractECDSAPublicKeyFromFile(args.multisigFile)
 		if err != nil {
-			logger.Printf("%v\n", err)
+			logger.Errorf("%v", err)
 			return err
 		}
 	} else {
@@ -133,16 +222,34 @@ func Start(args *startArgs, logger *log.Logger) error {
 
 	commPrivKey, err := crypto.ExtractRSAKeyFromFile(args.commitmentFile)
 	if err != nil {
-		logger.Printf("%v\n", err)
+		logger.Errorf("%v", err)
 		return err
 	}
 
 	rootCommPrivKey, err := crypto.ExtractRSAKeyFromFile(args.rootCommitmentFile)
 	if err != nil {
-		logger.Printf("%v\n", err)
+		logger.Errorf("%v", err)
 		return err
 	}
 
+	if args.prune {
+		miner.EnablePruning(args.pruneDepth)
+	}
+
+	if len(args.rpcAddress) > 0 {
+		if err := rpc.Init(args.rpcAddress); err != nil {
+			logger.Errorf("%v", err)
+			return err
+		}
+	}
+
+	if len(args.metricsAddress) > 0 {
+		if err := metrics.Init(args.metricsAddress); err != nil {
+			logger.Errorf("%v", err)
+			return err
+		}
+	}
+
 	miner.Init(validatorPubKey, multisigPubKey, &rootPrivKey.PublicKey, commPrivKey, rootCommPrivKey)
 	return nil
 }
@@ -188,7 +295,14 @@ func (args startArgs) String() string {
 			"- Multisig File:\t\t %v\n" +
 			"- Commitment File:\t\t %v\n" +
 			"- Root Wallet File:\t\t %v\n" +
-			"- Root Commitment File:\t %v\n",
+			"- Root Commitment File:\t %v\n" +
+			"- Pruning:\t\t\t %v (depth %v)\n" +
+			"- RPC Address:\t\t\t %v\n" +
+			"- Metrics Address:\t\t %v\n" +
+			"- Log Level:\t\t\t %v (%v)\n" +
+			"- Node Key File:\t\t %v\n" +
+			"- Allow-List File:\t\t %v\n" +
+			"- Chain ID:\t\t\t %v\n",
 		args.dbname,
 		args.myNodeAddress,
 		args.bootstrapNodeAddress,
@@ -196,5 +310,14 @@ func (args startArgs) String() string {
 		args.multisigFile,
 		args.commitmentFile,
 		args.rootKeyFile,
-		args.rootCommitmentFile)
+		args.rootCommitmentFile,
+		args.prune,
+		args.pruneDepth,
+		args.rpcAddress,
+		args.metricsAddress,
+		args.logLevel,
+		args.logFormat,
+		args.nodeKeyFile,
+		args.allowListFile,
+		args.chainID)
 }
\ No newline at end of file
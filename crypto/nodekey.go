@@ -0,0 +1,79 @@
+package crypto
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//Node keys identify miners on the network, they are independent of wallets and commitments. Files contain the
+//hex encoded seed of the private key.
+
+func ExtractNodeKeyFromFile(filename string) (privKey ed25519.PrivateKey, err error) {
+	if _, err = os.Stat(filename); os.IsNotExist(err) {
+		err = CreateNodeKeyFile(filename)
+		if err != nil {
+			return privKey, err
+		}
+	}
+
+	filehandle, err := os.Open(filename)
+	if err != nil {
+		return privKey, errors.New(fmt.Sprintf("%v", err))
+	}
+	defer filehandle.Close()
+
+	scanner := bufio.NewScanner(filehandle)
+	seed, err := hex.DecodeString(nextLine(scanner))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return privKey, errors.New(fmt.Sprintf("Could not read node key from file %v.", filename))
+	}
+
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+func CreateNodeKeyFile(filename string) error {
+	_, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(hex.EncodeToString(privKey.Seed()) + "\n")
+	return err
+}
+
+//Reads hex encoded public node keys, one per line. Empty lines and lines starting with # are ignored.
+func ReadNodePubKeysFromFile(filename string) (pubKeys []ed25519.PublicKey, err error) {
+	filehandle, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%v", err))
+	}
+	defer filehandle.Close()
+
+	scanner := bufio.NewScanner(filehandle)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pubKey, err := hex.DecodeString(line)
+		if err != nil || len(pubKey) != ed25519.PublicKeySize {
+			return nil, errors.New(fmt.Sprintf("Invalid node key %v in %v.", line, filename))
+		}
+		pubKeys = append(pubKeys, ed25519.PublicKey(pubKey))
+	}
+
+	return pubKeys, scanner.Err()
+}
@@ -0,0 +1,61 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+const (
+	NODEKEY_TEST_FILE   = "test_nodekey.txt"
+	ALLOWLIST_TEST_FILE = "test_allowlist.txt"
+)
+
+func TestExtractNodeKeyFromFile(t *testing.T) {
+	os.Remove(NODEKEY_TEST_FILE)
+	defer os.Remove(NODEKEY_TEST_FILE)
+
+	//The key is created if the file does not exist and loaded again afterwards.
+	created, err := ExtractNodeKeyFromFile(NODEKEY_TEST_FILE)
+	if err != nil {
+		t.Fatalf("Could not create node key. Failed with error: %v", err)
+	}
+
+	loaded, err := ExtractNodeKeyFromFile(NODEKEY_TEST_FILE)
+	if err != nil {
+		t.Fatalf("Could not extract node key from file. Failed with error: %v", err)
+	}
+
+	if !created.Equal(loaded) {
+		t.Error("Extracted node key differs from the created one.")
+	}
+
+	ioutil.WriteFile(NODEKEY_TEST_FILE, []byte("no key\n"), 0600)
+	if _, err := ExtractNodeKeyFromFile(NODEKEY_TEST_FILE); err == nil {
+		t.Error("Invalid node key file accepted.")
+	}
+}
+
+func TestReadNodePubKeysFromFile(t *testing.T) {
+	defer os.Remove(ALLOWLIST_TEST_FILE)
+
+	key1, key2 := make([]byte, 32), make([]byte, 32)
+	key1[0], key2[0] = 1, 2
+
+	content := "# Allowed miners\n" + hex.EncodeToString(key1) + "\n\n  " + hex.EncodeToString(key2) + "  \n"
+	ioutil.WriteFile(ALLOWLIST_TEST_FILE, []byte(content), 0600)
+
+	pubKeys, err := ReadNodePubKeysFromFile(ALLOWLIST_TEST_FILE)
+	if err != nil {
+		t.Fatalf("Could not read node keys. Failed with error: %v", err)
+	}
+	if len(pubKeys) != 2 || pubKeys[0][0] != 1 || pubKeys[1][0] != 2 {
+		t.Errorf("Wrong node keys read: %x\n", pubKeys)
+	}
+
+	ioutil.WriteFile(ALLOWLIST_TEST_FILE, []byte("abcd\n"), 0600)
+	if _, err := ReadNodePubKeysFromFile(ALLOWLIST_TEST_FILE); err == nil {
+		t.Error("Invalid node key accepted.")
+	}
+}
@@ -0,0 +1,294 @@
+package logging
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Leveled logging with fields, shared by all packages. Every package logs through its own subsystem logger such that
+//the level can be set per subsystem, e.g., "info,p2p=debug" logs debug messages of the p2p package only.
+
+type Level uint8
+
+const (
+	DEBUG Level = iota
+	INFO
+	WARN
+	ERROR
+)
+
+//Subsystems
+const (
+	MINER   = "miner"
+	P2P     = "p2p"
+	STORAGE = "storage"
+	VM      = "vm"
+	RPC     = "rpc"
+	METRICS = "metrics"
+	CLI     = "cli"
+)
+
+//Output formats
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+)
+
+//Common field keys. Hashes ([32]byte) are logged hex encoded.
+const (
+	BLOCK  = "block"
+	HEIGHT = "height"
+	PEER   = "peer"
+	NODE   = "node"
+	TX     = "tx"
+)
+
+type Fields map[string]interface{}
+
+type Logger struct {
+	subsystem string
+	fields    Fields
+}
+
+var (
+	levelNames = []string{"debug", "info", "warn", "error"}
+	subsystems = []string{MINER, P2P, STORAGE, VM, RPC, METRICS, CLI}
+
+	config = struct {
+		sync.RWMutex
+		output       io.Writer
+		format       string
+		defaultLevel Level
+		levels       map[string]Level
+	}{
+		output:       os.Stdout,
+		format:       FORMAT_TEXT,
+		defaultLevel: INFO,
+		levels:       make(map[string]Level),
+	}
+)
+
+func New(subsystem string) *Logger {
+	return &Logger{subsystem: subsystem}
+}
+
+func (level Level) String() string {
+	if int(level) < len(levelNames) {
+		return levelNames[level]
+	}
+	return fmt.Sprintf("level(%v)", uint8(level))
+}
+
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.ToLower(name) == levelName {
+			return Level(level), nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("Unknown log level %v.", name))
+}
+
+//Sets the default level and optionally the level of single subsystems, e.g., "warn" or "info,p2p=debug,vm=error".
+func SetLevels(spec string) error {
+	defaultLevel := INFO
+	levels := make(map[string]Level)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		subsystem, name := "", part
+		if i := strings.Index(part, "="); i >= 0 {
+			subsystem, name = part[:i], part[i+1:]
+		}
+
+		level, err := ParseLevel(name)
+		if err != nil {
+			return err
+		}
+
+		if subsystem == "" {
+			defaultLevel = level
+			continue
+		}
+		if !isSubsystem(subsystem) {
+			return errors.New(fmt.Sprintf("Unknown subsystem %v, expected one of %v.", subsystem, strings.Join(subsystems, ", ")))
+		}
+		levels[subsystem] = level
+	}
+
+	config.Lock()
+	defer config.Unlock()
+
+	config.defaultLevel = defaultLevel
+	config.levels = levels
+	return nil
+}
+
+func SetFormat(format string) error {
+	if format != FORMAT_TEXT && format != FORMAT_JSON {
+		return errors.New(fmt.Sprintf("Unknown log format %v, expected %v or %v.", format, FORMAT_TEXT, FORMAT_JSON))
+	}
+
+	config.Lock()
+	defer config.Unlock()
+
+	config.format = format
+	return nil
+}
+
+func SetOutput(output io.Writer) {
+	config.Lock()
+	defer config.Unlock()
+
+	config.output = output
+}
+
+func isSubsystem(name string) bool {
+	for _, subsystem := range subsystems {
+		if subsystem == name {
+			return true
+		}
+	}
+	return false
+}
+
+//Returns a logger that adds the fields to every message, in addition to the fields of l.
+func (l *Logger) WithFields(fields Fields) *Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+
+	return &Logger{subsystem: l.subsystem, fields: merged}
+}
+
+//Can be used to skip expensive messages, e.g., state dumps.
+func (l *Logger) Enabled(level Level) bool {
+	config.RLock()
+	defer config.RUnlock()
+
+	minLevel, ok := config.levels[l.subsystem]
+	if !ok {
+		minLevel = config.defaultLevel
+	}
+	return level >= minLevel
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.log(DEBUG, format, args...)
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log(INFO, format, args...)
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.log(WARN, format, args...)
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log(ERROR, format, args...)
+}
+
+//Logs the message as error and exits.
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.log(ERROR, format, args...)
+	os.Exit(1)
+}
+
+func (l *Logger) log(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	caller := "???"
+	if _, file, line, ok := runtime.Caller(2); ok {
+		caller = fmt.Sprintf("%v:%v", filepath.Base(file), line)
+	}
+
+	message := strings.TrimRight(fmt.Sprintf(format, args...), "\n")
+
+	config.Lock()
+	defer config.Unlock()
+
+	var line []byte
+	if config.format == FORMAT_JSON {
+		line = formatJSON(time.Now(), level, l.subsystem, caller, message, l.fields)
+	} else {
+		line = formatText(time.Now(), level, l.subsystem, caller, message, l.fields)
+	}
+	config.output.Write(line)
+}
+
+//2006-01-02 15:04:05 INFO  miner block.go:42: Validated block block=4f2a... height=7
+func formatText(now time.Time, level Level, subsystem, caller, message string, fields Fields) []byte {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%v %-5v %v %v: %v", now.Format("2006-01-02 15:04:05"), strings.ToUpper(level.String()), subsystem, caller, message)
+
+	for _, key := range sortedKeys(fields) {
+		value := fmt.Sprint(formatValue(fields[key]))
+		if strings.ContainsAny(value, " \"=\n") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&builder, " %v=%v", key, value)
+	}
+
+	builder.WriteString("\n")
+	return []byte(builder.String())
+}
+
+//One JSON object per line. Fields cannot overwrite the standard keys.
+func formatJSON(now time.Time, level Level, subsystem, caller, message string, fields Fields) []byte {
+	entry := make(map[string]interface{}, len(fields)+5)
+	for key, value := range fields {
+		entry[key] = formatValue(value)
+	}
+	entry["time"] = now.Format(time.RFC3339Nano)
+	entry["level"] = level.String()
+	entry["subsystem"] = subsystem
+	entry["caller"] = caller
+	entry["msg"] = message
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		line, _ = json.Marshal(map[string]string{"level": ERROR.String(), "msg": fmt.Sprintf("Could not encode log entry: %v", err)})
+	}
+	return append(line, '\n')
+}
+
+func formatValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case [32]byte:
+		return hex.EncodeToString(v[:])
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return value
+}
+
+func sortedKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func capture(t *testing.T, levels, format string) *bytes.Buffer {
+	if err := SetLevels(levels); err != nil {
+		t.Fatalf("Could not set levels %v: %v\n", levels, err)
+	}
+	if err := SetFormat(format); err != nil {
+		t.Fatalf("Could not set format %v: %v\n", format, err)
+	}
+
+	var output bytes.Buffer
+	SetOutput(&output)
+	return &output
+}
+
+func reset() {
+	SetLevels("info")
+	SetFormat(FORMAT_TEXT)
+	SetOutput(os.Stdout)
+}
+
+func TestLevels(t *testing.T) {
+	defer reset()
+	output := capture(t, "warn,p2p=debug,vm=error", FORMAT_TEXT)
+
+	miner, p2p, vm := New(MINER), New(P2P), New(VM)
+
+	miner.Infof("miner info")
+	miner.Warnf("miner warn")
+	p2p.Debugf("p2p debug")
+	vm.Warnf("vm warn")
+	vm.Errorf("vm error")
+
+	logged := output.String()
+	for _, expected := range []string{"miner warn", "p2p debug", "vm error"} {
+		if !strings.Contains(logged, expected) {
+			t.Errorf("Message %q not logged: %v\n", expected, logged)
+		}
+	}
+	for _, unexpected := range []string{"miner info", "vm warn"} {
+		if strings.Contains(logged, unexpected) {
+			t.Errorf("Message %q logged below its level: %v\n", unexpected, logged)
+		}
+	}
+
+	if miner.Enabled(INFO) || !p2p.Enabled(DEBUG) {
+		t.Error("Enabled does not match the configured levels.")
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	defer reset()
+
+	for _, levels := range []string{"verbose", "info,p2p", "info,consensus=debug"} {
+		if err := SetLevels(levels); err == nil {
+			t.Errorf("Invalid levels %q accepted.\n", levels)
+		}
+	}
+
+	if err := SetFormat("xml"); err == nil {
+		t.Error("Invalid format accepted.")
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	defer reset()
+	output := capture(t, "info", FORMAT_TEXT)
+
+	New(MINER).WithFields(Fields{BLOCK: [32]byte{0xab}, HEIGHT: 7, PEER: "127.0.0.1:8000"}).Infof("Validated block\n")
+
+	line := output.String()
+	if strings.Count(line, "\n") != 1 {
+		t.Errorf("Message not logged as a single line: %q\n", line)
+	}
+	for _, expected := range []string{
+		" INFO  miner logging_test.go:",
+		": Validated block ",
+		"block=ab00000000000000000000000000000000000000000000000000000000000000",
+		"height=7",
+		"peer=127.0.0.1:8000",
+	} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("%q missing in %q\n", expected, line)
+		}
+	}
+
+	//Fields are sorted by key
+	if strings.Index(line, "block=") > strings.Index(line, "height=") || strings.Index(line, "height=") > strings.Index(line, "peer=") {
+		t.Errorf("Fields not sorted: %q\n", line)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	defer reset()
+	output := capture(t, "debug", FORMAT_JSON)
+
+	logger := New(P2P).WithFields(Fields{PEER: "127.0.0.1:8000"})
+	logger.WithFields(Fields{TX: [32]byte{0x01}, "msg": "overwritten"}).Debugf("Received tx %v", 1)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(output.Bytes(), &entry); err != nil {
+		t.Fatalf("Invalid JSON %q: %v\n", output.String(), err)
+	}
+
+	expected := map[string]interface{}{
+		"level":     "debug",
+		"subsystem": P2P,
+		"msg":       "Received tx 1",
+		PEER:        "127.0.0.1:8000",
+		TX:          "0100000000000000000000000000000000000000000000000000000000000000",
+	}
+	for key, value := range expected {
+		if entry[key] != value {
+			t.Errorf("Wrong %v: %v, expected %v\n", key, entry[key], value)
+		}
+	}
+	if caller, _ := entry["caller"].(string); !strings.HasPrefix(caller, "logging_test.go:") {
+		t.Errorf("Wrong caller: %v\n", entry["caller"])
+	}
+
+	//Fields of the parent logger are not changed
+	if len(logger.fields) != 1 {
+		t.Errorf("Parent logger has %v fields, expected 1\n", len(logger.fields))
+	}
+}
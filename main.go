@@ -2,13 +2,13 @@ package main
 
 import (
 	"github.com/bazo-blockchain/bazo-miner/cli"
-	"github.com/bazo-blockchain/bazo-miner/storage"
+	"github.com/bazo-blockchain/bazo-miner/logging"
 	cli2 "github.com/urfave/cli"
 	"os"
 )
 
 func main() {
-	logger := storage.InitLogger()
+	logger := logging.New(logging.CLI)
 
 	app := cli2.NewApp()
 
@@ -20,10 +20,14 @@ func main() {
 		cli.GetStartCommand(logger),
 		cli.GetGenerateWalletCommand(),
 		cli.GetGenerateCommitmentCommand(),
+		cli.GetExportCommand(logger),
+		cli.GetImportCommand(logger),
+		cli.GetDbCommand(logger),
+		cli.GetPeersCommand(logger),
 	}
 
 	err := app.Run(os.Args)
 	if err != nil {
-		logger.Fatal(err)
+		logger.Fatalf("%v", err)
 	}
 }
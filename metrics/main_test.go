@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/logging"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+const (
+	TestIpPort     = "127.0.0.1:8000"
+	TestDBFileName = "test.db"
+)
+
+func TestMain(m *testing.M) {
+
+	storage.Init(TestDBFileName, TestIpPort)
+	storage.DeleteAll()
+	logging.SetOutput(ioutil.Discard)
+
+	retCode := m.Run()
+
+	storage.TearDown()
+	os.Remove(TestDBFileName)
+	os.Exit(retCode)
+}
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+
+	"github.com/bazo-blockchain/bazo-miner/logging"
+	"github.com/bazo-blockchain/bazo-miner/miner"
+	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Exports the node's metrics at /metrics in the Prometheus text format (version 0.0.4).
+
+var logger = logging.New(logging.METRICS)
+
+//Starts the metrics server at address in the background.
+func Init(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", serveMetrics)
+
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			logger.Errorf("Metrics server stopped: %v", err)
+		}
+	}()
+
+	logger.Infof("Metrics server listening on %v", listener.Addr())
+	return nil
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	writer := bufio.NewWriter(w)
+	writeMetrics(writer)
+	writer.Flush()
+}
+
+func writeMetrics(w *bufio.Writer) {
+	minerMetrics := miner.ReadMetrics()
+	p2pMetrics := p2p.ReadMetrics()
+	mempoolStats := storage.ReadMempoolStats()
+
+	gauge(w, "bazo_chain_height", "Height of the last validated block.", float64(minerMetrics.Height))
+	gauge(w, "bazo_last_block_timestamp_seconds", "Timestamp of the last validated block.", float64(minerMetrics.LastBlockTimestamp))
+	gauge(w, "bazo_difficulty_target", "Current proof of stake target.", float64(minerMetrics.Target))
+
+	header(w, "bazo_block_validations_total", "Number of block validations by result.", "counter")
+	sample(w, "bazo_block_validations_total", `{result="ok"}`, float64(minerMetrics.Validations))
+	sample(w, "bazo_block_validations_total", `{result="failed"}`, float64(minerMetrics.ValidationFailures))
+	counter(w, "bazo_block_validation_seconds_total", "Time spent validating blocks.", minerMetrics.ValidationTime.Seconds())
+	counter(w, "bazo_block_rollbacks_total", "Number of rolled back blocks.", float64(minerMetrics.Rollbacks))
+	counter(w, "bazo_block_rollback_seconds_total", "Time spent rolling back blocks.", minerMetrics.RollbackTime.Seconds())
+	counter(w, "bazo_reorgs_total", "Number of switches to a longer chain.", float64(minerMetrics.Reorgs))
+	gauge(w, "bazo_sync_height", "Height up to which blocks were downloaded in the initial synchronization.", float64(minerMetrics.SyncHeight))
+	gauge(w, "bazo_sync_target_height", "Height of the chain the node synchronizes with.", float64(minerMetrics.SyncTarget))
+
+	gauge(w, "bazo_mempool_txs", "Number of txs in the mempool.", float64(mempoolStats.Txs))
+	gauge(w, "bazo_mempool_bytes", "Size of the txs in the mempool.", float64(mempoolStats.Bytes))
+	header(w, "bazo_mempool_events_total", "Number of mempool changes by type.", "counter")
+	sample(w, "bazo_mempool_events_total", `{event="added"}`, float64(mempoolStats.Added))
+	sample(w, "bazo_mempool_events_total", `{event="removed"}`, float64(mempoolStats.Removed))
+	sample(w, "bazo_mempool_events_total", `{event="evicted"}`, float64(mempoolStats.Evicted))
+	sample(w, "bazo_mempool_events_total", `{event="replaced"}`, float64(mempoolStats.Replaced))
+	sample(w, "bazo_mempool_events_total", `{event="rejected"}`, float64(mempoolStats.Rejected))
+
+	header(w, "bazo_peers", "Number of connected peers by type.", "gauge")
+	sample(w, "bazo_peers", `{type="miner"}`, float64(p2pMetrics.Miners))
+	sample(w, "bazo_peers", `{type="client"}`, float64(p2pMetrics.Clients))
+	byType(w, "bazo_p2p_received_bytes_total", "Bytes received by message type.", p2pMetrics.BytesIn)
+	byType(w, "bazo_p2p_sent_bytes_total", "Bytes sent by message type.", p2pMetrics.BytesOut)
+	counter(w, "bazo_p2p_dropped_messages_total", "Broadcast messages dropped because the outbound queue of a peer was full.", float64(p2pMetrics.DroppedMsgs))
+
+	params := minerMetrics.Parameters
+	header(w, "bazo_parameter", "Active system parameters.", "gauge")
+	for _, param := range []struct {
+		name  string
+		value uint64
+	}{
+		{"fee_minimum", params.Fee_minimum},
+		{"block_size", params.Block_size},
+		{"diff_interval", params.Diff_interval},
+		{"block_interval", params.Block_interval},
+		{"block_reward", params.Block_reward},
+		{"staking_minimum", params.Staking_minimum},
+		{"waiting_minimum", params.Waiting_minimum},
+		{"accepted_time_diff", params.Accepted_time_diff},
+		{"slashing_window_size", params.Slashing_window_size},
+		{"slash_reward", params.Slash_reward},
+	} {
+		sample(w, "bazo_parameter", fmt.Sprintf(`{name="%v"}`, param.name), float64(param.value))
+	}
+}
+
+func header(w *bufio.Writer, name, help, metricType string) {
+	fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, metricType)
+}
+
+func sample(w *bufio.Writer, name, labels string, value float64) {
+	fmt.Fprintf(w, "%v%v %v\n", name, labels, value)
+}
+
+func gauge(w *bufio.Writer, name, help string, value float64) {
+	header(w, name, help, "gauge")
+	sample(w, name, "", value)
+}
+
+func counter(w *bufio.Writer, name, help string, value float64) {
+	header(w, name, help, "counter")
+	sample(w, name, "", value)
+}
+
+//Message types are sorted such that the output is stable.
+func byType(w *bufio.Writer, name, help string, values map[string]uint64) {
+	header(w, name, help, "counter")
+
+	var types []string
+	for msgType := range values {
+		types = append(types, msgType)
+	}
+	sort.Strings(types)
+
+	for _, msgType := range types {
+		sample(w, name, fmt.Sprintf(`{type="%v"}`, msgType), float64(values[msgType]))
+	}
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func TestServeMetrics(t *testing.T) {
+	tx := &protocol.FundsTx{Fee: 5, TxCnt: 1, From: [32]byte{1}, To: [32]byte{2}}
+	if err := storage.WriteOpenTx(tx); err != nil {
+		t.Fatalf("Tx could not be written: %v\n", err)
+	}
+	defer storage.DeleteOpenTx(tx)
+
+	recorder := httptest.NewRecorder()
+	serveMetrics(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("Wrong content type: %v\n", contentType)
+	}
+
+	body := recorder.Body.String()
+	for _, expected := range []string{
+		"# TYPE bazo_chain_height gauge\nbazo_chain_height 0\n",
+		"# TYPE bazo_block_validations_total counter\n",
+		"bazo_block_validations_total{result=\"failed\"} 0\n",
+		"bazo_mempool_txs 1\n",
+		"bazo_mempool_events_total{event=\"added\"} 1\n",
+		"bazo_peers{type=\"miner\"} 0\n",
+		"bazo_parameter{name=\"block_size\"} ",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Metric missing: %q\n", expected)
+		}
+	}
+
+	//Every sample is preceded by the HELP and TYPE lines of its metric.
+	var current string
+	for _, line := range strings.Split(strings.TrimSpace(body), "\n") {
+		if strings.HasPrefix(line, "# TYPE ") {
+			current = strings.Fields(line)[2]
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := strings.FieldsFunc(line, func(r rune) bool { return r == '{' || r == ' ' })[0]
+		if name != current {
+			t.Errorf("Sample %v without TYPE line\n", line)
+		}
+	}
+}
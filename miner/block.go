@@ -24,6 +24,17 @@ type blockData struct {
 	block         *protocol.Block
 }
 
+//Returned if a block violates the consensus rules (e.g., wrong merkle root, commitment proof or PoS, invalid txs). Only
+//the senders of such blocks are penalized, blocks that could not be validated for local reasons (e.g., txs that could
+//not be fetched or a skewed system time) are not invalid.
+type invalidBlockError struct {
+	reason string
+}
+
+func (err *invalidBlockError) Error() string {
+	return err.reason
+}
+
 //Block constructor, argument is the previous block in the blockchain.
 func newBlock(prevHash [32]byte, commitmentProof [crypto.COMM_PROOF_LENGTH]byte, height uint32) *protocol.Block {
 	block := new(protocol.Block)
@@ -102,7 +113,7 @@ func addTx(b *protocol.Block, tx protocol.Transaction) error {
 	//ActiveParameters is a datastructure that stores the current system parameters, gets only changed when
 	//configTxs are broadcast in the network.
 	if tx.TxFee() < activeParameters.Fee_minimum {
-		logger.Printf("Transaction fee too low: %v (minimum is: %v)\n", tx.TxFee(), activeParameters.Fee_minimum)
+		txLogger(tx).Debugf("Transaction fee too low: %v (minimum is: %v)", tx.TxFee(), activeParameters.Fee_minimum)
 		err := fmt.Sprintf("Transaction fee too low: %v (minimum is: %v)\n", tx.TxFee(), activeParameters.Fee_minimum)
 		return errors.New(err)
 	}
@@ -114,7 +125,7 @@ func addTx(b *protocol.Block, tx protocol.Transaction) error {
 	//So the trade-off is effectively clean abstraction vs. tx size. Everything related to fundsTx is postponed because
 	//the txs depend on each other.
 	if !verify(tx) {
-		logger.Printf("Transaction could not be verified: %v", tx)
+		txLogger(tx).Debugf("Transaction could not be verified: %v", tx)
 		return errors.New("Transaction could not be verified.")
 	}
 
@@ -122,25 +133,25 @@ func addTx(b *protocol.Block, tx protocol.Transaction) error {
 	case *protocol.AccTx:
 		err := addAccTx(b, tx.(*protocol.AccTx))
 		if err != nil {
-			logger.Printf("Adding accTx tx failed (%v): %v\n", err, tx.(*protocol.AccTx))
+			txLogger(tx).Debugf("Adding accTx tx failed (%v): %v", err, tx.(*protocol.AccTx))
 			return err
 		}
 	case *protocol.FundsTx:
 		err := addFundsTx(b, tx.(*protocol.FundsTx))
 		if err != nil {
-			logger.Printf("Adding fundsTx tx failed (%v): %v\n", err, tx.(*protocol.FundsTx))
+			txLogger(tx).Debugf("Adding fundsTx tx failed (%v): %v", err, tx.(*protocol.FundsTx))
 			return err
 		}
 	case *protocol.ConfigTx:
 		err := addConfigTx(b, tx.(*protocol.ConfigTx))
 		if err != nil {
-			logger.Printf("Adding configTx tx failed (%v): %v\n", err, tx.(*protocol.ConfigTx))
+			txLogger(tx).Debugf("Adding configTx tx failed (%v): %v", err, tx.(*protocol.ConfigTx))
 			return err
 		}
 	case *protocol.StakeTx:
 		err := addStakeTx(b, tx.(*protocol.StakeTx))
 		if err != nil {
-			logger.Printf("Adding stakeTx tx failed (%v): %v\n", err, tx.(*protocol.StakeTx))
+			txLogger(tx).Debugf("Adding stakeTx tx failed (%v): %v", err, tx.(*protocol.StakeTx))
 			return err
 		}
 	default:
@@ -162,7 +173,7 @@ func addAccTx(b *protocol.Block, tx *protocol.AccTx) error {
 
 	//Add the tx hash to the block header and write it to open storage (non-validated transactions).
 	b.AccTxData = append(b.AccTxData, tx.Hash())
-	logger.Printf("Added tx to the AccTxData slice: %v", *tx)
+	txLogger(tx).Debugf("Added tx to the AccTxData slice: %v", *tx)
 	return nil
 }
 
@@ -240,14 +251,14 @@ func addFundsTx(b *protocol.Block, tx *protocol.FundsTx) error {
 
 	//Add the tx hash to the block header and write it to open storage (non-validated transactions).
 	b.FundsTxData = append(b.FundsTxData, tx.Hash())
-	logger.Printf("Added tx to the FundsTxData slice: %v", *tx)
+	txLogger(tx).Debugf("Added tx to the FundsTxData slice: %v", *tx)
 	return nil
 }
 
 func addConfigTx(b *protocol.Block, tx *protocol.ConfigTx) error {
 	//No further checks needed, static checks were already done with verify().
 	b.ConfigTxData = append(b.ConfigTxData, tx.Hash())
-	logger.Printf("Added tx to the ConfigTxData slice: %v", *tx)
+	txLogger(tx).Debugf("Added tx to the ConfigTxData slice: %v", *tx)
 	return nil
 }
 
@@ -287,191 +298,156 @@ func addStakeTx(b *protocol.Block, tx *protocol.StakeTx) error {
 
 	//No further checks needed, static checks were already done with verify().
 	b.StakeTxData = append(b.StakeTxData, tx.Hash())
-	logger.Printf("Added tx to the StakeTxData slice: %v", *tx)
+	txLogger(tx).Debugf("Added tx to the StakeTxData slice: %v", *tx)
 	return nil
 }
 
 //We use slices (not maps) because order is now important.
 func fetchAccTxData(block *protocol.Block, accTxSlice []*protocol.AccTx, initialSetup bool, errChan chan error) {
+	var missing [][32]byte
 	for cnt, txHash := range block.AccTxData {
-		var tx protocol.Transaction
-		var accTx *protocol.AccTx
-
 		closedTx := storage.ReadClosedTx(txHash)
 		if closedTx != nil {
 			if initialSetup {
-				accTx = closedTx.(*protocol.AccTx)
-				accTxSlice[cnt] = accTx
+				accTxSlice[cnt] = closedTx.(*protocol.AccTx)
 				continue
 			} else {
 				//Reject blocks that have txs which have already been validated.
-				errChan <- errors.New("Block validation had accTx that was already in a previous block.")
+				errChan <- &invalidBlockError{"Block validation had accTx that was already in a previous block."}
 				return
 			}
 		}
 
 		//TODO Optimize code (duplicated)
 		//Tx is either in open storage or needs to be fetched from the network.
-		tx = storage.ReadOpenTx(txHash)
-		if tx != nil {
-			accTx = tx.(*protocol.AccTx)
+		if tx := storage.ReadOpenTx(txHash); tx != nil {
+			accTxSlice[cnt] = tx.(*protocol.AccTx)
 		} else {
-			err := p2p.TxReq(txHash, p2p.ACCTX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("AccTx could not be read: %v", err))
-				return
-			}
-
-			//Blocking Wait
-			select {
-			case accTx = <-p2p.AccTxChan:
-				//Limit the waiting time for TXFETCH_TIMEOUT seconds.
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("AccTx fetch timed out.")
-			}
-			//This check is important. A malicious miner might have sent us a tx whose hash is a different one
-			//from what we requested.
-			if accTx.Hash() != txHash {
-				errChan <- errors.New("Received txHash did not correspond to our request.")
-			}
+			missing = append(missing, txHash)
 		}
+	}
 
-		accTxSlice[cnt] = accTx
+	//Missing txs are fetched in batches, the p2p package checks that the received txs are the ones we requested.
+	fetchedTxs, err := p2p.FetchTxs(missing, p2p.ACCTX_REQ, TXFETCH_TIMEOUT*time.Second)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("AccTx could not be read: %v", err))
+		return
+	}
+	for cnt, txHash := range block.AccTxData {
+		if accTxSlice[cnt] == nil {
+			accTxSlice[cnt] = fetchedTxs[txHash].(*protocol.AccTx)
+		}
 	}
 
 	errChan <- nil
 }
 
 func fetchFundsTxData(block *protocol.Block, fundsTxSlice []*protocol.FundsTx, initialSetup bool, errChan chan error) {
+	var missing [][32]byte
 	for cnt, txHash := range block.FundsTxData {
-		var tx protocol.Transaction
-		var fundsTx *protocol.FundsTx
-
 		closedTx := storage.ReadClosedTx(txHash)
 		if closedTx != nil {
 			if initialSetup {
-				fundsTx = closedTx.(*protocol.FundsTx)
-				fundsTxSlice[cnt] = fundsTx
+				fundsTxSlice[cnt] = closedTx.(*protocol.FundsTx)
 				continue
 			} else {
-				errChan <- errors.New("Block validation had fundsTx that was already in a previous block.")
+				errChan <- &invalidBlockError{"Block validation had fundsTx that was already in a previous block."}
 				return
 			}
 		}
 
 		//TODO Optimize code (duplicated)
-		tx = storage.ReadOpenTx(txHash)
-		if tx != nil {
-			fundsTx = tx.(*protocol.FundsTx)
+		if tx := storage.ReadOpenTx(txHash); tx != nil {
+			fundsTxSlice[cnt] = tx.(*protocol.FundsTx)
 		} else {
-			err := p2p.TxReq(txHash, p2p.FUNDSTX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("FundsTx could not be read: %v", err))
-				return
-			}
-
-			select {
-			case fundsTx = <-p2p.FundsTxChan:
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("FundsTx fetch timed out.")
-				return
-			}
-			if fundsTx.Hash() != txHash {
-				errChan <- errors.New("Received txHash did not correspond to our request.")
-			}
+			missing = append(missing, txHash)
 		}
+	}
 
-		fundsTxSlice[cnt] = fundsTx
+	//Missing txs are fetched in batches, the p2p package checks that the received txs are the ones we requested.
+	fetchedTxs, err := p2p.FetchTxs(missing, p2p.FUNDSTX_REQ, TXFETCH_TIMEOUT*time.Second)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("FundsTx could not be read: %v", err))
+		return
+	}
+	for cnt, txHash := range block.FundsTxData {
+		if fundsTxSlice[cnt] == nil {
+			fundsTxSlice[cnt] = fetchedTxs[txHash].(*protocol.FundsTx)
+		}
 	}
 
 	errChan <- nil
 }
 
 func fetchConfigTxData(block *protocol.Block, configTxSlice []*protocol.ConfigTx, initialSetup bool, errChan chan error) {
+	var missing [][32]byte
 	for cnt, txHash := range block.ConfigTxData {
-		var tx protocol.Transaction
-		var configTx *protocol.ConfigTx
-
 		closedTx := storage.ReadClosedTx(txHash)
 		if closedTx != nil {
 			if initialSetup {
-				configTx = closedTx.(*protocol.ConfigTx)
-				configTxSlice[cnt] = configTx
+				configTxSlice[cnt] = closedTx.(*protocol.ConfigTx)
 				continue
 			} else {
-				errChan <- errors.New("Block validation had configTx that was already in a previous block.")
+				errChan <- &invalidBlockError{"Block validation had configTx that was already in a previous block."}
 				return
 			}
 		}
 
 		//TODO Optimize code (duplicated)
-		tx = storage.ReadOpenTx(txHash)
-		if tx != nil {
-			configTx = tx.(*protocol.ConfigTx)
+		if tx := storage.ReadOpenTx(txHash); tx != nil {
+			configTxSlice[cnt] = tx.(*protocol.ConfigTx)
 		} else {
-			err := p2p.TxReq(txHash, p2p.CONFIGTX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("ConfigTx could not be read: %v", err))
-				return
-			}
-
-			select {
-			case configTx = <-p2p.ConfigTxChan:
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("ConfigTx fetch timed out.")
-				return
-			}
-			if configTx.Hash() != txHash {
-				errChan <- errors.New("Received txHash did not correspond to our request.")
-			}
+			missing = append(missing, txHash)
 		}
+	}
 
-		configTxSlice[cnt] = configTx
+	//Missing txs are fetched in batches, the p2p package checks that the received txs are the ones we requested.
+	fetchedTxs, err := p2p.FetchTxs(missing, p2p.CONFIGTX_REQ, TXFETCH_TIMEOUT*time.Second)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("ConfigTx could not be read: %v", err))
+		return
+	}
+	for cnt, txHash := range block.ConfigTxData {
+		if configTxSlice[cnt] == nil {
+			configTxSlice[cnt] = fetchedTxs[txHash].(*protocol.ConfigTx)
+		}
 	}
 
 	errChan <- nil
 }
 
 func fetchStakeTxData(block *protocol.Block, stakeTxSlice []*protocol.StakeTx, initialSetup bool, errChan chan error) {
+	var missing [][32]byte
 	for cnt, txHash := range block.StakeTxData {
-		var tx protocol.Transaction
-		var stakeTx *protocol.StakeTx
-
 		closedTx := storage.ReadClosedTx(txHash)
 		if closedTx != nil {
 			if initialSetup {
-				stakeTx = closedTx.(*protocol.StakeTx)
-				stakeTxSlice[cnt] = stakeTx
+				stakeTxSlice[cnt] = closedTx.(*protocol.StakeTx)
 				continue
 			} else {
-				errChan <- errors.New("Block validation had stakeTx that was already in a previous block.")
+				errChan <- &invalidBlockError{"Block validation had stakeTx that was already in a previous block."}
 				return
 			}
 		}
 
 		//TODO Optimize code (duplicated)
-		tx = storage.ReadOpenTx(txHash)
-		if tx != nil {
-			stakeTx = tx.(*protocol.StakeTx)
+		if tx := storage.ReadOpenTx(txHash); tx != nil {
+			stakeTxSlice[cnt] = tx.(*protocol.StakeTx)
 		} else {
-			err := p2p.TxReq(txHash, p2p.STAKETX_REQ)
-			if err != nil {
-				errChan <- errors.New(fmt.Sprintf("StakeTx could not be read: %v", err))
-				return
-			}
-
-			select {
-			case stakeTx = <-p2p.StakeTxChan:
-			case <-time.After(TXFETCH_TIMEOUT * time.Second):
-				errChan <- errors.New("StakeTx fetch timed out.")
-				return
-			}
-			if stakeTx.Hash() != txHash {
-				errChan <- errors.New("Received txHash did not correspond to our request.")
-			}
+			missing = append(missing, txHash)
 		}
+	}
 
-		stakeTxSlice[cnt] = stakeTx
+	//Missing txs are fetched in batches, the p2p package checks that the received txs are the ones we requested.
+	fetchedTxs, err := p2p.FetchTxs(missing, p2p.STAKETX_REQ, TXFETCH_TIMEOUT*time.Second)
+	if err != nil {
+		errChan <- errors.New(fmt.Sprintf("StakeTx could not be read: %v", err))
+		return
+	}
+	for cnt, txHash := range block.StakeTxData {
+		if stakeTxSlice[cnt] == nil {
+			stakeTxSlice[cnt] = fetchedTxs[txHash].(*protocol.StakeTx)
+		}
 	}
 
 	errChan <- nil
@@ -480,7 +456,7 @@ func fetchStakeTxData(block *protocol.Block, stakeTxSlice []*protocol.StakeTx, i
 //This function is split into block syntax/PoS check and actual state change
 //because there is the case that we might need to go fetch several blocks
 // and have to check the blocks first before changing the state in the correct order.
-func validate(b *protocol.Block, initialSetup bool) error {
+func validate(b *protocol.Block, initialSetup bool) (err error) {
 	//TODO Optimize code
 
 	//This mutex is necessary that own-mined blocks and received blocks from the network are not
@@ -488,6 +464,11 @@ func validate(b *protocol.Block, initialSetup bool) error {
 	blockValidation.Lock()
 	defer blockValidation.Unlock()
 
+	start := time.Now()
+	defer func() {
+		recordValidation(time.Since(start), err)
+	}()
+
 	//Prepare datastructure to fill tx payloads.
 	blockDataMap := make(map[[32]byte]blockData)
 
@@ -501,7 +482,7 @@ func validate(b *protocol.Block, initialSetup bool) error {
 	//If we are syncing or far behind, we cannot do this dynamic check,
 	//therefore we include a boolean uptodate. If it's true we consider ourselves uptodate and
 	//do dynamic time checking.
-	if len(blocksToValidate) > DELAYED_BLOCKS {
+	if len(blocksToValidate) > DELAYED_BLOCKS || importing || syncing {
 		uptodate = false
 	} else {
 		uptodate = true
@@ -525,18 +506,28 @@ func validate(b *protocol.Block, initialSetup bool) error {
 
 			blockDataMap[block.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, block}
 			if err := validateState(blockDataMap[block.Hash]); err != nil {
-				return err
+				return &invalidBlockError{err.Error()}
 			}
 
 			postValidate(blockDataMap[block.Hash], initialSetup)
 		}
 	} else {
+		recordReorg()
 		for _, block := range blocksToRollback {
+			rollbackStart := time.Now()
 			if err := rollback(block); err != nil {
 				return err
 			}
-			logger.Printf("Rolled back block: %vState:\n%v", block, getState())
+			recordRollback(time.Since(rollbackStart))
+			blockLogger(block).Infof("Rolled back block")
+			logState()
+		}
+
+		var rolledBack [][32]byte
+		for _, block := range blocksToRollback {
+			rolledBack = append(rolledBack, block.Hash)
 		}
+		storage.Publish(storage.Event{Type: storage.EVENT_REORG, RolledBack: rolledBack})
 		for _, block := range blocksToValidate {
 			//Fetching payload data from the txs (if necessary, ask other miners).
 			accTxs, fundsTxs, configTxs, stakeTxs, err := preValidate(block, initialSetup)
@@ -553,11 +544,25 @@ func validate(b *protocol.Block, initialSetup bool) error {
 
 			blockDataMap[block.Hash] = blockData{accTxs, fundsTxs, configTxs, stakeTxs, block}
 			if err := validateState(blockDataMap[block.Hash]); err != nil {
-				return err
+				return &invalidBlockError{err.Error()}
 			}
 
 			postValidate(blockDataMap[block.Hash], initialSetup)
 		}
+
+		recheckRolledBackTxs(blocksToRollback)
+	}
+
+	if !initialSetup {
+		//The txs of the block leave the mempool with one journal write.
+		if err := storage.FlushMempoolJournal(); err != nil {
+			logger.Errorf("Could not journal the mempool: %v", err)
+		}
+
+		//Pruning errors do not invalidate the block, the remaining blocks are pruned after the next validation.
+		if err := pruneChain(); err != nil {
+			logger.Errorf("Could not prune chain: %v", err)
+		}
 	}
 
 	return nil
@@ -575,32 +580,32 @@ func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protoc
 
 	//Check block size.
 	if block.GetSize() > activeParameters.Block_size {
-		return nil, nil, nil, nil, errors.New("Block size too large.")
+		return nil, nil, nil, nil, &invalidBlockError{"Block size too large."}
 	}
 
 	//Duplicates are not allowed, use tx hash hashmap to easily check for duplicates.
 	duplicates := make(map[[32]byte]bool)
 	for _, txHash := range block.AccTxData {
 		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, errors.New("Duplicate Account Transaction Hash detected.")
+			return nil, nil, nil, nil, &invalidBlockError{"Duplicate Account Transaction Hash detected."}
 		}
 		duplicates[txHash] = true
 	}
 	for _, txHash := range block.FundsTxData {
 		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, errors.New("Duplicate Funds Transaction Hash detected.")
+			return nil, nil, nil, nil, &invalidBlockError{"Duplicate Funds Transaction Hash detected."}
 		}
 		duplicates[txHash] = true
 	}
 	for _, txHash := range block.ConfigTxData {
 		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, errors.New("Duplicate Config Transaction Hash detected.")
+			return nil, nil, nil, nil, &invalidBlockError{"Duplicate Config Transaction Hash detected."}
 		}
 		duplicates[txHash] = true
 	}
 	for _, txHash := range block.StakeTxData {
 		if _, exists := duplicates[txHash]; exists {
-			return nil, nil, nil, nil, errors.New("Duplicate Stake Transaction Hash detected.")
+			return nil, nil, nil, nil, &invalidBlockError{"Duplicate Stake Transaction Hash detected."}
 		}
 		duplicates[txHash] = true
 	}
@@ -630,12 +635,12 @@ func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protoc
 	//Check state contains beneficiary.
 	acc, err := storage.GetAccount(block.Beneficiary)
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return nil, nil, nil, nil, &invalidBlockError{err.Error()}
 	}
 
 	//Check if node is part of the validator set.
 	if !acc.IsStaking {
-		return nil, nil, nil, nil, errors.New("Validator is not part of the validator set.")
+		return nil, nil, nil, nil, &invalidBlockError{"Validator is not part of the validator set."}
 	}
 
 	//First, initialize an RSA Public Key instance with the modulus of the proposer of the block (acc)
@@ -643,12 +648,12 @@ func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protoc
 	//Invalid if the commitment proof can not be verified with the public key of the proposer
 	commitmentPubKey, err := crypto.CreateRSAPubKeyFromBytes(acc.CommitmentKey)
 	if err != nil {
-		return nil, nil, nil, nil, errors.New("Invalid commitment key in account.")
+		return nil, nil, nil, nil, &invalidBlockError{"Invalid commitment key in account."}
 	}
 
 	err = crypto.VerifyMessageWithRSAKey(commitmentPubKey, fmt.Sprint(block.Height), block.CommitmentProof)
 	if err != nil {
-		return nil, nil, nil, nil, errors.New("The submitted commitment proof can not be verified.")
+		return nil, nil, nil, nil, &invalidBlockError{"The submitted commitment proof can not be verified."}
 	}
 
 	//Invalid if PoS calculation is not correct.
@@ -656,7 +661,7 @@ func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protoc
 
 	//PoS validation
 	if !validateProofOfStake(getDifficulty(), prevProofs, block.Height, acc.Balance, block.CommitmentProof, block.Timestamp) {
-		return nil, nil, nil, nil, errors.New("The nonce is incorrect.")
+		return nil, nil, nil, nil, &invalidBlockError{"The nonce is incorrect."}
 	}
 
 	//Invalid if PoS is too far in the future.
@@ -667,7 +672,7 @@ func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protoc
 
 	//Check for minimum waiting time.
 	if block.Height-acc.StakingBlockHeight < uint32(activeParameters.Waiting_minimum) {
-		return nil, nil, nil, nil, errors.New("The miner must wait a minimum amount of blocks before start validating. Block Height:" + fmt.Sprint(block.Height) + " - Height when started validating " + string(acc.StakingBlockHeight) + " MinWaitingTime: " + string(activeParameters.Waiting_minimum))
+		return nil, nil, nil, nil, &invalidBlockError{"The miner must wait a minimum amount of blocks before start validating. Block Height:" + fmt.Sprint(block.Height) + " - Height when started validating " + string(acc.StakingBlockHeight) + " MinWaitingTime: " + string(activeParameters.Waiting_minimum)}
 	}
 
 	//Check if block contains a proof for two conflicting block hashes, else no proof provided.
@@ -679,7 +684,7 @@ func preValidate(block *protocol.Block, initialSetup bool) (accTxSlice []*protoc
 
 	//Merkle Tree validation
 	if protocol.BuildMerkleTree(block).MerkleRoot() != block.MerkleRoot {
-		return nil, nil, nil, nil, errors.New("Merkle Root is incorrect.")
+		return nil, nil, nil, nil, &invalidBlockError{"Merkle Root is incorrect."}
 	}
 
 	return accTxSlice, fundsTxSlice, configTxSlice, stakeTxSlice, err
@@ -771,7 +776,7 @@ func postValidate(data blockData, initialSetup bool) {
 			storage.DeleteOpenTx(tx)
 		}
 
-		if len(data.fundsTxSlice) > 0 {
+		if len(data.fundsTxSlice) > 0 && !importing && !syncing {
 			broadcastVerifiedTxs(data.fundsTxSlice)
 		}
 
@@ -782,9 +787,27 @@ func postValidate(data blockData, initialSetup bool) {
 		// Write last block to db and delete last block's ancestor.
 		storage.DeleteAllLastClosedBlock()
 		storage.WriteLastClosedBlock(data.block)
+
+		storage.Publish(storage.Event{Type: storage.EVENT_BLOCK, Block: data.block, Txs: data.txs()})
 	}
 }
 
+func (data blockData) txs() (txs []protocol.Transaction) {
+	for _, tx := range data.accTxSlice {
+		txs = append(txs, tx)
+	}
+	for _, tx := range data.fundsTxSlice {
+		txs = append(txs, tx)
+	}
+	for _, tx := range data.configTxSlice {
+		txs = append(txs, tx)
+	}
+	for _, tx := range data.stakeTxSlice {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
 //Only blocks with timestamp not diverging from system time (past or future) more than one hour are accepted.
 func timestampCheck(timestamp int64) error {
 	systemTime := p2p.ReadSystemTime()
@@ -802,15 +825,17 @@ func timestampCheck(timestamp int64) error {
 	return nil
 }
 
+//Violations of the proof are returned as invalidBlockError. Conflicting blocks that cannot be fetched (or whose
+//ancestors cannot be found) do not make the proof invalid.
 func slashingCheck(slashedAddress, conflictingBlockHash1, conflictingBlockHash2 [32]byte) (bool, error) {
 	prefix := "Invalid slashing proof: "
 
 	if conflictingBlockHash1 == [32]byte{} || conflictingBlockHash2 == [32]byte{} {
-		return false, errors.New(fmt.Sprintf(prefix + "Invalid conflicting block hashes provided."))
+		return false, &invalidBlockError{prefix + "Invalid conflicting block hashes provided."}
 	}
 
 	if conflictingBlockHash1 == conflictingBlockHash2 {
-		return false, errors.New(fmt.Sprintf(prefix + "Conflicting block hashes are the same."))
+		return false, &invalidBlockError{prefix + "Conflicting block hashes are the same."}
 	}
 
 	//Fetch the blocks for the provided block hashes.
@@ -818,7 +843,7 @@ func slashingCheck(slashedAddress, conflictingBlockHash1, conflictingBlockHash2
 	conflictingBlock2 := storage.ReadClosedBlock(conflictingBlockHash2)
 
 	if IsInSameChain(conflictingBlock1, conflictingBlock2) {
-		return false, errors.New(fmt.Sprintf(prefix + "Conflicting block hashes are on the same chain."))
+		return false, &invalidBlockError{prefix + "Conflicting block hashes are on the same chain."}
 	}
 
 	//TODO Optimize code (duplicated)
@@ -827,14 +852,9 @@ func slashingCheck(slashedAddress, conflictingBlockHash1, conflictingBlockHash2
 		conflictingBlock1 = storage.ReadOpenBlock(conflictingBlockHash1)
 		if conflictingBlock1 == nil {
 			//Fetch the block we apparently missed from the network.
-			p2p.BlockReq(conflictingBlockHash1)
-
-			//Blocking wait
-			select {
-			case encodedBlock := <-p2p.BlockReqChan:
-				conflictingBlock1 = conflictingBlock1.Decode(encodedBlock)
-				//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
-			case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
+			var err error
+			conflictingBlock1, err = p2p.FetchBlock(conflictingBlockHash1, BLOCKFETCH_TIMEOUT*time.Second)
+			if err != nil {
 				return false, errors.New(fmt.Sprintf(prefix + "Could not find a block with the provided conflicting hash (1)."))
 			}
 		}
@@ -851,14 +871,9 @@ func slashingCheck(slashedAddress, conflictingBlockHash1, conflictingBlockHash2
 		conflictingBlock2 = storage.ReadOpenBlock(conflictingBlockHash2)
 		if conflictingBlock2 == nil {
 			//Fetch the block we apparently missed from the network.
-			p2p.BlockReq(conflictingBlockHash2)
-
-			//Blocking wait
-			select {
-			case encodedBlock := <-p2p.BlockReqChan:
-				conflictingBlock2 = conflictingBlock2.Decode(encodedBlock)
-				//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
-			case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
+			var err error
+			conflictingBlock2, err = p2p.FetchBlock(conflictingBlockHash2, BLOCKFETCH_TIMEOUT*time.Second)
+			if err != nil {
 				return false, errors.New(fmt.Sprintf(prefix + "Could not find a block with the provided conflicting hash (2)."))
 			}
 		}
@@ -872,7 +887,7 @@ func slashingCheck(slashedAddress, conflictingBlockHash1, conflictingBlockHash2
 	// We found the height of the blocks and the height of the blocks can be checked.
 	// If the height is not within the active slashing window size, we must throw an error. If not, the proof is valid.
 	if !(conflictingBlock1.Height < uint32(activeParameters.Slashing_window_size)+conflictingBlock2.Height) {
-		return false, errors.New(fmt.Sprintf(prefix + "Could not find a ancestor for the provided conflicting hash (2)."))
+		return false, &invalidBlockError{prefix + "Conflicting blocks are not within the slashing window."}
 	}
 
 	//Delete the proof from local slashing dictionary. If proof has not existed yet, nothing will be deleted.
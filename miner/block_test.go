@@ -127,6 +127,10 @@ func TestMultipleBlocks(t *testing.T) {
 	if err := validate(b4, false); err != nil {
 		t.Errorf("Block validation failed: %v\n", err)
 	}
+
+	if report, err := storage.CheckConsistency(false); err != nil || len(report.Problems) != 0 {
+		t.Errorf("Validated chain is inconsistent: %v %v\n", report.Problems, err)
+	}
 }
 
 //Test the blocktimestamp check
@@ -241,3 +245,27 @@ func createBlockWithTxs(b *protocol.Block) ([][32]byte, [][32]byte, [][32]byte,
 //		t.Errorf("Closed blocks are not equal after genesis block:\n%v\n%v", lastClosedBlocks, lastClosedBlocksAfterGenesis)
 //	}
 //}
+
+//Only blocks that violate the consensus rules are reported as invalid.
+func TestProcessBlockInvalid(t *testing.T) {
+	cleanAndPrepare()
+
+	b := newBlock([32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	createBlockWithTxs(b)
+	if err := finalizeBlock(b); err != nil {
+		t.Fatalf("Block finalization failed (%v)\n", err)
+	}
+
+	tampered := *b
+	tampered.MerkleRoot = [32]byte{1}
+	if err := processBlock(tampered.Encode()); err == nil {
+		t.Error("Block with wrong merkle root not reported as invalid.")
+	}
+
+	//Txs that cannot be fetched do not make the block invalid.
+	missing := *b
+	missing.FundsTxData = append([][32]byte{{1}}, b.FundsTxData[1:]...)
+	if err := processBlock(missing.Encode()); err != nil {
+		t.Errorf("Block with missing tx reported as invalid: %v\n", err)
+	}
+}
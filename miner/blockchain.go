@@ -4,15 +4,15 @@ import (
 	"crypto/ecdsa"
 	"crypto/rsa"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
-	"log"
 	"sync"
 
+	"github.com/bazo-blockchain/bazo-miner/logging"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
 )
 
 var (
-	logger              			*log.Logger
+	logger              			= logging.New(logging.MINER)
 	blockValidation     			= &sync.Mutex{}
 	parameterSlice      			[]Parameters
 	activeParameters    			*Parameters
@@ -32,16 +32,13 @@ func Init(validatorWallet, multisigWallet, rootWallet *ecdsa.PublicKey, validato
 	commPrivKey = validatorCommitment
 	rootCommPrivKey = rootCommitment
 
-	//Set up logger.
-	logger = storage.InitLogger()
-
 	parameterSlice = append(parameterSlice, NewDefaultParameters())
 	activeParameters = &parameterSlice[0]
 
 	//Initialize root key.
 	initRootKey(rootWallet)
 	if err != nil {
-		logger.Printf("Could not create a root account.\n")
+		logger.Errorf("Could not create a root account.")
 	}
 
 	currentTargetTime = new(timerange)
@@ -49,14 +46,18 @@ func Init(validatorWallet, multisigWallet, rootWallet *ecdsa.PublicKey, validato
 
 	initialBlock, err := initState()
 	if err != nil {
-		logger.Printf("Could not set up initial state: %v.\n", err)
+		logger.Errorf("Could not set up initial state: %v.", err)
 		return
 	}
 
-	logger.Printf("Active config params:%v", activeParameters)
+	reloadMempool()
+	close(initialized)
+
+	logger.Infof("Active config params: %v", activeParameters)
 
 	//Start to listen to network inputs (txs and blocks).
 	go incomingData()
+	go admitTxs()
 	mining(initialBlock)
 }
 
@@ -67,18 +68,19 @@ func mining(initialBlock *protocol.Block) {
 	for {
 		err := finalizeBlock(currentBlock)
 		if err != nil {
-			logger.Printf("%v\n", err)
+			logger.Infof("%v", err)
 		} else {
-			logger.Printf("Block mined (%x)\n", currentBlock.Hash[0:8])
+			blockLogger(currentBlock).Infof("Block mined")
 		}
 
 		if err == nil {
 			broadcastBlock(currentBlock)
 			err := validate(currentBlock, false)
 			if err == nil {
-				logger.Printf("Validated block: %vState:\n%v", currentBlock, getState())
+				blockLogger(currentBlock).Infof("Validated block")
+				logState()
 			} else {
-				logger.Printf("Received block (%x) could not be validated: %v\n", currentBlock.Hash[0:8], err)
+				blockLogger(currentBlock).Warnf("Mined block could not be validated: %v", err)
 			}
 		}
 
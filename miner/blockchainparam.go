@@ -2,6 +2,7 @@ package miner
 
 import (
 	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/p2p"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
 	"math"
@@ -79,13 +80,14 @@ func collectStatistics(b *protocol.Block) {
 
 		targetTimes = append(targetTimes, *currentTargetTime)
 
-		logger.Printf("Target changed, new target: %v", target[len(target)-1])
+		blockLogger(b).Infof("Target changed, new target: %v", target[len(target)-1])
 		localBlockCount = 0
 		currentTargetTime = new(timerange)
 		currentTargetTime.first = b.Timestamp
 	}
 
 	lastBlock = b
+	p2p.SetBestHeight(lastBlock.Height)
 }
 
 func collectStatisticsRollback(b *protocol.Block) {
@@ -103,6 +105,9 @@ func collectStatisticsRollback(b *protocol.Block) {
 	}
 
 	lastBlock = storage.ReadClosedBlock(b.PrevHash)
+	if lastBlock != nil {
+		p2p.SetBestHeight(lastBlock.Height)
+	}
 }
 
 func calculateNewDifficulty(t *timerange) uint8 {
@@ -3,31 +3,22 @@ package miner
 import (
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
-	"sort"
 )
 
-//The code here is needed if a new block is built. All open (not yet validated) transactions are first fetched
-//from the mempool and then sorted. The sorting is important because if transactions are fetched from the mempool
-//they're received in random order (because it's implemented as a map). However, if a user wants to issue more fundsTxs
-//they need to be sorted according to increasing txCnt, this greatly increases throughput.
-
-type openTxs []protocol.Transaction
+//The code here is needed if a new block is built. All open (not yet validated) transactions are fetched from the
+//mempool. Txs other than fundsTxs don't depend on each other and are added first. FundsTxs of the same sender need
+//to be added with increasing txCnt. The mempool keeps them per sender, only pending fundsTxs (whose txCnt follows
+//the state without gaps) are added. Queued fundsTxs stay in the mempool until the missing txCnt arrives.
 
 func prepareBlock(block *protocol.Block) {
-	//Fetch all txs from mempool (opentxs).
-	opentxs := storage.ReadAllOpenTxs()
-
-	//This copy is strange, but seems to be necessary to leverage the sort interface.
-	//Shouldn't be too bad because no deep copy.
-	var tmpCopy openTxs
-	tmpCopy = opentxs
-
-	sort.Sort(tmpCopy)
+	for _, tx := range storage.ReadAllOpenTxs() {
+		if _, isFundsTx := tx.(*protocol.FundsTx); isFundsTx {
+			continue
+		}
 
-	for _, tx := range opentxs {
 		//Prevent block size to overflow.
 		if block.GetSize()+tx.Size() > activeParameters.Block_size {
-			break
+			return
 		}
 
 		err := addTx(block, tx)
@@ -36,40 +27,29 @@ func prepareBlock(block *protocol.Block) {
 			storage.DeleteOpenTx(tx)
 		}
 	}
-}
 
-//Implement the sort interface
-func (f openTxs) Len() int {
-	return len(f)
-}
-
-func (f openTxs) Swap(i, j int) {
-	f[i], f[j] = f[j], f[i]
-}
+	pending, _, stale := storage.ReadFundsTxQueues()
 
-func (f openTxs) Less(i, j int) bool {
-	//Comparison only makes sense if both tx are fundsTxs.
-	//Why can we only do that with switch, and not e.g., if tx.(type) == ..?
-	switch f[i].(type) {
-	case *protocol.AccTx:
-		//We only want to sort a subset of all transactions, namely all fundsTxs.
-		//However, to successfully do that we have to place all other txs at the beginning.
-		//The order between accTxs and configTxs doesn't matter.
-		return true
-	case *protocol.ConfigTx:
-		return true
-	case *protocol.StakeTx:
-		return true
+	//The txCnt of stale txs has already been used.
+	for _, tx := range stale {
+		storage.DeleteOpenTx(tx)
 	}
 
-	switch f[j].(type) {
-	case *protocol.AccTx:
-		return false
-	case *protocol.ConfigTx:
-		return false
-	case *protocol.StakeTx:
-		return false
-	}
+	//Pending txs are ordered by sender and txCnt. If a tx fails, the following txs of the same sender would fail too.
+	failed := make(map[[32]byte]bool)
+	for _, tx := range pending {
+		if failed[tx.From] {
+			continue
+		}
 
-	return f[i].(*protocol.FundsTx).TxCnt < f[j].(*protocol.FundsTx).TxCnt
+		if block.GetSize()+tx.Size() > activeParameters.Block_size {
+			return
+		}
+
+		err := addTx(block, tx)
+		if err != nil {
+			storage.DeleteOpenTx(tx)
+			failed[tx.From] = true
+		}
+	}
 }
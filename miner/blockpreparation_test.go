@@ -63,3 +63,39 @@ func TestPrepareAndSortTxs(t *testing.T) {
 		t.Errorf("NrFundsTx (%v) vs. testsize*2 (%v)\n", b.NrFundsTx, testsize*2)
 	}
 }
+
+func TestPrepareBlockNonceGap(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	txCnt := storage.State[accAHash].TxCnt
+
+	var txs []*protocol.FundsTx
+	for cnt := txCnt; cnt < txCnt+5; cnt++ {
+		tx, _ := protocol.ConstrFundsTx(0x01, 1, 1, cnt, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+		txs = append(txs, tx)
+	}
+
+	//The tx with txCnt+2 is missing, the last two txs are queued
+	for _, tx := range []*protocol.FundsTx{txs[0], txs[1], txs[3], txs[4]} {
+		storage.WriteOpenTx(tx)
+	}
+
+	b := newBlock([32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	prepareBlock(b)
+	if len(b.FundsTxData) != 2 {
+		t.Errorf("Block contains %v fundsTxs instead of the 2 pending ones\n", len(b.FundsTxData))
+	}
+	if storage.ReadOpenTx(txs[3].Hash()) == nil || storage.ReadOpenTx(txs[4].Hash()) == nil {
+		t.Error("Queued txs were removed from the mempool\n")
+	}
+
+	//Filling the gap promotes the queued txs
+	storage.WriteOpenTx(txs[2])
+	b = newBlock([32]byte{}, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	prepareBlock(b)
+	if len(b.FundsTxData) != 5 {
+		t.Errorf("Block contains %v fundsTxs instead of 5 after the gap was filled\n", len(b.FundsTxData))
+	}
+}
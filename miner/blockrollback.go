@@ -87,25 +87,22 @@ func validateStateRollback(data blockData) {
 }
 
 func postValidateRollback(data blockData) {
-	//Put all validated txs into invalidated state.
+	//Put all validated txs into invalidated state. They are needed in the mempool to validate the blocks of the new
+	//chain and to be mined again if the new chain does not contain them.
 	for _, tx := range data.accTxSlice {
-		storage.WriteOpenTx(tx)
-		storage.DeleteClosedTx(tx)
+		reopenTx(tx)
 	}
 
 	for _, tx := range data.fundsTxSlice {
-		storage.WriteOpenTx(tx)
-		storage.DeleteClosedTx(tx)
+		reopenTx(tx)
 	}
 
 	for _, tx := range data.configTxSlice {
-		storage.WriteOpenTx(tx)
-		storage.DeleteClosedTx(tx)
+		reopenTx(tx)
 	}
 
 	for _, tx := range data.stakeTxSlice {
-		storage.WriteOpenTx(tx)
-		storage.DeleteClosedTx(tx)
+		reopenTx(tx)
 	}
 
 	collectStatisticsRollback(data.block)
@@ -117,4 +114,36 @@ func postValidateRollback(data blockData) {
 	//Save the previous block as the last closed block.
 	storage.DeleteAllLastClosedBlock()
 	storage.WriteLastClosedBlock(storage.ReadClosedBlock(data.block.PrevHash))
+
+	if err := rollbackStateSnapshot(); err != nil {
+		logger.Errorf("Could not rewrite state snapshot: %v", err)
+	}
+}
+
+func reopenTx(tx protocol.Transaction) {
+	if err := storage.WriteOpenTx(tx); err != nil {
+		txLogger(tx).Warnf("Rolled back tx could not be written to the mempool: %v", err)
+	}
+	storage.DeleteClosedTx(tx)
+}
+
+//Called after the blocks of the new chain have been validated. Rolled back txs that are part of the new chain have
+//been removed from the mempool already. The remaining ones are dropped if they are no longer valid on the new chain,
+//e.g., because the new chain contains a different tx with the same txCnt.
+func recheckRolledBackTxs(blocks []*protocol.Block) {
+	for _, block := range blocks {
+		for _, txHashes := range [][][32]byte{block.AccTxData, block.FundsTxData, block.ConfigTxData, block.StakeTxData} {
+			for _, txHash := range txHashes {
+				tx := storage.ReadOpenTx(txHash)
+				if tx == nil {
+					continue
+				}
+
+				if err := checkTxState(tx); err != nil {
+					txLogger(tx).Infof("Dropped rolled back tx: %v", err)
+					storage.DeleteOpenTx(tx)
+				}
+			}
+		}
+	}
 }
@@ -41,11 +41,28 @@ func TestValidateBlockRollback(t *testing.T) {
 		t.Errorf("State wasn't changed despite validating a block!\n%v\n\n%v", accsBefore, accsAfter)
 	}
 
+	if block := storage.ReadClosedBlockByHeight(b.Height); block == nil || block.Hash != b.Hash {
+		t.Error("Validated block was not indexed by height")
+	}
+
 	err := rollback(b)
 	if err != nil {
 		t.Errorf("%v\n", err)
 	}
 
+	//Indexes have to be rolled back together with the block and its txs
+	if storage.ReadClosedBlockByHeight(b.Height) != nil {
+		t.Error("Height index wasn't rolled back")
+	}
+	for _, txHash := range b.FundsTxData {
+		if storage.ReadTxLocation(txHash) != nil {
+			t.Error("Tx location index wasn't rolled back")
+		}
+	}
+	if len(storage.ReadAccountTxHashes(protocol.SerializeHashContent(accA.Address))) != 0 {
+		t.Error("Account index wasn't rolled back")
+	}
+
 	for _, acc := range storage.State {
 		accsBefore2[acc.Address] = *acc
 	}
@@ -182,6 +199,78 @@ func TestMultipleBlocksRollback(t *testing.T) {
 	}
 }
 
+//Tests whether txs of rolled back blocks are returned to the mempool if they are still valid on the new chain
+func TestRollbackReturnsTxsToMempool(t *testing.T) {
+	cleanAndPrepare()
+
+	genesis := lastBlock
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	txCnt := storage.State[accAHash].TxCnt
+
+	valid, _ := protocol.ConstrFundsTx(0x01, 10, 1, txCnt, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+
+	//Both branches create the same account
+	stale, newAccKey, _ := protocol.ConstrAccTx(0, 1, [64]byte{}, PrivKeyRoot, nil, nil)
+	competing, _, _ := protocol.ConstrAccTx(0, 2, crypto.GetAddressFromPubKey(&newAccKey.PublicKey), PrivKeyRoot, nil, nil)
+
+	//Competing branch: genesis <- c <- c2
+	c := newBlock(genesis.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	if err := addTx(c, competing); err != nil {
+		t.Fatalf("Could not add tx: %v\n", err)
+	}
+	storage.WriteOpenTx(competing)
+	if err := finalizeBlock(c); err != nil {
+		t.Fatal(err)
+	}
+	storage.WriteOpenBlock(c)
+
+	//PoW needs lastBlock, have to set it manually
+	lastBlock = c
+	c2 := newBlock(c.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, 2)
+	if err := finalizeBlock(c2); err != nil {
+		t.Fatal(err)
+	}
+
+	//Current branch: genesis <- b
+	lastBlock = genesis
+	b := newBlock(genesis.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, 1)
+	for _, tx := range []protocol.Transaction{valid, stale} {
+		if err := addTx(b, tx); err != nil {
+			t.Fatalf("Could not add tx: %v\n", err)
+		}
+		storage.WriteOpenTx(tx)
+	}
+	if err := finalizeBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := validate(b, false); err != nil {
+		t.Fatalf("Could not validate block: %v\n", err)
+	}
+
+	if storage.ReadOpenTx(valid.Hash()) != nil || storage.ReadOpenTx(stale.Hash()) != nil {
+		t.Fatal("Validated txs are still in the mempool")
+	}
+
+	//The longer branch replaces b
+	if err := validate(c2, false); err != nil {
+		t.Fatalf("Could not validate longer branch: %v\n", err)
+	}
+	if lastBlock.Hash != c2.Hash {
+		t.Fatal("Longer branch was not adopted")
+	}
+
+	if storage.ReadOpenTx(valid.Hash()) == nil || storage.ReadClosedTx(valid.Hash()) != nil {
+		t.Error("Still valid tx of the rolled back block was not returned to the mempool")
+	}
+	if storage.ReadOpenTx(stale.Hash()) != nil || storage.ReadClosedTx(stale.Hash()) != nil {
+		t.Error("Tx that is invalid on the new chain was returned to the mempool")
+	}
+	if storage.ReadOpenTx(competing.Hash()) != nil || storage.ReadClosedTx(competing.Hash()) == nil {
+		t.Error("Tx of the new chain was not validated")
+	}
+}
+
 // resetStakingBlockHeight sets the StackingBlockHeight of all accounts to 0.
 // This is needed so that the other fields can get tested.
 // TODO Remove this function if rollback of StakingBlockHeight gets implemented.
@@ -5,8 +5,11 @@ const (
 	//that this dynamic check is not possible anymore?!
 	DELAYED_BLOCKS = 10
 
-	TXFETCH_TIMEOUT    = 5  //Sec
-	BLOCKFETCH_TIMEOUT = 40 //Sec
+	TXFETCH_TIMEOUT    = 5   //Sec
+	BLOCKFETCH_TIMEOUT = 40  //Sec
+	BLOCKFETCH_BATCH   = 500 //Blocks fetched at once if we are behind
+	SYNC_PEERS         = 3   //Miners whose header chains are compared when synchronizing
+	SYNC_RETRIES       = 3   //Attempts to fetch a block of the synchronized chain before the chain is dropped
 
 	//Some prominent programming languages (e.g., Java) have not unsigned integer types
 	//Neglecting MSB simplifies compatibility
@@ -26,4 +29,7 @@ const (
 	SLASHING_WINDOW_SIZE = 100     //Blocks
 	SLASH_REWARD         = 2       //Coins
 	NUM_INCL_PREV_PROOFS = 5       //Number of previous proofs included in the PoS condition
+
+	//Number of blocks kept in addition to the slashing window if pruning is enabled
+	PRUNE_DEPTH = 100
 )
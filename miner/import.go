@@ -0,0 +1,102 @@
+package miner
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Set while a chain archive is imported. Imported blocks are old, so the dynamic timestamp check is skipped, and
+//validated txs are not broadcast since there is no network.
+var importing bool
+
+//Imports a chain archive into an empty database. Every block is validated the same way as a block received from
+//the network. The archive needs to start with the genesis block. Returns the number of imported blocks.
+func Import(r io.Reader, rootWallet *ecdsa.PublicKey, rootCommitment *rsa.PrivateKey) (count int, err error) {
+	rootCommPrivKey = rootCommitment
+
+	parameterSlice = append(parameterSlice, NewDefaultParameters())
+	activeParameters = &parameterSlice[0]
+
+	initRootKey(rootWallet)
+
+	currentTargetTime = new(timerange)
+	target = append(target, 15)
+
+	return importChain(r)
+}
+
+func importChain(r io.Reader) (count int, err error) {
+	if storage.ReadLastClosedBlock() != nil {
+		return 0, errors.New("Chain archives can only be imported into an empty database.")
+	}
+
+	archive, err := storage.NewArchiveReader(r)
+	if err != nil {
+		return 0, err
+	}
+
+	importing = true
+	defer func() {
+		importing = false
+	}()
+
+	for {
+		block, txs, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+
+		if count == 0 {
+			if err := writeGenesis(block, txs); err != nil {
+				return count, err
+			}
+			count++
+			continue
+		}
+
+		for _, tx := range txs {
+			//Txs that are already pending (e.g., restored from the mempool journal) are not added again.
+			if storage.ReadOpenTx(tx.Hash()) != nil {
+				continue
+			}
+			if err := storage.WriteOpenTx(tx); err != nil {
+				txHash := tx.Hash()
+				return count, errors.New(fmt.Sprintf("Tx (%x) of block with height %v could not be written: %v", txHash[0:8], block.Height, err))
+			}
+		}
+
+		if err := validate(block, false); err != nil {
+			return count, errors.New(fmt.Sprintf("Block (%x) with height %v could not be validated: %v", block.Hash[0:8], block.Height, err))
+		}
+
+		count++
+		if count%100 == 0 {
+			logger.Infof("Imported %v blocks", count)
+		}
+	}
+
+	return count, nil
+}
+
+//The genesis block is not validated (see initState), it is the common starting point of imported and synchronized
+//chains.
+func writeGenesis(block *protocol.Block, txs []protocol.Transaction) error {
+	if block.Height != 0 || len(txs) > 0 {
+		return errors.New("Chain archive does not start with the genesis block.")
+	}
+
+	storage.WriteClosedBlock(block)
+	storage.WriteLastClosedBlock(block)
+	postValidate(blockData{nil, nil, nil, nil, block}, true)
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package miner
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Tests whether an exported chain can be imported into an empty database and results in the same state
+func TestExportImportChain(t *testing.T) {
+	cleanAndPrepare()
+
+	validatorAddress := validatorAccAddress
+	validator := *storage.State[protocol.SerializeHashContent(validatorAddress)]
+
+	var blocks []*protocol.Block
+	prevHash := [32]byte{}
+	for height := uint32(1); height <= 3; height++ {
+		b := newBlock(prevHash, [crypto.COMM_PROOF_LENGTH]byte{}, height)
+		createBlockWithTxs(b)
+		finalizeBlock(b)
+		if err := validate(b, false); err != nil {
+			t.Fatalf("Block validation failed: %v\n", err)
+		}
+		blocks = append(blocks, b)
+		prevHash = b.Hash
+	}
+
+	balancesBefore := make(map[[32]byte]uint64)
+	for hash, acc := range storage.State {
+		balancesBefore[hash] = acc.Balance
+	}
+
+	archive := new(bytes.Buffer)
+	count, err := storage.ExportChain(archive, 0, 3)
+	if err != nil || count != 4 {
+		t.Fatalf("Could not export chain (%v blocks): %v\n", count, err)
+	}
+	encoded := archive.Bytes()
+
+	//Importing into a database that contains a chain must fail
+	if _, err := importChain(bytes.NewBuffer(encoded)); err == nil {
+		t.Error("Chain was imported into a non-empty database")
+	}
+
+	cleanAndPrepare()
+	storage.DeleteAll()
+
+	//cleanAndPrepare creates a new validator, the imported blocks were validated by the previous one.
+	delete(storage.State, protocol.SerializeHashContent(validatorAccAddress))
+	validatorAccAddress = validatorAddress
+	storage.State[protocol.SerializeHashContent(validatorAddress)] = &validator
+
+	count, err = importChain(bytes.NewBuffer(encoded))
+	if err != nil || count != 4 {
+		t.Fatalf("Could not import chain (%v blocks): %v\n", count, err)
+	}
+
+	balancesAfter := make(map[[32]byte]uint64)
+	for hash, acc := range storage.State {
+		balancesAfter[hash] = acc.Balance
+	}
+	if !reflect.DeepEqual(balancesBefore, balancesAfter) {
+		t.Errorf("State after import differs:\n%v\n\n%v\n", balancesBefore, balancesAfter)
+	}
+
+	for _, b := range blocks {
+		if block := storage.ReadClosedBlockByHeight(b.Height); block == nil || block.Hash != b.Hash {
+			t.Errorf("Block with height %v was not imported\n", b.Height)
+		}
+		for _, txHash := range b.FundsTxData {
+			if storage.ReadClosedTx(txHash) == nil {
+				t.Errorf("Tx %x of block with height %v was not imported\n", txHash[0:8], b.Height)
+			}
+		}
+	}
+
+	if lastBlock.Hash != blocks[2].Hash {
+		t.Errorf("Last block after import should be %x but is %x\n", blocks[2].Hash[0:8], lastBlock.Hash[0:8])
+	}
+}
@@ -1,7 +1,6 @@
 package miner
 
 import (
-	"errors"
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/p2p"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
@@ -9,6 +8,16 @@ import (
 	"time"
 )
 
+//Returned if a block does not extend the longest chain. Such blocks are not necessarily invalid (e.g., competing blocks
+//of the same height), their senders are not penalized.
+type chainError struct {
+	reason string
+}
+
+func (err *chainError) Error() string {
+	return err.reason
+}
+
 //Function to give a list of blocks to rollback (in the right order) and a list of blocks to validate.
 //Covers both cases (if block belongs to the longest chain or not).
 func getBlockSequences(newBlock *protocol.Block) (blocksToRollback, blocksToValidate []*protocol.Block, err error) {
@@ -17,7 +26,7 @@ func getBlockSequences(newBlock *protocol.Block) (blocksToRollback, blocksToVali
 
 	//Common ancestor not found, discard block.
 	if ancestor == nil {
-		return nil, nil, errors.New("Common ancestor not found.")
+		return nil, nil, &chainError{"Common ancestor not found."}
 	}
 
 	//Count how many blocks there are on the currently active chain.
@@ -35,7 +44,7 @@ func getBlockSequences(newBlock *protocol.Block) (blocksToRollback, blocksToVali
 	//Compare current length with new chain length.
 	if len(blocksToRollback) >= len(newChain) {
 		//Current chain length is longer or equal (our consensus protocol states that in this case we reject the block).
-		return nil, nil, errors.New(fmt.Sprintf("Block belongs to shorter or equally long chain (blocks to rollback %d vs block of new chain %d)", len(blocksToRollback), len(newChain)))
+		return nil, nil, &chainError{fmt.Sprintf("Block belongs to shorter or equally long chain (blocks to rollback %d vs block of new chain %d)", len(blocksToRollback), len(newChain))}
 	} else {
 		//New chain is longer, rollback and validate new chain.
 		return blocksToRollback, newChain, nil
@@ -45,6 +54,10 @@ func getBlockSequences(newBlock *protocol.Block) (blocksToRollback, blocksToVali
 //Returns the ancestor from which the split occurs (if a split occurred, if not it's just our last block) and a list
 //of blocks that belong to a new chain.
 func getNewChain(newBlock *protocol.Block) (ancestor *protocol.Block, newChain []*protocol.Block) {
+	//Blocks fetched in batches, indexed by hash. The batches cover the heights from fetchedFrom up to the new block.
+	fetchedBlocks := make(map[[32]byte]*protocol.Block)
+	fetchedFrom := newBlock.Height
+
 	for {
 		newChain = append(newChain, newBlock)
 
@@ -66,19 +79,44 @@ func getNewChain(newBlock *protocol.Block) (ancestor *protocol.Block, newChain [
 			continue
 		}
 
-		//TODO Optimize code (duplicated)
+		if height := newChain[len(newChain)-1].Height; fetchedBlocks[prevBlockHash] == nil && height <= fetchedFrom {
+			var blocks []*protocol.Block
+			blocks, fetchedFrom = fetchMissingBlocks(height)
+			for _, block := range blocks {
+				fetchedBlocks[block.Hash] = block
+			}
+		}
+		if newBlock = fetchedBlocks[prevBlockHash]; newBlock != nil {
+			continue
+		}
+
 		//Fetch the block we apparently missed from the network.
-		p2p.BlockReq(prevBlockHash)
-
-		//Blocking wait
-		select {
-		case encodedBlock := <-p2p.BlockReqChan:
-			newBlock = newBlock.Decode(encodedBlock)
-			//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
-		case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
+		var err error
+		if newBlock, err = p2p.FetchBlock(prevBlockHash, BLOCKFETCH_TIMEOUT*time.Second); err != nil {
+			logger.Warnf("Could not fetch block %x: %v", prevBlockHash[:8], err)
 			return nil, nil
 		}
 	}
 
 	return nil, nil
 }
+
+//If several blocks below the given height are missing (e.g., we are behind), up to BLOCKFETCH_BATCH of them are
+//fetched in batches. Returns the fetched blocks and the lowest height that was requested.
+func fetchMissingBlocks(height uint32) (blocks []*protocol.Block, from uint32) {
+	if height <= lastBlock.Height+2 {
+		return nil, height
+	}
+
+	from = lastBlock.Height + 1
+	if height-from > BLOCKFETCH_BATCH {
+		from = height - BLOCKFETCH_BATCH
+	}
+
+	blocks, err := p2p.FetchBlocks(from, height-1, BLOCKFETCH_TIMEOUT*time.Second)
+	if err != nil {
+		logger.Debugf("Could not fetch all blocks from height %v to %v: %v", from, height-1, err)
+	}
+
+	return blocks, from
+}
@@ -8,11 +8,11 @@ import (
 	"fmt"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"io/ioutil"
-	"log"
 	"math/big"
 	"os"
 	"testing"
 
+	"github.com/bazo-blockchain/bazo-miner/logging"
 	"github.com/bazo-blockchain/bazo-miner/p2p"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
@@ -249,8 +249,7 @@ func TestMain(m *testing.M) {
 	addTestingAccounts()
 	addRootAccounts()
 	//We don't want logging msgs when testing, we have designated messages
-	logger = log.New(nil, "", 0)
-	logger.SetOutput(ioutil.Discard)
+	logging.SetOutput(ioutil.Discard)
 	retCode := m.Run()
 
 	//Teardown
@@ -0,0 +1,81 @@
+package miner
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Loads the txs that were pending when the miner was stopped. Txs that have been validated in the meantime (e.g.,
+//received with the chain from other miners) or that are no longer valid with respect to the current state are dropped.
+func reloadMempool() {
+	var reloaded, dropped int
+	for _, tx := range storage.ReadJournaledTxs() {
+		if storage.ReadClosedTx(tx.Hash()) != nil {
+			storage.DeleteOpenTx(tx)
+			dropped++
+			continue
+		}
+
+		if err := checkTxState(tx); err != nil {
+			txLogger(tx).Infof("Dropped journaled tx: %v", err)
+			storage.DeleteOpenTx(tx)
+			dropped++
+			continue
+		}
+
+		storage.WriteOpenTx(tx)
+		reloaded++
+	}
+
+	if err := storage.FlushMempoolJournal(); err != nil {
+		logger.Errorf("Could not journal the mempool: %v", err)
+	}
+	logger.Infof("Reloaded %v tx(s) into the mempool, dropped %v tx(s).", reloaded, dropped)
+}
+
+//Checks txs received from the network before they enter the mempool. The state must not change during the check.
+func admitTxs() {
+	for {
+		admission := <-p2p.TxAdmissionChan
+		blockValidation.Lock()
+		admission.Result <- checkTxState(admission.Tx)
+		blockValidation.Unlock()
+	}
+}
+
+//Checks whether a tx can still be included in a future block given the current state. In contrast to addTx, txs
+//whose tx count lies in the future are accepted since their predecessors might still arrive. The balance is only
+//checked against the state, not against other txs of the sender in the mempool.
+func checkTxState(tx protocol.Transaction) error {
+	if tx.TxFee() < activeParameters.Fee_minimum {
+		return p2p.NewTxError(p2p.TX_ERR_FEE, "Transaction fee too low: %v (minimum is: %v)", tx.TxFee(), activeParameters.Fee_minimum)
+	}
+
+	if !verify(tx) {
+		return p2p.NewTxError(p2p.TX_ERR_SIGNATURE, "Transaction could not be verified.")
+	}
+
+	switch tx := tx.(type) {
+	case *protocol.FundsTx:
+		acc := storage.State[tx.From]
+		if tx.TxCnt < acc.TxCnt {
+			return p2p.NewTxError(p2p.TX_ERR_TXCNT, "Sender txCnt already used: %v (tx.txCnt) vs. %v (state txCnt)", tx.TxCnt, acc.TxCnt)
+		}
+		if !storage.IsRootKey(tx.From) && tx.Amount+tx.Fee > acc.Balance {
+			return p2p.NewTxError(p2p.TX_ERR_BALANCE, "Not enough funds: %v (amount + fee) vs. %v (balance)", tx.Amount+tx.Fee, acc.Balance)
+		}
+	case *protocol.AccTx:
+		if tx.Header&0x02 != 0x02 {
+			if _, exists := storage.State[protocol.SerializeHashContent(tx.PubKey)]; exists {
+				return p2p.NewTxError(p2p.TX_ERR_STATE, "Account already exists.")
+			}
+		}
+	case *protocol.StakeTx:
+		if acc := storage.State[tx.Account]; acc != nil && acc.IsStaking == tx.IsStaking {
+			return p2p.NewTxError(p2p.TX_ERR_STATE, "Account has bool already set to the desired value.")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Tests whether journaled txs that are no longer valid are dropped on reload
+func TestReloadMempool(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	storage.State[accAHash].TxCnt = 5
+
+	valid, _ := protocol.ConstrFundsTx(0x01, 10, 1, 5, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+	future, _ := protocol.ConstrFundsTx(0x01, 10, 1, 7, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+	stale, _ := protocol.ConstrFundsTx(0x01, 10, 1, 4, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+	closed, _ := protocol.ConstrFundsTx(0x01, 20, 1, 6, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+
+	for _, tx := range []*protocol.FundsTx{valid, future, stale, closed} {
+		storage.WriteOpenTx(tx)
+	}
+	storage.WriteClosedTx(closed)
+	storage.FlushMempoolJournal()
+
+	reloadMempool()
+
+	if storage.ReadOpenTx(valid.Hash()) == nil || storage.ReadOpenTx(future.Hash()) == nil {
+		t.Error("Valid journaled txs were not reloaded")
+	}
+
+	if storage.ReadOpenTx(stale.Hash()) != nil || storage.ReadOpenTx(closed.Hash()) != nil {
+		t.Error("Invalid journaled txs were reloaded")
+	}
+
+	if len(storage.ReadJournaledTxs()) != 2 {
+		t.Errorf("Journal should contain 2 txs, got %v\n", len(storage.ReadJournaledTxs()))
+	}
+}
+
+//Tests whether txs are rejected at admission with the right error code
+func TestCheckTxState(t *testing.T) {
+	cleanAndPrepare()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	storage.State[accAHash].TxCnt = 5
+
+	valid, _ := protocol.ConstrFundsTx(0x01, 10, 1, 5, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+	lowFee, _ := protocol.ConstrFundsTx(0x01, 10, 0, 5, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+	wrongSig, _ := protocol.ConstrFundsTx(0x01, 10, 1, 5, accAHash, accBHash, PrivKeyAccB, PrivKeyMultiSig, nil)
+	stale, _ := protocol.ConstrFundsTx(0x01, 10, 1, 4, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+	tooMuch, _ := protocol.ConstrFundsTx(0x01, accA.Balance, 1, 5, accAHash, accBHash, PrivKeyAccA, PrivKeyMultiSig, nil)
+
+	if err := checkTxState(valid); err != nil {
+		t.Errorf("Valid tx was rejected: %v\n", err)
+	}
+
+	rejected := map[uint8]*protocol.FundsTx{
+		p2p.TX_ERR_FEE:       lowFee,
+		p2p.TX_ERR_SIGNATURE: wrongSig,
+		p2p.TX_ERR_TXCNT:     stale,
+		p2p.TX_ERR_BALANCE:   tooMuch,
+	}
+	for code, tx := range rejected {
+		err := checkTxState(tx)
+		if txErr, ok := err.(*p2p.TxError); !ok || txErr.Code != code {
+			t.Errorf("Tx was not rejected with code %v: %v\n", code, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package miner
+
+import (
+	"sync"
+	"time"
+)
+
+//Metrics are kept separately from the state such that they can be read while a block is validated.
+type Metrics struct {
+	Height             uint32
+	LastBlockTimestamp int64
+	Target             uint8
+	Parameters         Parameters
+	Validations        uint64
+	ValidationFailures uint64
+	ValidationTime     time.Duration
+	Rollbacks          uint64
+	RollbackTime       time.Duration
+	Reorgs             uint64
+	SyncHeight         uint32
+	SyncTarget         uint32
+}
+
+var (
+	metrics      Metrics
+	metricsMutex = &sync.Mutex{}
+)
+
+func ReadMetrics() Metrics {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	return metrics
+}
+
+//Called at the end of every validation while the blockValidation mutex is held.
+func recordValidation(duration time.Duration, err error) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	if err != nil {
+		metrics.ValidationFailures++
+	} else {
+		metrics.Validations++
+	}
+	metrics.ValidationTime += duration
+
+	if lastBlock != nil {
+		metrics.Height = lastBlock.Height
+		metrics.LastBlockTimestamp = lastBlock.Timestamp
+	}
+	if len(target) > 0 {
+		metrics.Target = target[len(target)-1]
+	}
+	if activeParameters != nil {
+		metrics.Parameters = *activeParameters
+	}
+}
+
+func recordRollback(duration time.Duration) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	metrics.Rollbacks++
+	metrics.RollbackTime += duration
+}
+
+//Called while the bodies are downloaded in the initial synchronization.
+func recordSyncProgress(height, target uint32) {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	metrics.SyncHeight = height
+	metrics.SyncTarget = target
+}
+
+func recordReorg() {
+	metricsMutex.Lock()
+	defer metricsMutex.Unlock()
+
+	metrics.Reorgs++
+}
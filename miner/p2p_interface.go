@@ -12,28 +12,40 @@ import (
 func incomingData() {
 	for {
 		block := <-p2p.BlockIn
-		processBlock(block)
+		block.Result <- processBlock(block.Payload)
 	}
 }
 
-func processBlock(payload []byte) {
+//Invalid blocks are reported to the p2p package such that the sender can be penalized.
+func processBlock(payload []byte) error {
 	var block *protocol.Block
 	block = block.Decode(payload)
+	if block == nil {
+		return &invalidBlockError{"Block could not be decoded."}
+	}
 
 	//Block already confirmed and validated
 	if storage.ReadClosedBlock(block.Hash) != nil {
-		logger.Printf("Received block (%x) has already been validated.\n", block.Hash[0:8])
-		return
+		blockLogger(block).Debugf("Received block has already been validated")
+		return nil
 	}
 
 	//Start validation process
 	err := validate(block, false)
 	if err == nil {
-		logger.Printf("Validated block: %vState:\n%v", block, getState())
+		blockLogger(block).Infof("Validated block")
+		logState()
 		broadcastBlock(block)
 	} else {
-		logger.Printf("Received block (%x) could not be validated: %v\n", block.Hash[0:8], err)
+		blockLogger(block).Warnf("Received block could not be validated: %v", err)
+	}
+
+	//Blocks of other chains, blocks that were validated concurrently (e.g., received from another miner) and blocks that
+	//could not be validated for local reasons are not invalid.
+	if _, ok := err.(*invalidBlockError); !ok || storage.ReadClosedBlock(block.Hash) != nil {
+		return nil
 	}
+	return err
 }
 
 //p2p.BlockOut is a channel whose data get consumed by the p2p package
@@ -0,0 +1,181 @@
+package miner
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+
+	"github.com/bazo-blockchain/bazo-miner/logging"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+var (
+	pruning    bool
+	pruneDepth uint64
+)
+
+//Consensus bookkeeping that is otherwise rebuilt by replaying the chain from genesis. The timeranges are flattened
+//because gob does not encode unexported fields.
+type minerSnapshot struct {
+	Parameters        []Parameters
+	Target            []uint8
+	TargetTimes       [][2]int64
+	CurrentTargetTime [2]int64
+	GlobalBlockCount  int64
+	LocalBlockCount   int64
+}
+
+//Validators only need the blocks of the slashing window (and some more for deep forks). In pruning mode all block
+//bodies and txs older than Slashing_window_size + depth blocks are deleted, only the headers are kept.
+//Needs to be called before Init.
+func EnablePruning(depth uint64) {
+	pruning = true
+	pruneDepth = depth
+}
+
+//Persists the current state and deletes all block bodies that are out of range. Called after every successful block
+//validation while the blockValidation mutex is held.
+func pruneChain() error {
+	if !pruning || lastBlock == nil {
+		return nil
+	}
+
+	//The snapshot is written first. Should we crash in between, the blocks that are not pruned yet are still
+	//available and the state at the tip is already persisted.
+	if err := writeStateSnapshot(lastBlock); err != nil {
+		return err
+	}
+
+	keep := activeParameters.Slashing_window_size + pruneDepth
+	if uint64(lastBlock.Height) <= keep {
+		return nil
+	}
+
+	pruneHeight := lastBlock.Height - uint32(keep)
+	//The genesis block has no txs, we start pruning at height 1.
+	for height := storage.ReadPrunedHeight() + 1; height <= pruneHeight; height++ {
+		block := storage.ReadClosedBlockByHeight(height)
+		if block == nil {
+			return errors.New(fmt.Sprintf("Block with height %v to prune not found.", height))
+		}
+
+		if err := storage.PruneBlock(block); err != nil {
+			return err
+		}
+
+		blockLogger(block).Debugf("Pruned block")
+	}
+
+	return nil
+}
+
+//A rolled back block must not remain the snapshot block, otherwise the chain could not be loaded after a crash. The
+//snapshot is rewritten at the new last block. Called while the blockValidation mutex is held.
+func rollbackStateSnapshot() error {
+	if !pruning || lastBlock == nil {
+		return nil
+	}
+
+	return writeStateSnapshot(lastBlock)
+}
+
+func writeStateSnapshot(block *protocol.Block) error {
+	snapshot := minerSnapshot{
+		Parameters:        parameterSlice,
+		Target:            target,
+		CurrentTargetTime: [2]int64{currentTargetTime.first, currentTargetTime.last},
+		GlobalBlockCount:  globalBlockCount,
+		LocalBlockCount:   localBlockCount,
+	}
+	for _, targetTime := range targetTimes {
+		snapshot.TargetTimes = append(snapshot.TargetTimes, [2]int64{targetTime.first, targetTime.last})
+	}
+
+	buffer := new(bytes.Buffer)
+	if err := gob.NewEncoder(buffer).Encode(snapshot); err != nil {
+		return err
+	}
+
+	var rootKeys [][32]byte
+	for hash := range storage.RootKeys {
+		rootKeys = append(rootKeys, hash)
+	}
+
+	return storage.WriteStateSnapshot(&storage.StateSnapshot{
+		BlockHash:  block.Hash,
+		Height:     block.Height,
+		State:      storage.State,
+		RootKeys:   rootKeys,
+		MinerState: buffer.Bytes(),
+	})
+}
+
+//Restores the state of a pruned chain. The root keys point to the same accounts as the state, as if the chain was
+//replayed.
+func restoreStateSnapshot(snapshot *storage.StateSnapshot) error {
+	var decoded minerSnapshot
+	if err := gob.NewDecoder(bytes.NewBuffer(snapshot.MinerState)).Decode(&decoded); err != nil {
+		return err
+	}
+
+	if len(decoded.Parameters) == 0 || len(decoded.Target) == 0 {
+		return errors.New("Snapshot does not contain any system parameters.")
+	}
+
+	storage.State = snapshot.State
+	storage.RootKeys = make(map[[32]byte]*protocol.Account)
+	for _, hash := range snapshot.RootKeys {
+		acc, err := storage.GetAccount(hash)
+		if err != nil {
+			return err
+		}
+		storage.RootKeys[hash] = acc
+	}
+
+	//Not serialized because unexported, and not changeable by configTxs anyway.
+	for i := range decoded.Parameters {
+		decoded.Parameters[i].num_included_prev_proofs = NUM_INCL_PREV_PROOFS
+	}
+	parameterSlice = decoded.Parameters
+	activeParameters = &parameterSlice[len(parameterSlice)-1]
+
+	target = decoded.Target
+	targetTimes = nil
+	for _, targetTime := range decoded.TargetTimes {
+		targetTimes = append(targetTimes, timerange{targetTime[0], targetTime[1]})
+	}
+	currentTargetTime = &timerange{decoded.CurrentTargetTime[0], decoded.CurrentTargetTime[1]}
+	globalBlockCount = decoded.GlobalBlockCount
+	localBlockCount = decoded.LocalBlockCount
+
+	return nil
+}
+
+//Reads the snapshot and makes sure it belongs to the chain that is about to be loaded.
+func loadStateSnapshot() (*storage.StateSnapshot, error) {
+	snapshot := storage.ReadStateSnapshot()
+	if snapshot == nil {
+		return nil, errors.New("Chain is pruned but no state snapshot was found.")
+	}
+
+	found := false
+	for _, block := range storage.AllClosedBlocksAsc {
+		if block.Hash == snapshot.BlockHash {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.New(fmt.Sprintf("Snapshot block (%x) is not part of the chain.", snapshot.BlockHash[0:8]))
+	}
+
+	if err := restoreStateSnapshot(snapshot); err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not restore state snapshot: %v", err))
+	}
+
+	logger.WithFields(logging.Fields{logging.BLOCK: snapshot.BlockHash, logging.HEIGHT: snapshot.Height}).Infof("Restored state snapshot")
+
+	return snapshot, nil
+}
@@ -0,0 +1,105 @@
+package miner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Tests whether blocks outside of the slashing window get pruned and the state can be restored from the snapshot
+func TestPruneChain(t *testing.T) {
+	cleanAndPrepare()
+
+	EnablePruning(0)
+	defer func() {
+		pruning = false
+		pruneDepth = 0
+	}()
+	activeParameters.Slashing_window_size = 2
+
+	var blocks []*protocol.Block
+	prevHash := [32]byte{}
+	for height := uint32(1); height <= 4; height++ {
+		b := newBlock(prevHash, [crypto.COMM_PROOF_LENGTH]byte{}, height)
+		createBlockWithTxs(b)
+		finalizeBlock(b)
+		if err := validate(b, false); err != nil {
+			t.Fatalf("Block validation failed: %v\n", err)
+		}
+		blocks = append(blocks, b)
+		prevHash = b.Hash
+	}
+
+	if prunedHeight := storage.ReadPrunedHeight(); prunedHeight != 2 {
+		t.Errorf("Pruned height should be 2 but is %v\n", prunedHeight)
+	}
+
+	for _, b := range blocks[:2] {
+		header := storage.ReadClosedBlockByHeight(b.Height)
+		if header == nil || header.Hash != b.Hash {
+			t.Errorf("Header of pruned block with height %v not found\n", b.Height)
+			continue
+		}
+		if len(header.FundsTxData) != 0 || len(header.AccTxData) != 0 {
+			t.Errorf("Tx hashes of pruned block with height %v were not removed\n", b.Height)
+		}
+		for _, txHash := range b.FundsTxData {
+			if storage.ReadClosedTx(txHash) != nil || storage.ReadTxLocation(txHash) != nil {
+				t.Errorf("Tx %x of pruned block with height %v still exists\n", txHash[0:8], b.Height)
+			}
+		}
+	}
+
+	for _, b := range blocks[2:] {
+		for _, txHash := range b.FundsTxData {
+			if storage.ReadClosedTx(txHash) == nil {
+				t.Errorf("Tx %x of block with height %v was pruned\n", txHash[0:8], b.Height)
+			}
+		}
+	}
+
+	snapshot := storage.ReadStateSnapshot()
+	if snapshot == nil || snapshot.BlockHash != blocks[3].Hash {
+		t.Fatal("State snapshot of the last block not found")
+	}
+
+	balancesBefore := make(map[[32]byte]uint64)
+	for hash, acc := range storage.State {
+		balancesBefore[hash] = acc.Balance
+	}
+	paramsBefore := make([]Parameters, len(parameterSlice))
+	copy(paramsBefore, parameterSlice)
+
+	storage.State = make(map[[32]byte]*protocol.Account)
+	parameterSlice = []Parameters{NewDefaultParameters()}
+	if err := restoreStateSnapshot(snapshot); err != nil {
+		t.Fatalf("Could not restore state snapshot: %v\n", err)
+	}
+
+	balancesAfter := make(map[[32]byte]uint64)
+	for hash, acc := range storage.State {
+		balancesAfter[hash] = acc.Balance
+	}
+	if !reflect.DeepEqual(balancesBefore, balancesAfter) {
+		t.Errorf("Restored state differs:\n%v\n\n%v\n", balancesBefore, balancesAfter)
+	}
+	if len(parameterSlice) != len(paramsBefore) || activeParameters.Slashing_window_size != 2 {
+		t.Errorf("System parameters were not restored: %v\n", activeParameters)
+	}
+	for hash, acc := range storage.RootKeys {
+		if storage.State[hash] != acc {
+			t.Errorf("Root key %x does not point to the restored state\n", hash[0:8])
+		}
+	}
+
+	//The snapshot moves back with the chain, otherwise it would not be part of the chain loaded after a crash.
+	if err := rollback(blocks[3]); err != nil {
+		t.Fatalf("Block could not be rolled back: %v\n", err)
+	}
+	if snapshot := storage.ReadStateSnapshot(); snapshot == nil || snapshot.BlockHash != blocks[2].Hash {
+		t.Error("State snapshot was not rewritten after the rollback")
+	}
+}
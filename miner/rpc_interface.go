@@ -0,0 +1,53 @@
+package miner
+
+import (
+	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//The code in this source file is called by the rpc package, possibly before the miner has been initialized.
+
+//Closed as soon as the state has been set up in Init.
+var initialized = make(chan struct{})
+
+//Returns a copy of the account, the state must not be read while a block is validated.
+func ReadAccount(hash [32]byte) (acc *protocol.Account, ok bool) {
+	blockValidation.Lock()
+	defer blockValidation.Unlock()
+
+	if state := storage.State[hash]; state != nil {
+		accCopy := *state
+		return &accCopy, true
+	}
+
+	return nil, false
+}
+
+//Txs submitted locally pass the same checks as txs received from the network before they are written to the mempool
+//and broadcast.
+func SubmitTx(tx protocol.Transaction) error {
+	select {
+	case <-initialized:
+	default:
+		return p2p.NewTxError(p2p.TX_ERR_TIMEOUT, "Miner is not initialized yet.")
+	}
+
+	if storage.ReadOpenTx(tx.Hash()) != nil || storage.ReadClosedTx(tx.Hash()) != nil {
+		return p2p.NewTxError(p2p.TX_ERR_DUPLICATE, "Tx already known.")
+	}
+
+	blockValidation.Lock()
+	err := checkTxState(tx)
+	blockValidation.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := storage.WriteOpenTx(tx); err != nil {
+		return p2p.NewTxError(p2p.TX_ERR_MEMPOOL, "%v", err)
+	}
+
+	p2p.BroadcastTx(tx)
+	return nil
+}
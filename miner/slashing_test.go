@@ -76,3 +76,16 @@ func TestSlashingCondition(t *testing.T) {
 		t.Error("Slashing reward is not properly added.", initBalance, myAcc.Balance, expectedBalance)
 	}
 }
+
+//Only violations of the proof make a block invalid.
+func TestSlashingCheckErrors(t *testing.T) {
+	cleanAndPrepare()
+
+	for _, hashes := range [][2][32]byte{{{}, {1}}, {{1}, {1}}} {
+		if _, err := slashingCheck([32]byte{2}, hashes[0], hashes[1]); err == nil {
+			t.Errorf("Invalid slashing proof %x accepted.\n", hashes)
+		} else if _, ok := err.(*invalidBlockError); !ok {
+			t.Errorf("Invalid slashing proof %x not reported as invalid block: %v\n", hashes, err)
+		}
+	}
+}
@@ -6,9 +6,9 @@ import (
 	"github.com/bazo-blockchain/bazo-miner/crypto"
 	"github.com/bazo-blockchain/bazo-miner/p2p"
 	"strconv"
+	"github.com/bazo-blockchain/bazo-miner/logging"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
-	"time"
 )
 
 //Separate function to reuse mechanism in client implementation
@@ -86,55 +86,19 @@ func getState() (state string) {
 }
 
 func initState() (initialBlock *protocol.Block, err error) {
-	var allClosedBlocks []*protocol.Block
 	if p2p.IsBootstrap() {
-		allClosedBlocks = storage.ReadAllClosedBlocks()
+		//Switch array order to validate genesis block first
+		storage.AllClosedBlocksAsc = InvertBlockArray(storage.ReadAllClosedBlocks())
 	} else {
-		p2p.LastBlockReq()
-		var lastBlock *protocol.Block
-		//Blocking wait
-		select {
-		case encodedBlock := <-p2p.BlockReqChan:
-			lastBlock = lastBlock.Decode(encodedBlock)
-			//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
-		case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
-			return nil, nil
-		}
-
-		storage.WriteClosedBlock(lastBlock)
-		storage.WriteLastClosedBlock(lastBlock)
-		if len(allClosedBlocks) > 0 && allClosedBlocks[len(allClosedBlocks)-1].Hash == lastBlock.Hash {
-			fmt.Printf("Block with height %v already exists", lastBlock.Height)
-		} else {
-			allClosedBlocks = append(allClosedBlocks, lastBlock)
+		//Headers first, the blocks are validated while they are downloaded (see sync.go).
+		if storage.AllClosedBlocksAsc, err = syncChain(); err != nil {
+			return nil, err
 		}
+		logger.Infof("%v block(s) synchronized. Chain good to go.", len(storage.AllClosedBlocksAsc))
 
-		for {
-			p2p.BlockReq(lastBlock.PrevHash)
-			select {
-			case encodedBlock := <-p2p.BlockReqChan:
-				lastBlock = lastBlock.Decode(encodedBlock)
-				//Limit waiting time to BLOCKFETCH_TIMEOUT seconds before aborting.
-			case <-time.After(BLOCKFETCH_TIMEOUT * time.Second):
-				logger.Println("Timed out")
-			}
-
-			storage.WriteClosedBlock(lastBlock)
-			if len(allClosedBlocks) > 0 && allClosedBlocks[len(allClosedBlocks)-1].Hash == lastBlock.Hash {
-				fmt.Printf("Block with height %v already exists", lastBlock.Height)
-			} else {
-				allClosedBlocks = append(allClosedBlocks, lastBlock)
-			}
-			fmt.Println("Last block: ", lastBlock.Height)
-			if lastBlock.Height == 0 {
-				break;
-			}
-		}
+		return lastBlock, nil
 	}
 
-	//Switch array order to validate genesis block first
-	storage.AllClosedBlocksAsc = InvertBlockArray(allClosedBlocks)
-
 	if len(storage.AllClosedBlocksAsc) > 0 {
 		//Set the last closed block as the initial block
 		initialBlock = storage.AllClosedBlocksAsc[len(storage.AllClosedBlocksAsc)-1]
@@ -154,8 +118,22 @@ func initState() (initialBlock *protocol.Block, err error) {
 		storage.WriteClosedBlock(initialBlock)
 	}
 
+	//Pruned chains cannot be replayed from genesis, the state is restored from the snapshot instead.
+	var snapshot *storage.StateSnapshot
+	if p2p.IsBootstrap() && storage.ReadPrunedHeight() > 0 {
+		if snapshot, err = loadStateSnapshot(); err != nil {
+			return nil, err
+		}
+	}
+
 	//Validate all closed blocks and update state
 	for _, blockToValidate := range storage.AllClosedBlocksAsc {
+		//Blocks up to the snapshot are already part of the restored state.
+		if snapshot != nil && blockToValidate.Height <= snapshot.Height {
+			lastBlock = blockToValidate
+			continue
+		}
+
 		//Prepare datastructure to fill tx payloads
 		blockDataMap := make(map[[32]byte]blockData)
 
@@ -181,13 +159,13 @@ func initState() (initialBlock *protocol.Block, err error) {
 			postValidate(blockDataMap[blockToValidate.Hash], true)
 		}
 
-		logger.Printf("Validated block with height %v\n", blockToValidate.Height)
+		blockLogger(blockToValidate).Infof("Validated block")
 
 		//Set the last validated block as the lastBlock
 		lastBlock = blockToValidate
 	}
 
-	logger.Printf("%v block(s) validated. Chain good to go.", len(storage.AllClosedBlocksAsc))
+	logger.Infof("%v block(s) validated. Chain good to go.", len(storage.AllClosedBlocksAsc))
 
 	return initialBlock, nil
 }
@@ -305,7 +283,7 @@ func configStateChange(configTxSlice []*protocol.ConfigTx, blockHash [32]byte) {
 		newParameters.BlockHash = blockHash
 		parameterSlice = append(parameterSlice, newParameters)
 		activeParameters = &parameterSlice[len(parameterSlice)-1]
-		logger.Printf("Config parameters changed. New configuration: %v", *activeParameters)
+		logger.WithFields(logging.Fields{logging.BLOCK: blockHash}).Infof("Config parameters changed. New configuration: %v", *activeParameters)
 	}
 }
 
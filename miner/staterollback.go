@@ -12,7 +12,7 @@ func accStateChangeRollback(txSlice []*protocol.AccTx) {
 
 			acc, err := storage.GetAccount(accHash)
 			if err != nil {
-				logger.Fatal("CRITICAL: An account that should have been saved does not exist.")
+				logger.Fatalf("CRITICAL: An account that should have been saved does not exist.")
 			}
 
 			delete(storage.State, accHash)
@@ -62,7 +62,7 @@ func configStateChangeRollback(txSlice []*protocol.ConfigTx, blockHash [32]byte)
 	//remove the latest entry in the parameters slice$
 	parameterSlice = parameterSlice[:len(parameterSlice)-1]
 	activeParameters = &parameterSlice[len(parameterSlice)-1]
-	logger.Printf("Config parameters rolled back. New configuration: %v", *activeParameters)
+	logger.Infof("Config parameters rolled back. New configuration: %v", *activeParameters)
 }
 
 func stakeStateChangeRollback(txSlice []*protocol.StakeTx) {
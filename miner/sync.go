@@ -0,0 +1,296 @@
+package miner
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"golang.org/x/crypto/sha3"
+)
+
+//Nodes that do not bootstrap the network synchronize headers first. The header chains of up to SYNC_PEERS miners are
+//downloaded in parallel and checked, the longest valid chain is chosen. Its bodies are then downloaded in height
+//ranges from several miners in parallel, have to match the headers and are validated in ascending order like blocks
+//received from the network. Commitment proofs and the PoS condition depend on the proposer's account (commitment key
+//and balance), a header chain is therefore only trusted as far as its bodies could be validated. If bodies cannot be
+//fetched (e.g., only pruning miners have them) or are invalid, the chain is dropped together with the miners that
+//sent it. The blocks that are not part of the next best chain are rolled back and that chain is synchronized.
+
+//Set while synchronizing. Synchronized blocks are old, so the dynamic timestamp check is skipped, and validated txs
+//are not broadcast.
+var syncing bool
+
+//Returns the validated blocks of the best chain in ascending order.
+func syncChain() ([]*protocol.Block, error) {
+	chains, err := syncHeaders()
+	if err != nil {
+		return nil, err
+	}
+
+	clearClosedChain()
+	syncing = true
+	defer func() {
+		syncing = false
+	}()
+
+	for {
+		headers := bestChain(chains)
+		if len(headers) == 0 {
+			return nil, errors.New("No header chain could be synchronized.")
+		}
+
+		blocks, err := syncBodies(headers)
+		if err == nil {
+			return blocks, nil
+		}
+
+		tip := headers[len(headers)-1]
+		logger.Warnf("Could not synchronize chain with tip %x at height %v: %v", tip.Hash[:8], tip.Height, err)
+		chains = excludeChain(chains, tip.Hash)
+	}
+}
+
+//The state is not persisted, blocks and txs stored by an earlier run are removed such that they are validated again.
+//The pruned height and the state snapshot of an earlier run belong to the removed chain and are removed as well.
+func clearClosedChain() {
+	for _, block := range storage.ReadAllClosedBlocks() {
+		for _, txHashes := range [][][32]byte{block.AccTxData, block.FundsTxData, block.ConfigTxData, block.StakeTxData} {
+			for _, txHash := range txHashes {
+				if tx := storage.ReadClosedTx(txHash); tx != nil {
+					storage.DeleteClosedTx(tx)
+				}
+			}
+		}
+		storage.DeleteClosedBlock(block.Hash)
+	}
+	storage.DeleteAllLastClosedBlock()
+	storage.DeleteChainState()
+}
+
+//Returns the header chains of the miners, nil for miners whose headers could not be synchronized.
+func syncHeaders() ([][]*protocol.Block, error) {
+	//Wait for miners to connect.
+	deadline := time.Now().Add(BLOCKFETCH_TIMEOUT * time.Second)
+	miners := p2p.SyncPeers(SYNC_PEERS)
+	for len(miners) == 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		miners = p2p.SyncPeers(SYNC_PEERS)
+	}
+	if len(miners) == 0 {
+		return nil, errors.New("No miner to synchronize with.")
+	}
+
+	chains := make([][]*protocol.Block, len(miners))
+	var wg sync.WaitGroup
+	for i, miner := range miners {
+		wg.Add(1)
+		go func(i int, miner string) {
+			defer wg.Done()
+
+			var prev *protocol.Block
+			headers, err := p2p.FetchHeaders(miner, func(headers []*protocol.Block) error {
+				if err := verifyHeaders(prev, headers); err != nil {
+					return err
+				}
+				prev = headers[len(headers)-1]
+				return nil
+			}, BLOCKFETCH_TIMEOUT*time.Second)
+			if err != nil {
+				logger.Warnf("Could not synchronize headers with %v: %v", miner, err)
+				return
+			}
+
+			logger.Infof("Received %v headers from %v", len(headers), miner)
+			chains[i] = headers
+		}(i, miner)
+	}
+	wg.Wait()
+
+	return chains, nil
+}
+
+//Checks the headers that can be checked without the state: heights and hash linkage, block hashes and timestamps.
+//prev is the header before the first header, nil if the headers start with the genesis block.
+func verifyHeaders(prev *protocol.Block, headers []*protocol.Block) error {
+	for _, header := range headers {
+		if prev == nil {
+			if header.Height != 0 || header.Hash != [32]byte{} {
+				return errors.New("Header chain does not start with the genesis block.")
+			}
+			prev = header
+			continue
+		}
+
+		if header.Height != prev.Height+1 || header.PrevHash != prev.Hash {
+			return errors.New(fmt.Sprintf("Header %x at height %v does not link to the previous header.", header.Hash[:8], header.Height))
+		}
+		if computeBlockHash(header) != header.Hash {
+			return errors.New(fmt.Sprintf("Header %x at height %v has a wrong hash.", header.Hash[:8], header.Height))
+		}
+		if binary.BigEndian.Uint64(header.Nonce[:]) != uint64(header.Timestamp) {
+			return errors.New(fmt.Sprintf("Header %x at height %v has a wrong timestamp.", header.Hash[:8], header.Height))
+		}
+		if header.Timestamp > time.Now().Unix()+int64(activeParameters.Accepted_time_diff) {
+			return errors.New(fmt.Sprintf("Header %x at height %v is too far in the future.", header.Hash[:8], header.Height))
+		}
+
+		prev = header
+	}
+
+	return nil
+}
+
+//The hash of a block as computed in finalizeBlock, the partial hash is computed before the timestamp and the
+//commitment proof are set.
+func computeBlockHash(block *protocol.Block) [32]byte {
+	partial := *block
+	partial.Timestamp = 0
+	partial.CommitmentProof = [crypto.COMM_PROOF_LENGTH]byte{}
+	partialHash := partial.HashBlock()
+
+	return sha3.Sum256(append(block.Nonce[:], partialHash[:]...))
+}
+
+//The longest chain wins, chains of the same length are ranked by the number of miners that sent them.
+func bestChain(chains [][]*protocol.Block) (best []*protocol.Block) {
+	votes := make(map[[32]byte]int)
+	for _, chain := range chains {
+		if len(chain) > 0 {
+			votes[chain[len(chain)-1].Hash]++
+		}
+	}
+
+	for _, chain := range chains {
+		if len(chain) == 0 {
+			continue
+		}
+		if len(chain) > len(best) || (len(chain) == len(best) && votes[chain[len(chain)-1].Hash] > votes[best[len(best)-1].Hash]) {
+			best = chain
+		}
+	}
+
+	return best
+}
+
+//Removes the chains with the given tip.
+func excludeChain(chains [][]*protocol.Block, tip [32]byte) (remaining [][]*protocol.Block) {
+	for _, chain := range chains {
+		if len(chain) > 0 && chain[len(chain)-1].Hash != tip {
+			remaining = append(remaining, chain)
+		}
+	}
+
+	return remaining
+}
+
+//Downloads the bodies in ranges of BLOCKFETCH_BATCH blocks and validates them in ascending order. Validated blocks
+//of another chain are rolled back first. Bodies that are missing or do not match the header are fetched by hash.
+func syncBodies(headers []*protocol.Block) ([]*protocol.Block, error) {
+	if err := rollbackToHeaders(headers); err != nil {
+		return nil, err
+	}
+
+	//Blocks up to the last block are validated already.
+	blocks := make([]*protocol.Block, len(headers))
+	from := 0
+	if lastBlock != nil {
+		for height := 0; height <= int(lastBlock.Height); height++ {
+			blocks[height] = storage.ReadClosedBlock(headers[height].Hash)
+		}
+		from = int(lastBlock.Height) + 1
+	}
+
+	tip := headers[len(headers)-1]
+	for ; from < len(headers); from += BLOCKFETCH_BATCH {
+		to := from + BLOCKFETCH_BATCH - 1
+		if to >= len(headers) {
+			to = len(headers) - 1
+		}
+
+		fetched, err := p2p.FetchBlocks(uint32(from), uint32(to), BLOCKFETCH_TIMEOUT*time.Second)
+		if err != nil {
+			logger.Debugf("Could not fetch all blocks from height %v to %v: %v", from, to, err)
+		}
+		for _, block := range fetched {
+			if int(block.Height) < len(headers) && matchesHeader(block, headers[block.Height]) {
+				blocks[block.Height] = block
+			}
+		}
+
+		for height := from; height <= to; height++ {
+			if blocks[height] == nil {
+				if blocks[height], err = fetchBody(headers[height]); err != nil {
+					return nil, err
+				}
+			}
+
+			if err := syncBlock(blocks[height]); err != nil {
+				return nil, errors.New(fmt.Sprintf("Block %x at height %v is invalid: %v", headers[height].Hash[:8], height, err))
+			}
+		}
+
+		recordSyncProgress(uint32(to), tip.Height)
+		logger.Infof("Synchronized %v of %v blocks (%.1f%%)", to+1, len(headers), float64(to+1)*100/float64(len(headers)))
+	}
+
+	return blocks, nil
+}
+
+//Fetches the body of the header by hash, up to SYNC_RETRIES times.
+func fetchBody(header *protocol.Block) (block *protocol.Block, err error) {
+	for attempt := 0; attempt < SYNC_RETRIES; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Second)
+		}
+
+		block, err = p2p.FetchBlock(header.Hash, BLOCKFETCH_TIMEOUT*time.Second)
+		if err == nil && !matchesHeader(block, header) {
+			err = errors.New("Block does not match the header.")
+		}
+		if err == nil {
+			return block, nil
+		}
+		logger.Warnf("Could not fetch block %x: %v", header.Hash[:8], err)
+	}
+
+	return nil, errors.New(fmt.Sprintf("Block %x at height %v could not be fetched: %v", header.Hash[:8], header.Height, err))
+}
+
+//The genesis block is not validated (see initState), the other blocks are validated like blocks from the network.
+func syncBlock(block *protocol.Block) error {
+	if block.Height == 0 {
+		return writeGenesis(block, nil)
+	}
+
+	return validate(block, false)
+}
+
+//Rolls back the validated blocks that are not part of the given header chain. All chains share the genesis block.
+func rollbackToHeaders(headers []*protocol.Block) error {
+	blockValidation.Lock()
+	defer blockValidation.Unlock()
+
+	for lastBlock != nil && lastBlock.Height > 0 && (int(lastBlock.Height) >= len(headers) || headers[lastBlock.Height].Hash != lastBlock.Hash) {
+		block := lastBlock
+		if err := rollback(block); err != nil {
+			return err
+		}
+		blockLogger(block).Infof("Rolled back block of another chain")
+	}
+
+	return nil
+}
+
+func matchesHeader(block *protocol.Block, header *protocol.Block) bool {
+	if block.Height != header.Height || block.Hash != header.Hash {
+		return false
+	}
+
+	return block.Height == 0 || computeBlockHash(block) == header.Hash
+}
@@ -0,0 +1,126 @@
+package miner
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+//Headers are sent without tx hashes.
+func toHeader(block *protocol.Block) *protocol.Block {
+	header := *block
+	header.AccTxData, header.FundsTxData, header.ConfigTxData, header.StakeTxData = nil, nil, nil, nil
+	return &header
+}
+
+func TestVerifyHeaders(t *testing.T) {
+	cleanAndPrepare()
+
+	headers := []*protocol.Block{toHeader(genesisBlock)}
+	for height := uint32(1); height <= 3; height++ {
+		b := newBlock(lastBlock.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, height)
+		createBlockWithTxs(b)
+		if err := finalizeBlock(b); err != nil {
+			t.Fatalf("Block could not be finalized: %v\n", err)
+		}
+		validate(b, false)
+		headers = append(headers, toHeader(b))
+	}
+
+	if err := verifyHeaders(nil, headers); err != nil {
+		t.Errorf("Valid header chain rejected: %v\n", err)
+	}
+	if err := verifyHeaders(headers[1], headers[2:]); err != nil {
+		t.Errorf("Valid batch of headers rejected: %v\n", err)
+	}
+	if err := verifyHeaders(nil, headers[1:]); err == nil {
+		t.Error("Header chain without genesis block accepted.")
+	}
+	if err := verifyHeaders(headers[0], headers[2:]); err == nil {
+		t.Error("Headers that do not link accepted.")
+	}
+
+	//The hash covers the merkle root, bodies with other txs do not match the header.
+	tampered := *headers[2]
+	tampered.MerkleRoot = [32]byte{1}
+	if err := verifyHeaders(headers[1], []*protocol.Block{&tampered}); err == nil {
+		t.Error("Header with wrong hash accepted.")
+	}
+	if matchesHeader(&tampered, headers[2]) {
+		t.Error("Body with wrong merkle root matches the header.")
+	}
+}
+
+func TestBestChain(t *testing.T) {
+	genesis := protocol.NewBlock([32]byte{}, 0)
+	a := protocol.NewBlock([32]byte{}, 1)
+	a.Hash = [32]byte{1}
+	b := protocol.NewBlock([32]byte{}, 1)
+	b.Hash = [32]byte{2}
+	c := protocol.NewBlock(b.Hash, 2)
+	c.Hash = [32]byte{3}
+
+	//The longest chain wins.
+	chains := [][]*protocol.Block{{genesis, a}, {genesis, b, c}, nil}
+	if best := bestChain(chains); len(best) != 3 || best[2].Hash != c.Hash {
+		t.Errorf("Wrong chain chosen: %v\n", best)
+	}
+
+	//Chains of the same length are ranked by the number of miners that sent them.
+	chains = [][]*protocol.Block{{genesis, a}, {genesis, b}, {genesis, b}}
+	if best := bestChain(chains); best[1].Hash != b.Hash {
+		t.Errorf("Wrong chain chosen: %x\n", best[1].Hash)
+	}
+
+	if best := bestChain([][]*protocol.Block{nil, nil}); best != nil {
+		t.Error("Chain chosen without headers.")
+	}
+}
+
+func TestExcludeChain(t *testing.T) {
+	genesis := protocol.NewBlock([32]byte{}, 0)
+	a := protocol.NewBlock([32]byte{}, 1)
+	a.Hash = [32]byte{1}
+	b := protocol.NewBlock([32]byte{}, 1)
+	b.Hash = [32]byte{2}
+
+	//All miners that sent the chain are excluded.
+	chains := excludeChain([][]*protocol.Block{{genesis, a}, {genesis, b}, nil, {genesis, a}}, a.Hash)
+	if len(chains) != 1 || chains[0][1].Hash != b.Hash {
+		t.Errorf("Wrong chains left: %v\n", chains)
+	}
+}
+
+func TestSyncBodies(t *testing.T) {
+	cleanAndPrepare()
+
+	headers := []*protocol.Block{toHeader(genesisBlock)}
+	for height := uint32(1); height <= 3; height++ {
+		b := newBlock(lastBlock.Hash, [crypto.COMM_PROOF_LENGTH]byte{}, height)
+		createBlockWithTxs(b)
+		if err := finalizeBlock(b); err != nil {
+			t.Fatalf("Block could not be finalized: %v\n", err)
+		}
+		if err := validate(b, false); err != nil {
+			t.Fatalf("Block could not be validated: %v\n", err)
+		}
+		headers = append(headers, toHeader(b))
+	}
+
+	//Validated blocks of the chain are not fetched again.
+	blocks, err := syncBodies(headers)
+	if err != nil || len(blocks) != 4 || blocks[3].Hash != headers[3].Hash || len(blocks[3].FundsTxData) == 0 {
+		t.Fatalf("Wrong blocks: %v, %v\n", blocks, err)
+	}
+
+	//Blocks of another chain are rolled back down to the common ancestor.
+	other := *headers[2]
+	other.Hash = [32]byte{2}
+	if err := rollbackToHeaders([]*protocol.Block{headers[0], headers[1], &other}); err != nil {
+		t.Fatalf("Blocks could not be rolled back: %v\n", err)
+	}
+	if lastBlock.Hash != headers[1].Hash {
+		t.Errorf("Rolled back to block %x instead of %x.\n", lastBlock.Hash[:8], headers[1].Hash[:8])
+	}
+}
@@ -1,6 +1,7 @@
 package miner
 
 import (
+	"github.com/bazo-blockchain/bazo-miner/logging"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 )
 
@@ -11,3 +12,18 @@ func InvertBlockArray(array []*protocol.Block) []*protocol.Block {
 
 	return array
 }
+
+func blockLogger(block *protocol.Block) *logging.Logger {
+	return logger.WithFields(logging.Fields{logging.BLOCK: block.Hash, logging.HEIGHT: block.Height})
+}
+
+func txLogger(tx protocol.Transaction) *logging.Logger {
+	return logger.WithFields(logging.Fields{logging.TX: tx.Hash()})
+}
+
+//The whole state is only dumped at debug level, building the string is expensive for large states.
+func logState() {
+	if logger.Enabled(logging.DEBUG) {
+		logger.Debugf("State:\n%v", getState())
+	}
+}
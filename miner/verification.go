@@ -41,7 +41,7 @@ func verifyFundsTx(tx *protocol.FundsTx) bool {
 
 	//fundsTx only makes sense if amount > 0
 	if tx.Amount == 0 || tx.Amount > MAX_MONEY {
-		logger.Printf("Invalid transaction amount: %v\n", tx.Amount)
+		logger.Debugf("Invalid transaction amount: %v", tx.Amount)
 		return false
 	}
 
@@ -51,7 +51,7 @@ func verifyFundsTx(tx *protocol.FundsTx) bool {
 
 	//Accounts non existent
 	if accFrom == nil || accTo == nil {
-		logger.Printf("Account non existent. From: %v\nTo: %v\n", accFrom, accTo)
+		logger.Debugf("Account non existent. From: %v, To: %v", accFrom, accTo)
 		return false
 	}
 
@@ -77,7 +77,7 @@ func verifyFundsTx(tx *protocol.FundsTx) bool {
 		tx.To = accToHash
 		validSig1 = true
 	} else {
-		logger.Printf("Sig1 invalid. FromHash: %x\nToHash: %x\n", accFromHash[0:8], accToHash[0:8])
+		logger.Debugf("Sig1 invalid. FromHash: %x, ToHash: %x", accFromHash[0:8], accToHash[0:8])
 		return false
 	}
 
@@ -87,7 +87,7 @@ func verifyFundsTx(tx *protocol.FundsTx) bool {
 	if ecdsa.Verify(multisigPubKey, txHash[:], r, s) {
 		validSig2 = true
 	} else {
-		logger.Printf("Sig2 invalid. FromHash: %x\nToHash: %x\n", accFromHash[0:8], accToHash[0:8])
+		logger.Debugf("Sig2 invalid. FromHash: %x, ToHash: %x", accFromHash[0:8], accToHash[0:8])
 		return false
 	}
 
@@ -149,7 +149,7 @@ func verifyConfigTx(tx *protocol.ConfigTx) bool {
 
 func verifyStakeTx(tx *protocol.StakeTx) bool {
 	if tx == nil {
-		logger.Println("Transactions does not exist.")
+		logger.Debugf("Transactions does not exist.")
 		return false
 	}
 
@@ -158,7 +158,7 @@ func verifyStakeTx(tx *protocol.StakeTx) bool {
 
 	//Account non existent
 	if accFrom == nil {
-		logger.Println("Account does not exist.")
+		logger.Debugf("Account does not exist.")
 		return false
 	}
 
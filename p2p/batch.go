@@ -0,0 +1,330 @@
+package p2p
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+//Batch requests are only sent to miners that support FEATURE_BATCH_REQ (and request IDs). A TXS_REQ is the tx
+//request type (e.g., FUNDSTX_REQ) followed by up to MAX_BATCH_TXS tx hashes, the TXS_RES contains the requested txs
+//the miner has. A BLOCKS_REQ contains a height range (from and to, 4 bytes each), the BLOCKS_RES contains the closed
+//blocks of that range in ascending order up to the first block the miner does not have. Responses are lists of
+//length-prefixed (4 bytes) entries. Missing parts are requested from other miners. HEADERS_REQ and HEADERS_RES work
+//like blocks, but the response contains headers (see encodeHeader) and is only sent to miners that support
+//FEATURE_HEADERS.
+
+//Returns the txs with the given hashes, reqType is one of FUNDSTX_REQ, ACCTX_REQ, CONFIGTX_REQ and STAKETX_REQ. The
+//txs are requested in batches from several miners in parallel, fails if not all txs could be fetched.
+func FetchTxs(hashes [][32]byte, reqType uint8, timeout time.Duration) (map[[32]byte]protocol.Transaction, error) {
+	deadline := time.Now().Add(timeout)
+
+	//Duplicates are only requested once, txs received with compact blocks are not requested at all.
+	var unique [][32]byte
+	txs := make(map[[32]byte]protocol.Transaction)
+	seen := make(map[[32]byte]bool)
+	for _, hash := range hashes {
+		if tx := readRecentTx(hash); tx != nil && isTxOfType(tx, reqType) {
+			txs[hash] = tx
+		} else if !seen[hash] {
+			seen[hash] = true
+			unique = append(unique, hash)
+		}
+	}
+
+	batches := make([]map[[32]byte]protocol.Transaction, (len(unique)+TXS_PER_BATCH-1)/TXS_PER_BATCH)
+	err := runBatches(len(batches), func(i int) (err error) {
+		end := (i + 1) * TXS_PER_BATCH
+		if end > len(unique) {
+			end = len(unique)
+		}
+		batches[i], err = fetchTxBatch(unique[i*TXS_PER_BATCH:end], reqType, time.Until(deadline))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, batch := range batches {
+		for hash, tx := range batch {
+			txs[hash] = tx
+		}
+	}
+
+	return txs, nil
+}
+
+func fetchTxBatch(hashes [][32]byte, reqType uint8, timeout time.Duration) (map[[32]byte]protocol.Transaction, error) {
+	txs := make(map[[32]byte]protocol.Transaction)
+	requested := make(map[[32]byte]bool)
+	for _, hash := range hashes {
+		requested[hash] = true
+	}
+
+	payload := func() []byte {
+		payload := []byte{reqType}
+		for _, hash := range hashes {
+			if txs[hash] == nil {
+				payload = append(payload, hash[:]...)
+			}
+		}
+		return payload
+	}
+
+	err := fetch(TXS_REQ, FEATURE_REQUEST_IDS|FEATURE_BATCH_REQ, payload, timeout, func(typeID uint8, payload []byte) (bool, error) {
+		entries, err := decodeBatch(typeID, TXS_RES, payload)
+		if err != nil {
+			return false, err
+		}
+
+		for _, entry := range entries {
+			tx := decodeTxRes(txResTypes[reqType], entry)
+			if tx == nil || !requested[tx.Hash()] {
+				return false, errors.New(fmt.Sprintf("Requested %v txs, got something else.", LogMapping[reqType]))
+			}
+			txs[tx.Hash()] = tx
+		}
+
+		return len(txs) == len(hashes), nil
+	})
+
+	return txs, err
+}
+
+//Returns the closed blocks from height from to height to (inclusive) in ascending order. The range is requested in
+//batches from several miners in parallel. Blocks that could not be fetched are missing in the result, in that case
+//the error is returned as well. Miners might be on different chains, the caller has to check how the blocks link.
+func FetchBlocks(from, to uint32, timeout time.Duration) (blocks []*protocol.Block, err error) {
+	if to < from {
+		return nil, nil
+	}
+	deadline := time.Now().Add(timeout)
+
+	count := int(to-from) + 1
+	batches := make([][]*protocol.Block, (count+BLOCKS_PER_BATCH-1)/BLOCKS_PER_BATCH)
+	err = runBatches(len(batches), func(i int) (err error) {
+		batchFrom := from + uint32(i*BLOCKS_PER_BATCH)
+		batchTo := batchFrom + BLOCKS_PER_BATCH - 1
+		if batchTo > to || batchTo < batchFrom {
+			batchTo = to
+		}
+		batches[i], err = fetchBlockBatch(batchFrom, batchTo, time.Until(deadline))
+		return err
+	})
+
+	for _, batch := range batches {
+		blocks = append(blocks, batch...)
+	}
+
+	return blocks, err
+}
+
+func fetchBlockBatch(from, to uint32, timeout time.Duration) ([]*protocol.Block, error) {
+	var blocks []*protocol.Block
+
+	payload := func() []byte {
+		return encodeHeightRange(from+uint32(len(blocks)), to)
+	}
+
+	err := fetch(BLOCKS_REQ, FEATURE_REQUEST_IDS|FEATURE_BATCH_REQ, payload, timeout, func(typeID uint8, payload []byte) (bool, error) {
+		entries, err := decodeBatch(typeID, BLOCKS_RES, payload)
+		if err != nil {
+			return false, err
+		}
+
+		var received []*protocol.Block
+		next := from + uint32(len(blocks))
+		for i, entry := range entries {
+			var block *protocol.Block
+			if block = block.Decode(entry); block == nil || block.Height != next+uint32(i) || block.Height > to {
+				return false, errors.New(fmt.Sprintf("Requested blocks %v to %v, got something else.", next, to))
+			}
+			received = append(received, block)
+		}
+		blocks = append(blocks, received...)
+
+		return len(blocks) == int(to-from)+1, nil
+	})
+
+	return blocks, err
+}
+
+//Up to n miners that support header requests, the ones with the highest height (announced in the handshake) first.
+func SyncPeers(n int) (ids []string) {
+	var candidates []*peer
+	for _, p := range peers.getAllPeers(PEERTYPE_MINER) {
+		if p.hasFeature(FEATURE_REQUEST_IDS | FEATURE_HEADERS) {
+			candidates = append(candidates, p)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].height > candidates[j].height
+	})
+
+	for i := 0; i < n && i < len(candidates); i++ {
+		ids = append(ids, candidates[i].getIPPort())
+	}
+	return ids
+}
+
+//Downloads the header chain of the given miner (see SyncPeers) from height 0. check is called for every batch of
+//headers, the miner is penalized and the download aborted if the check fails. The timeout applies to every batch.
+//Headers are downloaded up to the height the miner announced plus HEADERS_HEIGHT_MARGIN, miners that have more are
+//penalized.
+func FetchHeaders(id string, check func(headers []*protocol.Block) error, timeout time.Duration) (headers []*protocol.Block, err error) {
+	var p *peer
+	for _, miner := range peers.getAllPeers(PEERTYPE_MINER) {
+		if miner.getIPPort() == id {
+			p = miner
+		}
+	}
+	if p == nil {
+		return nil, errors.New(fmt.Sprintf("Miner %v is not connected.", id))
+	}
+
+	//The header after maxHeight is requested as well to detect miners that go past it.
+	maxHeight := uint64(p.height) + HEADERS_HEIGHT_MARGIN
+	for {
+		from := uint64(len(headers))
+		to := from + MAX_BATCH_HEADERS - 1
+		if to > maxHeight+1 {
+			to = maxHeight + 1
+		}
+
+		res, err := request(p, HEADERS_REQ, encodeHeightRange(uint32(from), uint32(to)), timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		batch, err := decodeHeaders(uint32(from), res)
+		if err == nil && uint64(len(batch)) > to-from+1 {
+			err = errors.New(fmt.Sprintf("Requested headers from height %v to %v, got more.", from, to))
+			penalize(p, PENALTY_WRONG_RESPONSE, err.Error())
+			return nil, err
+		}
+		if err == nil && len(batch) > 0 {
+			err = check(batch)
+		}
+		if err != nil {
+			penalize(p, PENALTY_INVALID_BLOCK, err.Error())
+			return nil, err
+		}
+
+		if len(batch) == 0 {
+			return headers, nil
+		}
+		headers = append(headers, batch...)
+
+		if uint64(len(headers)) > maxHeight+1 {
+			err = errors.New(fmt.Sprintf("Miner sent headers above height %v, announced height %v.", maxHeight, p.height))
+			penalize(p, PENALTY_WRONG_RESPONSE, err.Error())
+			return nil, err
+		}
+	}
+}
+
+func decodeHeaders(from uint32, res response) (headers []*protocol.Block, err error) {
+	entries, err := decodeBatch(res.typeID, HEADERS_RES, res.payload)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, entry := range entries {
+		var header *protocol.Block
+		if header = header.Decode(entry); header == nil || header.Height != from+uint32(i) {
+			return nil, errors.New(fmt.Sprintf("Requested headers from height %v, got something else.", from))
+		}
+		headers = append(headers, header)
+	}
+
+	return headers, nil
+}
+
+//Headers are blocks without tx hashes and bloom filter.
+func encodeHeader(block *protocol.Block) []byte {
+	header := *block
+	header.BloomFilter = nil
+	header.AccTxData = nil
+	header.FundsTxData = nil
+	header.ConfigTxData = nil
+	header.StakeTxData = nil
+
+	return header.Encode()
+}
+
+func encodeHeightRange(from, to uint32) []byte {
+	encoded := make([]byte, 8)
+	binary.BigEndian.PutUint32(encoded[0:4], from)
+	binary.BigEndian.PutUint32(encoded[4:8], to)
+	return encoded
+}
+
+//Runs fetchBatch for every batch, at most MAX_PARALLEL_BATCHES at the same time. Returns the first error.
+func runBatches(count int, fetchBatch func(i int) error) (err error) {
+	running := make(chan bool, MAX_PARALLEL_BATCHES)
+	errs := make(chan error, count)
+
+	for i := 0; i < count; i++ {
+		running <- true
+		go func(i int) {
+			errs <- fetchBatch(i)
+			<-running
+		}(i)
+	}
+
+	for i := 0; i < count; i++ {
+		if batchErr := <-errs; batchErr != nil && err == nil {
+			err = batchErr
+		}
+	}
+
+	return err
+}
+
+func encodeBatch(entries [][]byte) (encoded []byte) {
+	for _, entry := range entries {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(entry)))
+		encoded = append(encoded, length[:]...)
+		encoded = append(encoded, entry...)
+	}
+
+	return encoded
+}
+
+func decodeBatch(typeID uint8, expectedType uint8, payload []byte) (entries [][]byte, err error) {
+	if typeID != expectedType {
+		return nil, errors.New(fmt.Sprintf("Expected %v, got %v.", LogMapping[expectedType], LogMapping[typeID]))
+	}
+
+	for len(payload) > 0 {
+		if len(payload) < 4 || uint64(len(payload)-4) < uint64(binary.BigEndian.Uint32(payload[:4])) {
+			return nil, errors.New(fmt.Sprintf("Malformed %v.", LogMapping[typeID]))
+		}
+		length := binary.BigEndian.Uint32(payload[:4])
+		entries = append(entries, payload[4:4+length])
+		payload = payload[4+length:]
+	}
+
+	return entries, nil
+}
+
+//Batch responses only contain txs of the requested type.
+func isTxOfType(tx protocol.Transaction, reqType uint8) bool {
+	switch tx.(type) {
+	case *protocol.FundsTx:
+		return reqType == FUNDSTX_REQ
+	case *protocol.AccTx:
+		return reqType == ACCTX_REQ
+	case *protocol.ConfigTx:
+		return reqType == CONFIGTX_REQ
+	case *protocol.StakeTx:
+		return reqType == STAKETX_REQ
+	}
+
+	return false
+}
@@ -0,0 +1,190 @@
+package p2p
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Answers batch requests, txs and blocks are only found if has returns true for their hash.
+func batchMiner(has func(hash [32]byte) bool) func(remote *peer, header *Header, payload []byte) {
+	return func(remote *peer, header *Header, payload []byte) {
+		id, rest, _ := splitRequestID(remote, payload)
+		switch header.TypeID {
+		case TXS_REQ:
+			var entries [][]byte
+			for i := 1; i < len(rest); i += 32 {
+				var hash [32]byte
+				copy(hash[:], rest[i:i+32])
+				if tx := storage.ReadOpenTx(hash); tx != nil && has(hash) {
+					entries = append(entries, tx.Encode())
+				}
+			}
+			sendData(remote, BuildPacket(TXS_RES, append(id, encodeBatch(entries)...)))
+		case BLOCKS_REQ:
+			blocksRes(remote, payload)
+		}
+	}
+}
+
+func TestFetchTxs(t *testing.T) {
+	var hashes [][32]byte
+	for i := 1; i <= TXS_PER_BATCH+10; i++ {
+		tx := &protocol.FundsTx{Amount: uint64(i), Fee: 1, TxCnt: uint32(i)}
+		storage.WriteOpenTx(tx)
+		defer storage.DeleteOpenTx(tx)
+		hashes = append(hashes, tx.Hash())
+	}
+
+	//Each miner only has some of the txs, the missing ones are requested from the other miner.
+	even := connectTestMiner(batchMiner(func(hash [32]byte) bool { return hash[0]%2 == 0 }))
+	defer disconnectTestMiner(even)
+	odd := connectTestMiner(batchMiner(func(hash [32]byte) bool { return hash[0]%2 == 1 }))
+	defer disconnectTestMiner(odd)
+
+	txs, err := FetchTxs(append(hashes, hashes[0]), FUNDSTX_REQ, 2*time.Second)
+	if err != nil || len(txs) != len(hashes) {
+		t.Fatalf("Could not fetch txs: %v\n", err)
+	}
+	for _, hash := range hashes {
+		if tx, ok := txs[hash].(*protocol.FundsTx); !ok || tx.Hash() != hash {
+			t.Errorf("Wrong tx for hash %x.\n", hash[:8])
+		}
+	}
+
+	if _, err := FetchTxs([][32]byte{{1}}, FUNDSTX_REQ, 2*time.Second); err == nil {
+		t.Error("Fetched a tx that no miner has.")
+	}
+
+	if getTestScore(even) < 0 || getTestScore(odd) < 0 {
+		t.Error("Miners that sent part of the txs were penalized.")
+	}
+}
+
+func TestFetchBlocks(t *testing.T) {
+	for height := uint32(1); height <= BLOCKS_PER_BATCH+5; height++ {
+		block := protocol.NewBlock([32]byte{}, height)
+		block.Hash = [32]byte{byte(height), 1}
+		storage.WriteClosedBlock(block)
+		defer storage.DeleteClosedBlock(block.Hash)
+	}
+
+	miner := connectTestMiner(batchMiner(nil))
+	defer disconnectTestMiner(miner)
+
+	blocks, err := FetchBlocks(2, BLOCKS_PER_BATCH+5, 2*time.Second)
+	if err != nil || len(blocks) != BLOCKS_PER_BATCH+4 {
+		t.Fatalf("Could not fetch blocks: %v\n", err)
+	}
+	for i, block := range blocks {
+		if block.Height != uint32(i+2) {
+			t.Errorf("Block %v has height %v.\n", i, block.Height)
+		}
+	}
+
+	//Blocks that could not be fetched are missing.
+	blocks, err = FetchBlocks(BLOCKS_PER_BATCH, BLOCKS_PER_BATCH+8, 2*time.Second)
+	if err == nil || len(blocks) != 6 {
+		t.Errorf("Expected 6 blocks and an error, got %v blocks: %v\n", len(blocks), err)
+	}
+}
+
+func TestFetchBlocksWrongResponse(t *testing.T) {
+	other := protocol.NewBlock([32]byte{}, 42)
+
+	wrong := connectTestMiner(func(remote *peer, header *Header, payload []byte) {
+		id, _, _ := splitRequestID(remote, payload)
+		sendData(remote, BuildPacket(BLOCKS_RES, append(id, encodeBatch([][]byte{other.Encode()})...)))
+	})
+	defer disconnectTestMiner(wrong)
+
+	if blocks, err := FetchBlocks(1, 2, 2*time.Second); err == nil || len(blocks) != 0 {
+		t.Error("Block of the wrong height accepted.")
+	}
+	if getTestScore(wrong) >= 0 {
+		t.Error("Miner that sent the wrong block was not penalized.")
+	}
+}
+
+func TestDecodeBatch(t *testing.T) {
+	entries := [][]byte{{1, 2, 3}, {}, {4}}
+
+	decoded, err := decodeBatch(TXS_RES, TXS_RES, encodeBatch(entries))
+	if err != nil || len(decoded) != 3 || len(decoded[0]) != 3 || len(decoded[1]) != 0 || decoded[2][0] != 4 {
+		t.Errorf("Wrong entries: %v, %v\n", decoded, err)
+	}
+
+	if _, err := decodeBatch(TXS_RES, TXS_RES, encodeBatch(entries)[:6]); err == nil {
+		t.Error("Truncated batch accepted.")
+	}
+	if _, err := decodeBatch(BLOCKS_RES, TXS_RES, nil); err == nil {
+		t.Error("Wrong response type accepted.")
+	}
+}
+
+func TestFetchHeaders(t *testing.T) {
+	for height := uint32(0); height < 5; height++ {
+		block := protocol.NewBlock([32]byte{}, height)
+		block.Hash = [32]byte{byte(height), 2}
+		block.FundsTxData = [][32]byte{{1}}
+		storage.WriteClosedBlock(block)
+		defer storage.DeleteClosedBlock(block.Hash)
+	}
+
+	miner := connectTestMiner(func(remote *peer, header *Header, payload []byte) {
+		headersRes(remote, payload)
+	})
+	defer disconnectTestMiner(miner)
+
+	if ids := SyncPeers(3); len(ids) != 1 || ids[0] != miner.getIPPort() {
+		t.Fatalf("Wrong sync peers: %v\n", ids)
+	}
+
+	var checked int
+	headers, err := FetchHeaders(miner.getIPPort(), func(headers []*protocol.Block) error {
+		checked += len(headers)
+		return nil
+	}, 2*time.Second)
+	if err != nil || len(headers) != 5 || checked != 5 {
+		t.Fatalf("Could not fetch headers: %v\n", err)
+	}
+	if headers[4].Hash != [32]byte{4, 2} || len(headers[4].FundsTxData) != 0 {
+		t.Errorf("Wrong header: %v\n", headers[4])
+	}
+
+	//Miners that send headers that do not pass the check are penalized.
+	if _, err := FetchHeaders(miner.getIPPort(), func(headers []*protocol.Block) error {
+		return errors.New("Invalid header.")
+	}, 2*time.Second); err == nil || getTestScore(miner) >= 0 {
+		t.Error("Invalid headers accepted.")
+	}
+}
+
+func TestFetchHeadersAboveHeight(t *testing.T) {
+	//The miner has headers at every height it is asked for.
+	miner := connectTestMiner(func(remote *peer, header *Header, payload []byte) {
+		id, rest, _ := splitRequestID(remote, payload)
+		var entries [][]byte
+		for height := binary.BigEndian.Uint32(rest[0:4]); height <= binary.BigEndian.Uint32(rest[4:8]); height++ {
+			entries = append(entries, encodeHeader(protocol.NewBlock([32]byte{}, height)))
+		}
+		sendData(remote, BuildPacket(HEADERS_RES, append(id, encodeBatch(entries)...)))
+	})
+	defer disconnectTestMiner(miner)
+	miner.height = 10
+
+	var checked int
+	if _, err := FetchHeaders(miner.getIPPort(), func(headers []*protocol.Block) error {
+		checked += len(headers)
+		return nil
+	}, 2*time.Second); err == nil || getTestScore(miner) >= 0 {
+		t.Error("Headers above the announced height accepted.")
+	}
+	if checked != 10+HEADERS_HEIGHT_MARGIN+2 {
+		t.Errorf("Wrong number of headers downloaded: %v\n", checked)
+	}
+}
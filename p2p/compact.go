@@ -0,0 +1,287 @@
+package p2p
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/logging"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+	"golang.org/x/crypto/sha3"
+)
+
+//Miners that support FEATURE_CMPCT_BLOCKS get a CMPCT_BLOCK_BRDCST instead of a BLOCK_BRDCST. The compact block is a
+//list of length-prefixed entries (see encodeBatch): the header (see encodeHeader) followed by the short IDs
+//(SHORT_ID_SIZE bytes each) of the acc, funds, config and stake txs. Short IDs are derived from the block hash, such
+//that collisions cannot be prepared in advance. The receiver rebuilds the block from its mempool and requests the
+//missing txs from the sender with a single BLOCKTXS_REQ: the block hash followed by the positions of the missing txs
+//(4 bytes each, counted over all tx lists in the order above). The BLOCKTXS_RES contains the txs in the requested
+//order. The received txs are kept with the block, such that the miner does not fetch them again (see FetchTxs). If
+//the block cannot be rebuilt, the full block is requested.
+
+type recentBlock struct {
+	block *protocol.Block
+	txs   map[[32]byte]protocol.Transaction
+}
+
+var (
+	//Tx request types in the order of the tx lists of compact blocks.
+	compactTxTypes = []uint8{ACCTX_REQ, FUNDSTX_REQ, CONFIGTX_REQ, STAKETX_REQ}
+
+	//Blocks announced to other miners and blocks rebuilt from compact blocks together with the txs that were
+	//requested. Only the last MAX_RECENT_BLOCKS are kept.
+	recentBlocks []*recentBlock
+	recentMutex  = &sync.Mutex{}
+)
+
+func announceBlock(encodedBlock []byte) {
+	var block *protocol.Block
+	if block = block.Decode(encodedBlock); block == nil {
+		return
+	}
+	addRecentBlock(block, nil)
+
+	announce(BLOCK_BRDCST, block.Hash, encodedBlock, encodeCompactBlock(block))
+}
+
+func addRecentBlock(block *protocol.Block, txs map[[32]byte]protocol.Transaction) {
+	recentMutex.Lock()
+	defer recentMutex.Unlock()
+
+	recentBlocks = append(recentBlocks, &recentBlock{block, txs})
+	if len(recentBlocks) > MAX_RECENT_BLOCKS {
+		recentBlocks = recentBlocks[len(recentBlocks)-MAX_RECENT_BLOCKS:]
+	}
+}
+
+func readRecentBlock(hash [32]byte) *protocol.Block {
+	recentMutex.Lock()
+	defer recentMutex.Unlock()
+
+	for _, recent := range recentBlocks {
+		if recent.block.Hash == hash {
+			return recent.block
+		}
+	}
+	return nil
+}
+
+func readRecentTx(hash [32]byte) protocol.Transaction {
+	recentMutex.Lock()
+	defer recentMutex.Unlock()
+
+	for _, recent := range recentBlocks {
+		if tx := recent.txs[hash]; tx != nil {
+			return tx
+		}
+	}
+	return nil
+}
+
+func shortTxID(blockHash, txHash [32]byte) (id [SHORT_ID_SIZE]byte) {
+	hash := sha3.Sum256(append(blockHash[:], txHash[:]...))
+	copy(id[:], hash[:SHORT_ID_SIZE])
+	return id
+}
+
+func txLists(block *protocol.Block) []*[][32]byte {
+	return []*[][32]byte{&block.AccTxData, &block.FundsTxData, &block.ConfigTxData, &block.StakeTxData}
+}
+
+func encodeCompactBlock(block *protocol.Block) []byte {
+	entries := [][]byte{encodeHeader(block)}
+	for _, list := range txLists(block) {
+		var ids []byte
+		for _, txHash := range *list {
+			id := shortTxID(block.Hash, txHash)
+			ids = append(ids, id[:]...)
+		}
+		entries = append(entries, ids)
+	}
+
+	return encodeBatch(entries)
+}
+
+//Returns the header and the short IDs of every tx list.
+func decodeCompactBlock(payload []byte) (header *protocol.Block, ids [][][SHORT_ID_SIZE]byte, err error) {
+	entries, err := decodeBatch(CMPCT_BLOCK_BRDCST, CMPCT_BLOCK_BRDCST, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(entries) != len(compactTxTypes)+1 {
+		return nil, nil, errors.New("Malformed compact block.")
+	}
+	if header = header.Decode(entries[0]); header == nil {
+		return nil, nil, errors.New("Malformed compact block header.")
+	}
+
+	counts := []int{int(header.NrAccTx), int(header.NrFundsTx), int(header.NrConfigTx), int(header.NrStakeTx)}
+	for i, entry := range entries[1:] {
+		if len(entry) != counts[i]*SHORT_ID_SIZE {
+			return nil, nil, errors.New("Number of short IDs does not match the header.")
+		}
+
+		list := make([][SHORT_ID_SIZE]byte, counts[i])
+		for j := range list {
+			copy(list[j][:], entry[j*SHORT_ID_SIZE:])
+		}
+		ids = append(ids, list)
+	}
+
+	return header, ids, nil
+}
+
+func processCompactBlock(p *peer, payload []byte) {
+	header, ids, err := decodeCompactBlock(payload)
+	if err != nil {
+		penalize(p, PENALTY_INVALID_BLOCK, fmt.Sprintf("Sent invalid compact block: %v", err))
+		return
+	}
+	p.known.add(header.Hash)
+	if storage.ReadClosedBlock(header.Hash) != nil {
+		return
+	}
+
+	block, err := rebuildBlock(p, header, ids)
+	if err != nil {
+		logger.WithFields(logging.Fields{logging.PEER: p.getIPPort(), logging.BLOCK: header.Hash}).Debugf("Could not rebuild compact block: %v", err)
+		if block, err = FetchBlock(header.Hash, BLOCKTXS_TIMEOUT*time.Second); err != nil {
+			return
+		}
+	}
+
+	forwardBlockToMiner(p, block.Encode())
+}
+
+//Fills the tx lists of the header with the txs of the mempool, the missing txs are requested from the sender.
+func rebuildBlock(p *peer, block *protocol.Block, ids [][][SHORT_ID_SIZE]byte) (*protocol.Block, error) {
+	lists := txLists(block)
+
+	mempool := make(map[[SHORT_ID_SIZE]byte]protocol.Transaction)
+	for _, tx := range storage.ReadAllOpenTxs() {
+		mempool[shortTxID(block.Hash, tx.Hash())] = tx
+	}
+
+	var missing []byte
+	var position uint32
+	for i, list := range ids {
+		*lists[i] = make([][32]byte, len(list))
+		for j, id := range list {
+			if tx := mempool[id]; tx != nil && isTxOfType(tx, compactTxTypes[i]) {
+				(*lists[i])[j] = tx.Hash()
+			} else {
+				missing = append(missing, encodePosition(position)...)
+			}
+			position++
+		}
+	}
+	if len(missing) == 0 {
+		return block, nil
+	}
+
+	res, err := request(p, BLOCKTXS_REQ, append(block.Hash[:], missing...), BLOCKTXS_TIMEOUT*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if res.typeID == NOT_FOUND {
+		return nil, errors.New("Missing txs not found by the sender.")
+	}
+
+	txs, err := fillMissingTxs(block, ids, missing, res)
+	if err != nil {
+		penalize(p, PENALTY_WRONG_RESPONSE, err.Error())
+		return nil, err
+	}
+
+	addRecentBlock(block, txs)
+	return block, nil
+}
+
+//Decodes the txs of the BLOCKTXS_RES and checks them against the short IDs.
+func fillMissingTxs(block *protocol.Block, ids [][][SHORT_ID_SIZE]byte, missing []byte, res response) (map[[32]byte]protocol.Transaction, error) {
+	entries, err := decodeBatch(res.typeID, BLOCKTXS_RES, res.payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != len(missing)/4 {
+		return nil, errors.New("Wrong number of missing txs.")
+	}
+
+	lists := txLists(block)
+	txs := make(map[[32]byte]protocol.Transaction)
+	for n, entry := range entries {
+		list, index := locatePosition(ids, binary.BigEndian.Uint32(missing[n*4:]))
+		tx := decodeTxRes(txResTypes[compactTxTypes[list]], entry)
+		if tx == nil || shortTxID(block.Hash, tx.Hash()) != ids[list][index] {
+			return nil, errors.New("Sent a tx that does not match the compact block.")
+		}
+
+		(*lists[list])[index] = tx.Hash()
+		txs[tx.Hash()] = tx
+	}
+
+	return txs, nil
+}
+
+//Answers with the requested txs of a recently announced or stored block.
+func blockTxsRes(p *peer, payload []byte) {
+	id, payload, err := splitRequestID(p, payload)
+	if err != nil || len(payload) < 32 || (len(payload)-32)%4 != 0 {
+		return
+	}
+
+	var blockHash [32]byte
+	copy(blockHash[:], payload[:32])
+	block := readRecentBlock(blockHash)
+	if block == nil {
+		block = storage.ReadOpenBlock(blockHash)
+	}
+	if block == nil {
+		block = storage.ReadClosedBlock(blockHash)
+	}
+
+	var txHashes [][32]byte
+	if block != nil {
+		for _, list := range txLists(block) {
+			txHashes = append(txHashes, *list...)
+		}
+	}
+
+	var encodedTxs [][]byte
+	for i := 32; i < len(payload); i += 4 {
+		position := binary.BigEndian.Uint32(payload[i : i+4])
+		var tx protocol.Transaction
+		if position < uint32(len(txHashes)) {
+			if tx = storage.ReadOpenTx(txHashes[position]); tx == nil {
+				tx = storage.ReadClosedTx(txHashes[position])
+			}
+		}
+		if tx == nil {
+			sendData(p, BuildPacket(NOT_FOUND, id))
+			return
+		}
+		encodedTxs = append(encodedTxs, tx.Encode())
+	}
+
+	sendData(p, BuildPacket(BLOCKTXS_RES, append(id, encodeBatch(encodedTxs)...)))
+}
+
+func encodePosition(position uint32) []byte {
+	encoded := make([]byte, 4)
+	binary.BigEndian.PutUint32(encoded, position)
+	return encoded
+}
+
+//Returns the tx list and the index in that list of a position that counts over all tx lists.
+func locatePosition(ids [][][SHORT_ID_SIZE]byte, position uint32) (list int, index int) {
+	for list = range ids {
+		if int(position) < len(ids[list]) {
+			return list, int(position)
+		}
+		position -= uint32(len(ids[list]))
+	}
+	return -1, -1
+}
@@ -0,0 +1,110 @@
+package p2p
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func newCompactTestBlock(height uint32, txs ...protocol.Transaction) *protocol.Block {
+	block := protocol.NewBlock([32]byte{}, height)
+	block.Hash = [32]byte{byte(height), 3}
+	for _, tx := range txs {
+		block.FundsTxData = append(block.FundsTxData, tx.Hash())
+	}
+	block.NrFundsTx = uint16(len(block.FundsTxData))
+	return block
+}
+
+func TestEncodeCompactBlock(t *testing.T) {
+	block := newCompactTestBlock(1, &protocol.FundsTx{Amount: 1}, &protocol.FundsTx{Amount: 2})
+	block.AccTxData = [][32]byte{{4}}
+	block.NrAccTx = 1
+
+	header, ids, err := decodeCompactBlock(encodeCompactBlock(block))
+	if err != nil || header.Hash != block.Hash || len(header.FundsTxData) != 0 {
+		t.Fatalf("Wrong compact block: %v, %v\n", header, err)
+	}
+	if len(ids) != 4 || len(ids[0]) != 1 || len(ids[1]) != 2 || ids[1][1] != shortTxID(block.Hash, block.FundsTxData[1]) {
+		t.Errorf("Wrong short IDs: %v\n", ids)
+	}
+
+	//Positions count over all tx lists.
+	if list, index := locatePosition(ids, 2); list != 1 || index != 1 {
+		t.Errorf("Position 2 located in list %v at index %v.\n", list, index)
+	}
+
+	block.NrFundsTx = 3
+	if _, _, err := decodeCompactBlock(encodeCompactBlock(block)); err == nil {
+		t.Error("Compact block with the wrong number of short IDs accepted.")
+	}
+}
+
+func TestRebuildBlock(t *testing.T) {
+	known := &protocol.FundsTx{Amount: 1, Fee: 1, TxCnt: 1}
+	missing := &protocol.FundsTx{Amount: 2, Fee: 1, TxCnt: 2}
+	storage.WriteOpenTx(known)
+	defer storage.DeleteOpenTx(known)
+
+	block := newCompactTestBlock(2, known, missing)
+	header, ids, _ := decodeCompactBlock(encodeCompactBlock(block))
+
+	var requested []byte
+	miner := connectTestMiner(func(remote *peer, header *Header, payload []byte) {
+		id, rest, _ := splitRequestID(remote, payload)
+		requested = rest[32:]
+		sendData(remote, BuildPacket(BLOCKTXS_RES, append(id, encodeBatch([][]byte{missing.Encode()})...)))
+	})
+	defer disconnectTestMiner(miner)
+
+	//Only the tx that is not in the mempool is requested.
+	rebuilt, err := rebuildBlock(miner, header, ids)
+	if err != nil {
+		t.Fatalf("Could not rebuild block: %v\n", err)
+	}
+	if !bytes.Equal(requested, encodePosition(1)) {
+		t.Errorf("Requested positions %v.\n", requested)
+	}
+	if rebuilt.FundsTxData[0] != known.Hash() || rebuilt.FundsTxData[1] != missing.Hash() {
+		t.Errorf("Wrong txs in the rebuilt block: %x\n", rebuilt.FundsTxData)
+	}
+	if readRecentTx(missing.Hash()) == nil {
+		t.Error("Received tx is not kept with the block.")
+	}
+
+	//A tx that does not match the short ID is rejected.
+	block = newCompactTestBlock(3, &protocol.FundsTx{Amount: 3})
+	header, ids, _ = decodeCompactBlock(encodeCompactBlock(block))
+	if _, err := rebuildBlock(miner, header, ids); err == nil || getTestScore(miner) >= 0 {
+		t.Error("Wrong tx accepted.")
+	}
+}
+
+func TestBlockTxsRes(t *testing.T) {
+	tx := &protocol.FundsTx{Amount: 4, Fee: 1, TxCnt: 4}
+	storage.WriteOpenTx(tx)
+	defer storage.DeleteOpenTx(tx)
+	block := newCompactTestBlock(4, tx)
+	addRecentBlock(block, nil)
+
+	miner := connectTestMiner(func(remote *peer, header *Header, payload []byte) {
+		blockTxsRes(remote, payload)
+	})
+	defer disconnectTestMiner(miner)
+
+	res, err := request(miner, BLOCKTXS_REQ, append(block.Hash[:], encodePosition(0)...), 2*time.Second)
+	if err != nil {
+		t.Fatalf("Request failed: %v\n", err)
+	}
+	entries, err := decodeBatch(res.typeID, BLOCKTXS_RES, res.payload)
+	if err != nil || len(entries) != 1 || !bytes.Equal(entries[0], tx.Encode()) {
+		t.Errorf("Wrong response: %v, %v\n", entries, err)
+	}
+
+	if res, _ := request(miner, BLOCKTXS_REQ, append(block.Hash[:], encodePosition(1)...), 2*time.Second); res.typeID != NOT_FOUND {
+		t.Errorf("Expected NOT_FOUND, got %v.\n", LogMapping[res.typeID])
+	}
+}
@@ -16,8 +16,63 @@ const (
 	TIME_BRDCST_INTERVAL = 60
 	//Calculate system time every UPDATE_SYS_TIME seconds
 	UPDATE_SYS_TIME = 90
+	//Seconds a client waits for the miner to check its tx before the tx is rejected
+	TX_ADMISSION_TIMEOUT = 5
+	//Txs that wait for the miner's check (e.g., while a block is validated), further txs relayed by miners are dropped
+	TX_ADMISSION_QUEUE = 1000
+	//Seconds to wait for a peer to complete the TLS handshake
+	TLS_HANDSHAKE_TIMEOUT = 10
+	//Peers are disconnected and banned for BAN_DURATION seconds if their score drops to BAN_SCORE. Penalties halve
+	//every SCORE_HALF_LIFE seconds
+	BAN_SCORE       = -100
+	BAN_DURATION    = 24 * 60 * 60
+	SCORE_HALF_LIFE = 600
+
+	//Penalties
+	PENALTY_INVALID_BLOCK  = 20
+	PENALTY_WRONG_RESPONSE = 25
+	PENALTY_OVERSIZED      = 100
+
+	//Batch requests are split into batches of TXS_PER_BATCH txs or BLOCKS_PER_BATCH blocks, up to
+	//MAX_PARALLEL_BATCHES batches are requested in parallel. Batches that are larger than MAX_BATCH_TXS,
+	//MAX_BATCH_BLOCKS or MAX_BATCH_HEADERS are not answered, responses stop adding entries once they are larger than
+	//MAX_BATCH_SIZE bytes
+	TXS_PER_BATCH        = 100
+	BLOCKS_PER_BATCH     = 10
+	MAX_PARALLEL_BATCHES = 8
+	MAX_BATCH_TXS        = 1000
+	MAX_BATCH_BLOCKS     = 100
+	MAX_BATCH_HEADERS    = 2000
+	MAX_BATCH_SIZE       = 10000000
+	//Headers more than HEADERS_HEIGHT_MARGIN blocks above the height a miner announced in the handshake are not
+	//downloaded, miners that send them are penalized
+	HEADERS_HEIGHT_MARGIN = 1000
+
+	//Blocks announced to other miners or rebuilt from compact blocks that are kept to answer and avoid tx requests
+	MAX_RECENT_BLOCKS = 10
+	//Seconds to wait for the missing txs of a compact block
+	BLOCKTXS_TIMEOUT = 10
+	//Hashes that are remembered per peer to avoid announcing txs and blocks the peer already has
+	MAX_KNOWN_INV = 10000
+	//Seconds to wait for requested inventory before it is requested from another peer
+	INV_REQUEST_TIMEOUT = 10
+	//INV and GETDATA messages with more entries are ignored
+	MAX_INV_ENTRIES = 1000
+
+	//Broadcast messages are queued per peer, messages are dropped if the queue of a peer is full. Peers that drop
+	//MAX_DROPPED_MSGS messages in a row are disconnected, as are peers that do not accept a message within
+	//WRITE_TIMEOUT seconds
+	OUTBOUND_QUEUE_SIZE = 1000
+	MAX_DROPPED_MSGS    = 100
+	WRITE_TIMEOUT       = 30
 
 	//Protocol constants
-	IPV4ADDR_SIZE = 4
-	PORT_SIZE     = 2
+	IPV4ADDR_SIZE    = 4
+	IPV6ADDR_SIZE    = 16
+	PORT_SIZE        = 2
+	MAX_HOSTNAME_LEN = 253
+	HANDSHAKE_SIZE   = 18
+	REQUEST_ID_SIZE  = 4
+	SHORT_ID_SIZE    = 8
+	INV_ENTRY_SIZE   = 33
 )
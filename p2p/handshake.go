@@ -0,0 +1,98 @@
+package p2p
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+//The payload of MINER_PING and CLIENT_PING is [port (2)][version (2)][min version (2)][features (4)][chain ID (4)]
+//[height (4)], the PONG carries the same information about the other side. Older peers only send their port and
+//answer with an empty PONG, they are treated as version 0 without features and their chain ID is not checked.
+//Longer payloads are accepted such that later versions can append fields.
+
+var (
+	chainID    uint32 = DEFAULT_CHAIN_ID
+	bestHeight uint32
+)
+
+type handshakeInfo struct {
+	port       string
+	version    uint16
+	minVersion uint16
+	features   uint32
+	chainID    uint32
+	height     uint32
+}
+
+//Must be called before Init, peers on other chains are rejected.
+func SetChainID(id uint32) {
+	chainID = id
+}
+
+//Called by the miner whenever the last block changes, the height is announced in the handshake.
+func SetBestHeight(height uint32) {
+	atomic.StoreUint32(&bestHeight, height)
+}
+
+func encodeHandshake(port int) []byte {
+	payload := make([]byte, HANDSHAKE_SIZE)
+	binary.BigEndian.PutUint16(payload[0:2], uint16(port))
+	binary.BigEndian.PutUint16(payload[2:4], PROTOCOL_VERSION)
+	binary.BigEndian.PutUint16(payload[4:6], MIN_PROTOCOL_VERSION)
+	binary.BigEndian.PutUint32(payload[6:10], FEATURES)
+	binary.BigEndian.PutUint32(payload[10:14], chainID)
+	binary.BigEndian.PutUint32(payload[14:18], atomic.LoadUint32(&bestHeight))
+
+	return payload
+}
+
+//Our own handshake, sent with the PONG.
+func localHandshake() []byte {
+	port, _ := strconv.Atoi(getPort(Ipport))
+	return encodeHandshake(port)
+}
+
+func decodeHandshake(payload []byte) (*handshakeInfo, error) {
+	if len(payload) != PORT_SIZE && len(payload) < HANDSHAKE_SIZE {
+		return nil, errors.New(fmt.Sprintf("Invalid handshake of %v bytes.", len(payload)))
+	}
+
+	info := &handshakeInfo{port: strconv.Itoa(int(binary.BigEndian.Uint16(payload[0:2])))}
+	if len(payload) == PORT_SIZE {
+		return info, nil
+	}
+
+	info.version = binary.BigEndian.Uint16(payload[2:4])
+	info.minVersion = binary.BigEndian.Uint16(payload[4:6])
+	info.features = binary.BigEndian.Uint32(payload[6:10])
+	info.chainID = binary.BigEndian.Uint32(payload[10:14])
+	info.height = binary.BigEndian.Uint32(payload[14:18])
+
+	return info, nil
+}
+
+func (info *handshakeInfo) checkCompatibility() error {
+	if info.version < MIN_PROTOCOL_VERSION {
+		return errors.New(fmt.Sprintf("Protocol version %v is not supported, at least version %v is required.", info.version, MIN_PROTOCOL_VERSION))
+	}
+	if info.minVersion > PROTOCOL_VERSION {
+		return errors.New(fmt.Sprintf("Peer requires protocol version %v, this node runs version %v.", info.minVersion, PROTOCOL_VERSION))
+	}
+	if info.version > 0 && info.chainID != chainID {
+		return errors.New(fmt.Sprintf("Peer is on chain %v, expected chain %v.", info.chainID, chainID))
+	}
+	if missing := REQUIRED_FEATURES &^ info.features; missing != 0 {
+		return errors.New(fmt.Sprintf("Peer does not support the required features %b.", missing))
+	}
+
+	return nil
+}
+
+//Tells the peer why it was rejected before the connection is closed.
+func rejectHandshake(p *peer, reason error) {
+	sendData(p, BuildPacket(HANDSHAKE_ERR, []byte(reason.Error())))
+	p.conn.Close()
+}
@@ -0,0 +1,85 @@
+package p2p
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestDecodeHandshake(t *testing.T) {
+	SetBestHeight(42)
+	defer SetBestHeight(0)
+
+	info, err := decodeHandshake(encodeHandshake(8000))
+	if err != nil {
+		t.Fatalf("Could not decode handshake: %v\n", err)
+	}
+	if info.port != "8000" ||
+		info.version != PROTOCOL_VERSION ||
+		info.minVersion != MIN_PROTOCOL_VERSION ||
+		info.features != FEATURES ||
+		info.chainID != DEFAULT_CHAIN_ID ||
+		info.height != 42 {
+		t.Errorf("Wrong handshake decoded: %+v\n", info)
+	}
+
+	//Older clients only send their port (8000).
+	info, err = decodeHandshake([]byte{31, 64})
+	if err != nil || info.port != "8000" || info.version != 0 {
+		t.Errorf("Could not decode legacy handshake: %v\n", err)
+	}
+
+	//Later versions may append fields.
+	if _, err := decodeHandshake(append(encodeHandshake(8000), 1, 2, 3)); err != nil {
+		t.Errorf("Could not decode extended handshake: %v\n", err)
+	}
+
+	for _, payload := range [][]byte{nil, {31}, make([]byte, HANDSHAKE_SIZE-1)} {
+		if _, err := decodeHandshake(payload); err == nil {
+			t.Errorf("Invalid handshake of %v bytes decoded.\n", len(payload))
+		}
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	compatible := []handshakeInfo{
+		{version: PROTOCOL_VERSION, chainID: DEFAULT_CHAIN_ID},
+		{version: PROTOCOL_VERSION + 1, minVersion: PROTOCOL_VERSION, chainID: DEFAULT_CHAIN_ID},
+		//Legacy peers do not send a chain ID.
+		{},
+	}
+	for _, info := range compatible {
+		if err := info.checkCompatibility(); err != nil {
+			t.Errorf("Compatible peer %+v rejected: %v\n", info, err)
+		}
+	}
+
+	incompatible := []handshakeInfo{
+		{version: PROTOCOL_VERSION + 1, minVersion: PROTOCOL_VERSION + 1, chainID: DEFAULT_CHAIN_ID},
+		{version: PROTOCOL_VERSION, chainID: DEFAULT_CHAIN_ID + 1},
+	}
+	for _, info := range incompatible {
+		if err := info.checkCompatibility(); err == nil {
+			t.Errorf("Incompatible peer %+v accepted.\n", info)
+		}
+	}
+}
+
+func TestRejectHandshake(t *testing.T) {
+	conn1, conn2 := net.Pipe()
+	defer conn1.Close()
+
+	payload := encodeHandshake(8002)
+	payload[10]++ //chain ID
+	go pongRes(newPeer(conn2, "", 0), payload, CLIENT_PING)
+
+	header, reason, err := RcvData(&peer{conn: conn1})
+	if err != nil || header.TypeID != HANDSHAKE_ERR || !strings.Contains(string(reason), "chain") {
+		t.Errorf("Peer on another chain not rejected: %v, %v\n", err, header)
+	}
+
+	//The connection is closed after the rejection.
+	if _, _, err := RcvData(&peer{conn: conn1}); err == nil {
+		t.Error("Connection of rejected peer not closed.")
+	}
+}
@@ -0,0 +1,217 @@
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+)
+
+//Connections between miners are encrypted with TLS 1.3. Every miner has an ed25519 node key and presents a
+//self-signed certificate for it; there is no CA, a peer is identified by the public key of its certificate. The TLS
+//handshake runs before the MINER_PING/MINER_PONG exchange. Clients may still connect without TLS, the listener
+//tells both apart by the first byte (0x16 starts a TLS handshake record, plain messages start with their length).
+
+const (
+	TLS_RECORD_HANDSHAKE = 0x16
+)
+
+var (
+	nodeKey       ed25519.PrivateKey
+	tlsConfig     *tls.Config
+	identityMutex = &sync.Mutex{}
+)
+
+//Wraps a connection whose first byte has already been read from the underlying connection.
+type sniffedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *sniffedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+//Sets the node key and the miners that are allowed to connect, all miners are allowed if allowed is empty. A random
+//node key is used if key is nil or if SetIdentity is not called before Init.
+func SetIdentity(key ed25519.PrivateKey, allowed []ed25519.PublicKey) (err error) {
+	if key == nil {
+		if _, key, err = ed25519.GenerateKey(rand.Reader); err != nil {
+			return err
+		}
+	}
+
+	config, err := newTLSConfig(key, newAllowList(allowed))
+	if err != nil {
+		return err
+	}
+
+	identityMutex.Lock()
+	defer identityMutex.Unlock()
+
+	nodeKey = key
+	tlsConfig = config
+	return nil
+}
+
+//Hex encoded public node key.
+func NodeID() string {
+	getTLSConfig()
+	return hex.EncodeToString(nodeKey.Public().(ed25519.PublicKey))
+}
+
+func getTLSConfig() *tls.Config {
+	identityMutex.Lock()
+	defer identityMutex.Unlock()
+
+	if tlsConfig == nil {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err == nil {
+			tlsConfig, err = newTLSConfig(key, nil)
+			nodeKey = key
+		}
+		if err != nil {
+			panic(fmt.Sprintf("Could not create a node identity: %v", err))
+		}
+	}
+
+	return tlsConfig
+}
+
+func newAllowList(allowed []ed25519.PublicKey) map[string]bool {
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	allowList := make(map[string]bool)
+	for _, pubKey := range allowed {
+		allowList[hex.EncodeToString(pubKey)] = true
+	}
+	return allowList
+}
+
+func newTLSConfig(key ed25519.PrivateKey, allowList map[string]bool) (*tls.Config, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey := key.Public().(ed25519.PublicKey)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: hex.EncodeToString(pubKey)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(100, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, pubKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{cert}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS13,
+		ClientAuth:   tls.RequireAnyClientCert,
+		//Certificates are self-signed, the chain is checked in VerifyPeerCertificate instead.
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			_, err := verifyPeerCertificate(rawCerts, allowList)
+			return err
+		},
+	}, nil
+}
+
+//TLS proves that the peer owns the key of the certificate, the certificate itself only has to be well-formed.
+func verifyPeerCertificate(rawCerts [][]byte, allowList map[string]bool) (ed25519.PublicKey, error) {
+	if len(rawCerts) != 1 {
+		return nil, errors.New(fmt.Sprintf("Expected 1 peer certificate, got %v.", len(rawCerts)))
+	}
+
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, ok := cert.PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("Peer certificate does not contain an ed25519 key.")
+	}
+	if err := cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature); err != nil {
+		return nil, errors.New(fmt.Sprintf("Peer certificate is not self-signed: %v", err))
+	}
+
+	if allowList != nil && !allowList[hex.EncodeToString(pubKey)] {
+		return nil, errors.New(fmt.Sprintf("Peer %x is not allowed.", pubKey))
+	}
+
+	return pubKey, nil
+}
+
+//Runs the client side of the TLS handshake on an outgoing connection.
+func secureConn(conn net.Conn, config *tls.Config) (net.Conn, ed25519.PublicKey, error) {
+	return handshake(tls.Client(conn, config))
+}
+
+//Runs the server side of the TLS handshake if the peer starts one, otherwise the connection is returned as is with
+//an empty identity.
+func acceptConn(conn net.Conn, config *tls.Config) (net.Conn, ed25519.PublicKey, error) {
+	conn.SetReadDeadline(time.Now().Add(TLS_HANDSHAKE_TIMEOUT * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+
+	reader := bufio.NewReader(conn)
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sniffed := &sniffedConn{conn, reader}
+	if first[0] != TLS_RECORD_HANDSHAKE {
+		return sniffed, nil, nil
+	}
+
+	return handshake(tls.Server(sniffed, config))
+}
+
+func handshake(conn *tls.Conn) (net.Conn, ed25519.PublicKey, error) {
+	conn.SetDeadline(time.Now().Add(TLS_HANDSHAKE_TIMEOUT * time.Second))
+	if err := conn.Handshake(); err != nil {
+		return nil, nil, errors.New(fmt.Sprintf("TLS handshake failed: %v", err))
+	}
+	conn.SetDeadline(time.Time{})
+
+	state := conn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil, nil, errors.New("Peer did not present a certificate.")
+	}
+
+	return conn, state.PeerCertificates[0].PublicKey.(ed25519.PublicKey), nil
+}
+
+//Miners must be authenticated and may only be connected once. The allow-list is enforced in the TLS handshake, plain
+//connections (clients) are not affected by it.
+func checkMinerIdentity(p *peer) error {
+	if p.identity == nil {
+		return errors.New("Miner connections require TLS.")
+	}
+
+	for _, other := range peers.getAllPeers(PEERTYPE_MINER) {
+		if other != p && bytes.Equal(other.identity, p.identity) {
+			return errors.New(fmt.Sprintf("Miner %x is already connected.", p.identity))
+		}
+	}
+
+	return nil
+}
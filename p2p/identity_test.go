@@ -0,0 +1,115 @@
+package p2p
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func newTestIdentity(t *testing.T, allowed ...ed25519.PublicKey) (ed25519.PublicKey, *tls.Config) {
+	pubKey, privKey, _ := ed25519.GenerateKey(rand.Reader)
+	config, err := newTLSConfig(privKey, newAllowList(allowed))
+	if err != nil {
+		t.Fatalf("Could not create TLS config: %v\n", err)
+	}
+	return pubKey, config
+}
+
+type accepted struct {
+	conn     net.Conn
+	identity ed25519.PublicKey
+	err      error
+}
+
+func accept(conn net.Conn, config *tls.Config) chan accepted {
+	result := make(chan accepted, 1)
+	go func() {
+		conn, identity, err := acceptConn(conn, config)
+		result <- accepted{conn, identity, err}
+	}()
+	return result
+}
+
+func TestSecureConn(t *testing.T) {
+	serverKey, serverConfig := newTestIdentity(t)
+	clientKey, clientConfig := newTestIdentity(t)
+
+	conn1, conn2 := net.Pipe()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	server := accept(conn2, serverConfig)
+	client, serverIdentity, err := secureConn(conn1, clientConfig)
+	if err != nil {
+		t.Fatalf("TLS handshake failed: %v\n", err)
+	}
+	result := <-server
+	if result.err != nil {
+		t.Fatalf("TLS handshake failed on the server side: %v\n", result.err)
+	}
+
+	//Both sides identify each other by their node key.
+	if !bytes.Equal(serverIdentity, serverKey) || !bytes.Equal(result.identity, clientKey) {
+		t.Errorf("Wrong identities: %x, %x\n", serverIdentity, result.identity)
+	}
+
+	packet := BuildPacket(BLOCK_BRDCST, []byte{1, 2, 3})
+	go client.Write(packet)
+
+	header, payload, err := RcvData(&peer{conn: result.conn})
+	if err != nil || header.TypeID != BLOCK_BRDCST || !reflect.DeepEqual(payload, []byte{1, 2, 3}) {
+		t.Errorf("Receiving data over TLS failed: %v\n", err)
+	}
+}
+
+func TestSecureConnAllowList(t *testing.T) {
+	allowedKey, _ := newTestIdentity(t)
+	_, serverConfig := newTestIdentity(t, allowedKey)
+	_, clientConfig := newTestIdentity(t)
+
+	conn1, conn2 := net.Pipe()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	server := accept(conn2, serverConfig)
+	secureConn(conn1, clientConfig)
+	conn1.Close()
+
+	if result := <-server; result.err == nil {
+		t.Error("Peer that is not on the allow-list was accepted.")
+	}
+}
+
+func TestAcceptPlainConn(t *testing.T) {
+	//Clients connect without TLS even if an allow-list is set.
+	allowedKey, _ := newTestIdentity(t)
+	_, serverConfig := newTestIdentity(t, allowedKey)
+
+	conn1, conn2 := net.Pipe()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	server := accept(conn2, serverConfig)
+	packet, _ := PrepareHandshake(CLIENT_PING, 8002)
+	go conn1.Write(packet)
+
+	result := <-server
+	if result.err != nil || result.identity != nil {
+		t.Fatalf("Plain connection not accepted: %v\n", result.err)
+	}
+
+	//The sniffed byte is not lost.
+	p := &peer{conn: result.conn}
+	header, payload, err := RcvData(p)
+	if info, _ := decodeHandshake(payload); err != nil || header.TypeID != CLIENT_PING || info == nil || info.port != "8002" {
+		t.Errorf("Receiving data over a plain connection failed: %v\n", err)
+	}
+
+	if err := checkMinerIdentity(p); err == nil {
+		t.Error("Miner without identity was accepted.")
+	}
+}
@@ -1,5 +1,9 @@
 package p2p
 
+import (
+	"github.com/bazo-blockchain/bazo-miner/logging"
+)
+
 //All incoming messages are processed here and acted upon accordingly
 func processIncomingMsg(p *peer, header *Header, payload []byte) {
 
@@ -14,9 +18,16 @@ func processIncomingMsg(p *peer, header *Header, payload []byte) {
 	case STAKETX_BRDCST:
 		processTxBrdcst(p, payload, STAKETX_BRDCST)
 	case BLOCK_BRDCST:
-		forwardBlockToMiner(p, payload)
+		processBlockBrdcst(p, payload)
+	case CMPCT_BLOCK_BRDCST:
+		//Missing txs are requested from the sender, the response is received by this goroutine.
+		go processCompactBlock(p, payload)
 	case TIME_BRDCST:
 		processTimeRes(p, payload)
+	case INV:
+		processInv(p, payload)
+	case GETDATA:
+		processGetData(p, payload)
 
 		//REQUESTS
 	case FUNDSTX_REQ:
@@ -40,22 +51,29 @@ func processIncomingMsg(p *peer, header *Header, payload []byte) {
 	case CLIENT_PING:
 		pongRes(p, payload, CLIENT_PING)
 	case NEIGHBOR_REQ:
-		neighborRes(p)
+		neighborRes(p, payload)
 	case INTERMEDIATE_NODES_REQ:
 		intermediateNodesRes(p, payload)
+	case MEMPOOL_REQ:
+		mempoolRes(p, payload)
+	case TXS_REQ:
+		txsRes(p, payload)
+	case BLOCKS_REQ:
+		blocksRes(p, payload)
+	case HEADERS_REQ:
+		headersRes(p, payload)
+	case BLOCKTXS_REQ:
+		blockTxsRes(p, payload)
 
 		//RESPONSES
 	case NEIGHBOR_RES:
-		processNeighborRes(p, payload)
-	case BLOCK_RES:
-		forwardBlockReqToMiner(p, payload)
-	case FUNDSTX_RES:
-		forwardTxReqToMiner(p, payload, FUNDSTX_RES)
-	case ACCTX_RES:
-		forwardTxReqToMiner(p, payload, ACCTX_RES)
-	case CONFIGTX_RES:
-		forwardTxReqToMiner(p, payload, CONFIGTX_RES)
-	case STAKETX_RES:
-		forwardTxReqToMiner(p, payload, STAKETX_RES)
+		processNeighborRes(p, payload, NEIGHBOR_RES)
+	case NEIGHBOR_RES_V2:
+		processNeighborRes(p, payload, NEIGHBOR_RES_V2)
+	case BLOCK_RES, FUNDSTX_RES, ACCTX_RES, CONFIGTX_RES, STAKETX_RES, TXS_RES, BLOCKS_RES, HEADERS_RES, BLOCKTXS_RES, NOT_FOUND:
+		processRes(p, header.TypeID, payload)
+
+	default:
+		logger.WithFields(logging.Fields{logging.PEER: p.getIPPort(), "type": header.TypeID}).Debugf("Ignoring unexpected message")
 	}
 }
@@ -0,0 +1,275 @@
+package p2p
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/logging"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Txs and blocks are announced with an INV to miners that support FEATURE_INV. An INV is a list of entries of
+//INV_ENTRY_SIZE bytes: the broadcast type (e.g., FUNDSTX_BRDCST or BLOCK_BRDCST) followed by the hash. The receiver
+//answers with a GETDATA (same format) for the entries it does not have, the data is then sent as the corresponding
+//broadcast (blocks as CMPCT_BLOCK_BRDCST to miners that support compact blocks). Every peer has a cache of the hashes
+//it is known to have (sent by or to the peer), announcements are only sent to peers that do not know the hash. Older
+//miners still receive the full broadcast. An entry is requested from one peer at a time, the other peers that announce
+//it meanwhile are remembered. If it has not arrived after INV_REQUEST_TIMEOUT seconds, it is requested from the next
+//of them.
+
+type invEntry struct {
+	invType uint8
+	hash    [32]byte
+}
+
+type invBrdcst struct {
+	entry   invEntry
+	full    []byte
+	compact []byte
+}
+
+//An entry that has been requested with a GETDATA from p, the announcers have not been asked yet.
+type invRequest struct {
+	entry      invEntry
+	p          *peer
+	deadline   time.Time
+	announcers []*peer
+}
+
+type invDeadline struct {
+	hash     [32]byte
+	deadline time.Time
+}
+
+//Hashes a peer is known to have, only the last MAX_KNOWN_INV are kept.
+type knownInv struct {
+	hashes map[[32]byte]bool
+	order  [][32]byte
+	l      sync.Mutex
+}
+
+var (
+	//Tx request types of the tx broadcast types.
+	invTxTypes = map[uint8]uint8{
+		FUNDSTX_BRDCST:  FUNDSTX_REQ,
+		ACCTX_BRDCST:    ACCTX_REQ,
+		CONFIGTX_BRDCST: CONFIGTX_REQ,
+		STAKETX_BRDCST:  STAKETX_REQ,
+	}
+
+	//Entries that have been requested with a GETDATA. The queue is ordered by deadline, requests that were sent again
+	//are queued again (the old queue entry is skipped since its deadline does not match).
+	invRequests     = make(map[[32]byte]*invRequest)
+	invRequestQueue []invDeadline
+	invRequestMutex = &sync.Mutex{}
+
+	minerInvBrdcst = make(chan invBrdcst)
+)
+
+func newKnownInv() *knownInv {
+	return &knownInv{hashes: make(map[[32]byte]bool)}
+}
+
+//Returns false if the hash was already known.
+func (known *knownInv) add(hash [32]byte) bool {
+	known.l.Lock()
+	defer known.l.Unlock()
+
+	if known.hashes[hash] {
+		return false
+	}
+
+	known.hashes[hash] = true
+	known.order = append(known.order, hash)
+	if len(known.order) > MAX_KNOWN_INV {
+		delete(known.hashes, known.order[0])
+		known.order = known.order[1:]
+	}
+	return true
+}
+
+//Announces a tx or block to all miners that do not know it yet. compact is only set for blocks.
+func announce(invType uint8, hash [32]byte, full []byte, compact []byte) {
+	msg := invBrdcst{invEntry{invType, hash}, BuildPacket(invType, full), nil}
+	if compact != nil {
+		msg.compact = BuildPacket(CMPCT_BLOCK_BRDCST, compact)
+	}
+
+	minerInvBrdcst <- msg
+}
+
+func announceTx(tx protocol.Transaction, brdcstType uint8) {
+	announce(brdcstType, tx.Hash(), tx.Encode(), nil)
+}
+
+//The packet that announces the entry to the given peer, nil if the peer already knows it.
+func invPacket(p *peer, msg invBrdcst) []byte {
+	if !p.known.add(msg.entry.hash) {
+		return nil
+	}
+
+	switch {
+	case p.hasFeature(FEATURE_INV):
+		return BuildPacket(INV, encodeInv([]invEntry{msg.entry}))
+	case msg.compact != nil && p.hasFeature(FEATURE_CMPCT_BLOCKS):
+		return msg.compact
+	default:
+		return msg.full
+	}
+}
+
+func processInv(p *peer, payload []byte) {
+	entries, err := decodeInv(payload)
+	if err != nil {
+		logger.WithFields(logging.Fields{logging.PEER: p.getIPPort()}).Debugf("Invalid INV: %v", err)
+		return
+	}
+
+	var wanted []invEntry
+	for _, entry := range entries {
+		p.known.add(entry.hash)
+		if !haveInv(entry) && requestInv(p, entry) {
+			wanted = append(wanted, entry)
+		}
+	}
+
+	if len(wanted) > 0 {
+		sendData(p, BuildPacket(GETDATA, encodeInv(wanted)))
+	}
+}
+
+//Sends the requested txs and blocks, entries that are not found are skipped.
+func processGetData(p *peer, payload []byte) {
+	entries, err := decodeInv(payload)
+	if err != nil {
+		logger.WithFields(logging.Fields{logging.PEER: p.getIPPort()}).Debugf("Invalid GETDATA: %v", err)
+		return
+	}
+
+	for _, entry := range entries {
+		var packet []byte
+		if entry.invType == BLOCK_BRDCST {
+			block := readRecentBlock(entry.hash)
+			if block == nil {
+				block = storage.ReadClosedBlock(entry.hash)
+			}
+			if block != nil && p.hasFeature(FEATURE_CMPCT_BLOCKS) {
+				packet = BuildPacket(CMPCT_BLOCK_BRDCST, encodeCompactBlock(block))
+			} else if block != nil {
+				packet = BuildPacket(BLOCK_BRDCST, block.Encode())
+			}
+		} else {
+			tx := storage.ReadOpenTx(entry.hash)
+			if tx == nil {
+				tx = storage.ReadClosedTx(entry.hash)
+			}
+			if tx != nil && isTxOfType(tx, invTxTypes[entry.invType]) {
+				packet = BuildPacket(entry.invType, tx.Encode())
+			}
+		}
+
+		if packet != nil {
+			p.known.add(entry.hash)
+			sendData(p, packet)
+		}
+	}
+}
+
+func haveInv(entry invEntry) bool {
+	if entry.invType == BLOCK_BRDCST {
+		return readRecentBlock(entry.hash) != nil || storage.ReadClosedBlock(entry.hash) != nil
+	}
+
+	return storage.ReadOpenTx(entry.hash) != nil || storage.ReadClosedTx(entry.hash) != nil
+}
+
+//Returns false if the entry has been requested already, the peer is then asked if the request times out. Otherwise
+//the request is recorded. Rejected txs are therefore requested at most once per announcer and timeout.
+func requestInv(p *peer, entry invEntry) bool {
+	invRequestMutex.Lock()
+	defer invRequestMutex.Unlock()
+
+	if request, exists := invRequests[entry.hash]; exists {
+		if request.p == p {
+			return false
+		}
+		for _, announcer := range request.announcers {
+			if announcer == p {
+				return false
+			}
+		}
+		request.announcers = append(request.announcers, p)
+		return false
+	}
+
+	request := &invRequest{entry: entry, p: p, deadline: time.Now().Add(INV_REQUEST_TIMEOUT * time.Second)}
+	invRequests[entry.hash] = request
+	invRequestQueue = append(invRequestQueue, invDeadline{entry.hash, request.deadline})
+	return true
+}
+
+//Requests the entries that have not arrived in time from their next announcer. Requests without announcers left are
+//dropped.
+func expireInvRequests(now time.Time) {
+	retries := make(map[*peer][]invEntry)
+
+	invRequestMutex.Lock()
+	for len(invRequestQueue) > 0 && !invRequestQueue[0].deadline.After(now) {
+		queued := invRequestQueue[0]
+		invRequestQueue = invRequestQueue[1:]
+
+		request := invRequests[queued.hash]
+		if request == nil || request.deadline != queued.deadline {
+			continue
+		}
+		if len(request.announcers) == 0 || haveInv(request.entry) {
+			delete(invRequests, request.entry.hash)
+			continue
+		}
+
+		request.p = request.announcers[0]
+		request.announcers = request.announcers[1:]
+		request.deadline = now.Add(INV_REQUEST_TIMEOUT * time.Second)
+		invRequestQueue = append(invRequestQueue, invDeadline{request.entry.hash, request.deadline})
+		retries[request.p] = append(retries[request.p], request.entry)
+	}
+	invRequestMutex.Unlock()
+
+	for p, entries := range retries {
+		sendData(p, BuildPacket(GETDATA, encodeInv(entries)))
+	}
+}
+
+func invRequestService() {
+	for now := range time.Tick(time.Second) {
+		expireInvRequests(now)
+	}
+}
+
+func encodeInv(entries []invEntry) (encoded []byte) {
+	for _, entry := range entries {
+		encoded = append(encoded, entry.invType)
+		encoded = append(encoded, entry.hash[:]...)
+	}
+
+	return encoded
+}
+
+func decodeInv(payload []byte) (entries []invEntry, err error) {
+	if len(payload)%INV_ENTRY_SIZE != 0 || len(payload)/INV_ENTRY_SIZE > MAX_INV_ENTRIES {
+		return nil, errors.New("Malformed inventory.")
+	}
+
+	for i := 0; i < len(payload); i += INV_ENTRY_SIZE {
+		entry := invEntry{invType: payload[i]}
+		if _, isTx := invTxTypes[entry.invType]; !isTx && entry.invType != BLOCK_BRDCST {
+			return nil, errors.New("Unknown inventory type.")
+		}
+		copy(entry.hash[:], payload[i+1:i+INV_ENTRY_SIZE])
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
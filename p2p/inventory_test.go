@@ -0,0 +1,158 @@
+package p2p
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+type testMsg struct {
+	typeID  uint8
+	payload []byte
+}
+
+//Passes all messages the miner receives to the returned channel.
+func recordingMiner() (*peer, chan testMsg) {
+	received := make(chan testMsg, 10)
+	p := connectTestMiner(func(remote *peer, header *Header, payload []byte) {
+		received <- testMsg{header.TypeID, payload}
+	})
+	return p, received
+}
+
+func receiveMsg(t *testing.T, received chan testMsg) testMsg {
+	t.Helper()
+	select {
+	case msg := <-received:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("No message received.")
+		return testMsg{}
+	}
+}
+
+func TestKnownInv(t *testing.T) {
+	hash := func(i int) (hash [32]byte) {
+		binary.BigEndian.PutUint32(hash[:], uint32(i))
+		return hash
+	}
+
+	known := newKnownInv()
+	for i := 0; i <= MAX_KNOWN_INV; i++ {
+		known.add(hash(i))
+	}
+
+	if known.add(hash(MAX_KNOWN_INV)) {
+		t.Error("Last hash is not known.")
+	}
+	if !known.add(hash(0)) {
+		t.Error("Oldest hash was not evicted.")
+	}
+}
+
+func TestInvPacket(t *testing.T) {
+	msg := invBrdcst{invEntry{BLOCK_BRDCST, [32]byte{5}}, []byte{1}, []byte{2}}
+
+	inv := newPeer(nil, "8000", PEERTYPE_MINER)
+	inv.features = FEATURES
+	if packet := invPacket(inv, msg); packet == nil || packet[4] != INV {
+		t.Errorf("Expected INV, got %v.\n", packet)
+	}
+	if packet := invPacket(inv, msg); packet != nil {
+		t.Error("Block announced twice.")
+	}
+
+	compact := newPeer(nil, "8000", PEERTYPE_MINER)
+	compact.features = FEATURE_CMPCT_BLOCKS
+	if packet := invPacket(compact, msg); !bytes.Equal(packet, msg.compact) {
+		t.Errorf("Expected compact block, got %v.\n", packet)
+	}
+
+	//The sender of a tx does not get it back.
+	legacy := newPeer(nil, "8000", PEERTYPE_MINER)
+	legacy.known.add([32]byte{6})
+	if packet := invPacket(legacy, msg); !bytes.Equal(packet, msg.full) {
+		t.Errorf("Expected full block, got %v.\n", packet)
+	}
+	if packet := invPacket(legacy, invBrdcst{invEntry{FUNDSTX_BRDCST, [32]byte{6}}, []byte{3}, nil}); packet != nil {
+		t.Error("Tx announced to its sender.")
+	}
+}
+
+func TestProcessInv(t *testing.T) {
+	tx := &protocol.FundsTx{Amount: 5, Fee: 1, TxCnt: 5}
+	storage.WriteOpenTx(tx)
+	defer storage.DeleteOpenTx(tx)
+
+	miner, received := recordingMiner()
+	defer disconnectTestMiner(miner)
+
+	//Only entries that are missing are requested.
+	missing := invEntry{FUNDSTX_BRDCST, [32]byte{7}}
+	defer delete(invRequests, missing.hash)
+	processInv(miner, encodeInv([]invEntry{{FUNDSTX_BRDCST, tx.Hash()}, missing}))
+	if msg := receiveMsg(t, received); msg.typeID != GETDATA || !bytes.Equal(msg.payload, encodeInv([]invEntry{missing})) {
+		t.Errorf("Wrong GETDATA: %v\n", msg)
+	}
+
+	//Requested entries are not requested from other miners until the request times out.
+	other, otherReceived := recordingMiner()
+	defer disconnectTestMiner(other)
+	processInv(miner, encodeInv([]invEntry{missing}))
+	processInv(other, encodeInv([]invEntry{missing}))
+	select {
+	case msg := <-received:
+		t.Errorf("Entry requested twice: %v\n", msg)
+	case msg := <-otherReceived:
+		t.Errorf("Entry requested twice: %v\n", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	//The next announcer is asked once the request times out.
+	expireInvRequests(time.Now().Add(INV_REQUEST_TIMEOUT * time.Second))
+	if msg := receiveMsg(t, otherReceived); msg.typeID != GETDATA || !bytes.Equal(msg.payload, encodeInv([]invEntry{missing})) {
+		t.Errorf("Wrong GETDATA: %v\n", msg)
+	}
+	expireInvRequests(time.Now().Add(2 * INV_REQUEST_TIMEOUT * time.Second))
+	invRequestMutex.Lock()
+	_, exists := invRequests[missing.hash]
+	invRequestMutex.Unlock()
+	if exists {
+		t.Error("Request without announcers left was not dropped.")
+	}
+
+	if _, err := decodeInv(append(encodeInv([]invEntry{missing}), 1)); err == nil {
+		t.Error("Malformed inventory accepted.")
+	}
+	if _, err := decodeInv(encodeInv([]invEntry{{TIME_BRDCST, [32]byte{}}})); err == nil {
+		t.Error("Unknown inventory type accepted.")
+	}
+}
+
+func TestProcessGetData(t *testing.T) {
+	tx := &protocol.FundsTx{Amount: 6, Fee: 1, TxCnt: 6}
+	storage.WriteOpenTx(tx)
+	defer storage.DeleteOpenTx(tx)
+	block := newCompactTestBlock(6, tx)
+	addRecentBlock(block, nil)
+
+	miner, received := recordingMiner()
+	defer disconnectTestMiner(miner)
+
+	//Entries that are not found are skipped, blocks are sent as compact blocks.
+	processGetData(miner, encodeInv([]invEntry{{FUNDSTX_BRDCST, [32]byte{8}}, {FUNDSTX_BRDCST, tx.Hash()}, {BLOCK_BRDCST, block.Hash}}))
+	if msg := receiveMsg(t, received); msg.typeID != FUNDSTX_BRDCST || !bytes.Equal(msg.payload, tx.Encode()) {
+		t.Errorf("Wrong tx: %v\n", msg)
+	}
+	if msg := receiveMsg(t, received); msg.typeID != CMPCT_BLOCK_BRDCST || !bytes.Equal(msg.payload, encodeCompactBlock(block)) {
+		t.Errorf("Wrong block: %v\n", msg)
+	}
+
+	if miner.known.add(tx.Hash()) {
+		t.Error("Sent tx is not known by the miner.")
+	}
+}
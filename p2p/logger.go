@@ -1,18 +1,15 @@
 package p2p
 
 import (
-	"github.com/bazo-blockchain/bazo-miner/storage"
-	"log"
+	"github.com/bazo-blockchain/bazo-miner/logging"
 )
 
 var (
 	LogMapping map[uint8]string
-	logger     *log.Logger
+	logger     = logging.New(logging.P2P)
 )
 
 func InitLogging() {
-	logger = storage.InitLogger()
-
 	//Instead of logging just the integer, we log the corresponding semantic meaning, makes scrolling through
 	//the log file more comfortable
 	LogMapping = make(map[uint8]string)
@@ -24,6 +21,7 @@ func InitLogging() {
 	LogMapping[6] = "BLOCK_BRDCST"
 	LogMapping[7] = "BLOCK_HEADER_BRDCST"
 	LogMapping[8] = "TX_BRDCST_ACK"
+	LogMapping[9] = "TX_BRDCST_ERR"
 
 	LogMapping[10] = "FUNDSTX_REQ"
 	LogMapping[11] = "ACCTX_REQ"
@@ -34,6 +32,7 @@ func InitLogging() {
 	LogMapping[16] = "ACC_REQ"
 	LogMapping[17] = "ROOTACC_REQ"
 	LogMapping[18] = "INTERMEDIATE_NODES_REQ"
+	LogMapping[19] = "MEMPOOL_REQ"
 
 	LogMapping[20] = "FUNDSTX_RES"
 	LogMapping[21] = "ACCTX_RES"
@@ -44,17 +43,32 @@ func InitLogging() {
 	LogMapping[26] = "ACC_RES"
 	LogMapping[27] = "ROOTACC_RES"
 	LogMapping[28] = "INTERMEDIATE_NODES_RES"
+	LogMapping[29] = "MEMPOOL_RES"
 
 	LogMapping[30] = "NEIGHBOR_REQ"
 
 	LogMapping[40] = "NEIGHBOR_RES"
+	LogMapping[41] = "NEIGHBOR_RES_V2"
 
 	LogMapping[50] = "TIME_BRDCST"
+	LogMapping[51] = "CMPCT_BLOCK_BRDCST"
+	LogMapping[52] = "INV"
+	LogMapping[53] = "GETDATA"
+
+	LogMapping[60] = "TXS_REQ"
+	LogMapping[61] = "BLOCKS_REQ"
+	LogMapping[62] = "HEADERS_REQ"
+	LogMapping[63] = "BLOCKTXS_REQ"
+	LogMapping[70] = "TXS_RES"
+	LogMapping[71] = "BLOCKS_RES"
+	LogMapping[72] = "HEADERS_RES"
+	LogMapping[73] = "BLOCKTXS_RES"
 
 	LogMapping[100] = "MINER_PING"
 	LogMapping[101] = "MINER_PONG"
 	LogMapping[102] = "CLIENT_PING"
 	LogMapping[103] = "CLIENT_PONG"
+	LogMapping[104] = "HANDSHAKE_ERR"
 
 	LogMapping[110] = "NOT_FOUND"
 }
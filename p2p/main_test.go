@@ -3,22 +3,30 @@ package p2p
 import (
 	"os"
 	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/storage"
 )
 
 var (
 	MINER_IPPORT = "127.0.0.1:8000"
 )
 
+const (
+	TestDBFileName = "test.db"
+)
+
 //Corresponds largely to server.go -> Init(...)
 func TestMain(m *testing.M) {
 	//Used for some tests, the bootstarp server is listening at 8000 at the same time
 	Ipport = "127.0.0.1:9000"
 	InitLogging()
+	//Bans are persisted
+	storage.Init(TestDBFileName, MINER_IPPORT)
 
 	peers.minerConns = make(map[*peer]bool)
 	peers.clientConns = make(map[*peer]bool)
 
-	BlockIn = make(chan []byte)
+	BlockIn = make(chan *IncomingBlock)
 	BlockOut = make(chan []byte)
 
 	iplistChan = make(chan string, MIN_MINERS)
@@ -36,5 +44,9 @@ func TestMain(m *testing.M) {
 	//Bootstrap server
 	go listener("127.0.0.1:8000")
 
-	os.Exit(m.Run())
+	retCode := m.Run()
+
+	storage.TearDown()
+	os.Remove(TestDBFileName)
+	os.Exit(retCode)
 }
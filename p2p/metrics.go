@@ -0,0 +1,61 @@
+package p2p
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+//Bytes sent and received per message type, including the header. Updated atomically by all peer goroutines.
+var bytesIn, bytesOut [256]uint64
+
+//Broadcast messages dropped because the outbound queue of a peer was full.
+var droppedMsgs uint64
+
+type Metrics struct {
+	Miners      int
+	Clients     int
+	BytesIn     map[string]uint64
+	BytesOut    map[string]uint64
+	DroppedMsgs uint64
+}
+
+func countBytesIn(typeID uint8, length int) {
+	atomic.AddUint64(&bytesIn[typeID], uint64(length))
+}
+
+func countDroppedMsg() {
+	atomic.AddUint64(&droppedMsgs, 1)
+}
+
+func countBytesOut(packet []byte) {
+	if len(packet) >= HEADER_LEN {
+		atomic.AddUint64(&bytesOut[packet[4]], uint64(len(packet)))
+	}
+}
+
+//Message types without traffic are omitted, unknown types are labeled by their type ID (e.g., TYPE_200).
+func ReadMetrics() Metrics {
+	metrics := Metrics{
+		Miners:      len(peers.getAllPeers(PEERTYPE_MINER)),
+		Clients:     len(peers.getAllPeers(PEERTYPE_CLIENT)),
+		BytesIn:     make(map[string]uint64),
+		BytesOut:    make(map[string]uint64),
+		DroppedMsgs: atomic.LoadUint64(&droppedMsgs),
+	}
+
+	for typeID := range bytesIn {
+		name, ok := LogMapping[uint8(typeID)]
+		if !ok {
+			name = fmt.Sprintf("TYPE_%d", typeID)
+		}
+
+		if in := atomic.LoadUint64(&bytesIn[typeID]); in > 0 {
+			metrics.BytesIn[name] = in
+		}
+		if out := atomic.LoadUint64(&bytesOut[typeID]); out > 0 {
+			metrics.BytesOut[name] = out
+		}
+	}
+
+	return metrics
+}
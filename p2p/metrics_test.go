@@ -0,0 +1,16 @@
+package p2p
+
+import (
+	"testing"
+)
+
+func TestReadMetrics(t *testing.T) {
+	countBytesIn(FUNDSTX_BRDCST, 10)
+	countBytesIn(200, 20)
+	countBytesIn(201, 30)
+
+	metrics := ReadMetrics()
+	if metrics.BytesIn["FUNDSTX_BRDCST"] < 10 || metrics.BytesIn["TYPE_200"] != 20 || metrics.BytesIn["TYPE_201"] != 30 {
+		t.Errorf("Wrong bytes in: %v\n", metrics.BytesIn)
+	}
+}
@@ -1,12 +1,15 @@
 package p2p
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 )
 
 var (
 	//Block from the network, to the miner
-	BlockIn chan []byte = make(chan []byte)
+	BlockIn chan *IncomingBlock = make(chan *IncomingBlock)
 	//Block from the miner, to the network
 	BlockOut       chan []byte = make(chan []byte)
 	//BlockHeader from the miner, to the clients
@@ -14,24 +17,61 @@ var (
 
 	VerifiedTxsOut chan []byte = make(chan []byte)
 
-	//Data requested by miner, to allow parallelism, we have a chan for every tx type.
-	FundsTxChan  = make(chan *protocol.FundsTx)
-	AccTxChan    = make(chan *protocol.AccTx)
-	ConfigTxChan = make(chan *protocol.ConfigTx)
-	StakeTxChan  = make(chan *protocol.StakeTx)
-
-	BlockReqChan = make(chan []byte)
+	//Txs received from the network, checked by the miner against the current state before they enter the mempool.
+	TxAdmissionChan = make(chan *TxAdmission, TX_ADMISSION_QUEUE)
 )
 
+//The miner sends the result of the check to Result, nil if the tx is admitted.
+type TxAdmission struct {
+	Tx     protocol.Transaction
+	Result chan error
+}
+
+//The miner sends the result of the validation to Result, nil if the block is valid or already known. The sender of an
+//invalid block is penalized.
+type IncomingBlock struct {
+	Payload []byte
+	Result  chan error
+}
+
+//Reason why a tx was rejected, the code is sent back to clients with TX_BRDCST_ERR.
+type TxError struct {
+	Code   uint8
+	Reason string
+}
+
+func NewTxError(code uint8, format string, a ...interface{}) *TxError {
+	return &TxError{code, fmt.Sprintf(format, a...)}
+}
+
+func (err *TxError) Error() string {
+	return err.Reason
+}
+
 //This is for blocks and txs that the miner successfully validated.
 func forwardBlockBrdcstToMiner() {
 	for {
-		block := <-BlockOut
-		toBrdcst := BuildPacket(BLOCK_BRDCST, block)
-		minerBrdcstMsg <- toBrdcst
+		announceBlock(<-BlockOut)
 	}
 }
 
+//Txs submitted locally (e.g., over RPC) after they have been admitted to the mempool.
+func BroadcastTx(tx protocol.Transaction) {
+	var brdcstType uint8
+	switch tx.(type) {
+	case *protocol.FundsTx:
+		brdcstType = FUNDSTX_BRDCST
+	case *protocol.AccTx:
+		brdcstType = ACCTX_BRDCST
+	case *protocol.ConfigTx:
+		brdcstType = CONFIGTX_BRDCST
+	case *protocol.StakeTx:
+		brdcstType = STAKETX_BRDCST
+	}
+
+	announceTx(tx, brdcstType)
+}
+
 func forwardBlockHeaderBrdcstToMiner() {
 	for {
 		blockHeader := <- BlockHeaderOut
@@ -46,50 +86,51 @@ func forwardVerifiedTxsToMiner() {
 	}
 }
 
-func forwardBlockToMiner(p *peer, payload []byte) {
-	BlockIn <- payload
+//Queues a tx relayed by a miner until the miner has checked it, done is called with the result. Validating a block can
+//take longer than a client would wait, relayed txs are therefore not rejected on a timeout. Returns false if the queue
+//is full.
+func queueTx(tx protocol.Transaction, done func(err error)) bool {
+	admission := &TxAdmission{tx, make(chan error, 1)}
+
+	select {
+	case TxAdmissionChan <- admission:
+	default:
+		return false
+	}
+
+	go func() {
+		done(<-admission.Result)
+	}()
+	return true
 }
 
-//These are transactions the miner specifically requested.
-func forwardTxReqToMiner(p *peer, payload []byte, txType uint8) {
-	if payload == nil {
-		return
+//Blocks until the miner has checked the tx. Txs are rejected if the miner does not answer in time (e.g., because it
+//is busy validating a block), a client can resubmit them.
+func admitTx(tx protocol.Transaction) error {
+	admission := &TxAdmission{tx, make(chan error, 1)}
+	timeout := time.After(TX_ADMISSION_TIMEOUT * time.Second)
+
+	select {
+	case TxAdmissionChan <- admission:
+	case <-timeout:
+		return NewTxError(TX_ERR_TIMEOUT, "Tx could not be checked in time.")
 	}
 
-	switch txType {
-	case FUNDSTX_RES:
-		var fundsTx *protocol.FundsTx
-		fundsTx = fundsTx.Decode(payload)
-		if fundsTx == nil {
-			return
-		}
-		FundsTxChan <- fundsTx
-	case ACCTX_RES:
-		var accTx *protocol.AccTx
-		accTx = accTx.Decode(payload)
-		if accTx == nil {
-			return
-		}
-		AccTxChan <- accTx
-	case CONFIGTX_RES:
-		var configTx *protocol.ConfigTx
-		configTx = configTx.Decode(payload)
-		if configTx == nil {
-			return
-		}
-		ConfigTxChan <- configTx
-	case STAKETX_RES:
-		var stakeTx *protocol.StakeTx
-		stakeTx = stakeTx.Decode(payload)
-		if stakeTx == nil {
-			return
-		}
-		StakeTxChan <- stakeTx
+	select {
+	case err := <-admission.Result:
+		return err
+	case <-timeout:
+		return NewTxError(TX_ERR_TIMEOUT, "Tx could not be checked in time.")
 	}
 }
 
-func forwardBlockReqToMiner(p *peer, payload []byte) {
-	BlockReqChan <- payload
+func forwardBlockToMiner(p *peer, payload []byte) {
+	block := &IncomingBlock{payload, make(chan error, 1)}
+	BlockIn <- block
+
+	if err := <-block.Result; err != nil {
+		penalize(p, PENALTY_INVALID_BLOCK, fmt.Sprintf("Sent invalid block: %v", err))
+	}
 }
 
 func ReadSystemTime() int64 {
@@ -1,44 +1,261 @@
 package p2p
 
 import (
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/logging"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
 )
 
-//Both block and tx requests are handled asymmetricaly, using channels as inter-communication
-//All the request in this file are specifically initiated by the miner package
-func BlockReq(hash [32]byte) error {
+//All the requests in this file are initiated by the miner package. Block and tx requests are only sent to miners
+//that support FEATURE_REQUEST_IDS: the request starts with a request ID (REQUEST_ID_SIZE bytes) and the response
+//(or NOT_FOUND) starts with the same ID, which routes it to the waiting request. Requests that time out are removed,
+//late responses are dropped. If a peer does not have the data or answers with something else, the request is
+//retried with another peer. Batch requests are in batch.go.
+
+type pendingRequest struct {
+	p        *peer
+	response chan response
+}
 
-	p := peers.getRandomPeer(PEERTYPE_MINER)
-	if p == nil {
-		return errors.New("Couldn't get a connection, request not transmitted.")
+type response struct {
+	typeID  uint8
+	payload []byte
+}
+
+var (
+	txResTypes = map[uint8]uint8{
+		FUNDSTX_REQ:  FUNDSTX_RES,
+		ACCTX_REQ:    ACCTX_RES,
+		CONFIGTX_REQ: CONFIGTX_RES,
+		STAKETX_REQ:  STAKETX_RES,
 	}
 
-	packet := BuildPacket(BLOCK_REQ, hash[:])
-	sendData(p, packet)
-	return nil
+	lastRequestID   uint32
+	pendingRequests = make(map[uint32]*pendingRequest)
+	requestsMutex   = &sync.Mutex{}
+)
+
+//Returns the tx with the given hash, reqType is one of FUNDSTX_REQ, ACCTX_REQ, CONFIGTX_REQ and STAKETX_REQ.
+func FetchTx(hash [32]byte, reqType uint8, timeout time.Duration) (protocol.Transaction, error) {
+	var tx protocol.Transaction
+	err := fetch(reqType, FEATURE_REQUEST_IDS, func() []byte { return hash[:] }, timeout, func(typeID uint8, payload []byte) (bool, error) {
+		if typeID != txResTypes[reqType] {
+			return false, errors.New(fmt.Sprintf("Requested tx %x, got %v.", hash, LogMapping[typeID]))
+		}
+		if tx = decodeTxRes(typeID, payload); tx == nil || tx.Hash() != hash {
+			return false, errors.New(fmt.Sprintf("Requested tx %x, got something else.", hash))
+		}
+		return true, nil
+	})
+
+	return tx, err
+}
+
+func FetchBlock(hash [32]byte, timeout time.Duration) (*protocol.Block, error) {
+	var block *protocol.Block
+	err := fetch(BLOCK_REQ, FEATURE_REQUEST_IDS, func() []byte { return hash[:] }, timeout, func(typeID uint8, payload []byte) (bool, error) {
+		if block = decodeBlockRes(typeID, payload); block == nil || block.Hash != hash {
+			return false, errors.New(fmt.Sprintf("Requested block %x, got something else.", hash))
+		}
+		return true, nil
+	})
+
+	return block, err
+}
+
+//Returns the last block of a random miner.
+func FetchLastBlock(timeout time.Duration) (*protocol.Block, error) {
+	var block *protocol.Block
+	err := fetch(BLOCK_REQ, FEATURE_REQUEST_IDS, func() []byte { return nil }, timeout, func(typeID uint8, payload []byte) (bool, error) {
+		if block = decodeBlockRes(typeID, payload); block == nil {
+			return false, errors.New("Requested the last block, got something else.")
+		}
+		return true, nil
+	})
+
+	return block, err
 }
 
-func LastBlockReq() error {
+//Asks one miner that supports the features after the other until the request is complete. handle returns whether a
+//response completes the request, miners whose responses are rejected are penalized. The payload is built for every
+//miner, such that partially answered requests only ask for the missing parts. Waits for a miner to connect if there
+//is none, gives up if all miners were asked.
+func fetch(reqType uint8, features uint32, payload func() []byte, timeout time.Duration, handle func(typeID uint8, payload []byte) (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	asked := make(map[*peer]bool)
+	err := errors.New("No miner connected.")
+
+	for time.Now().Before(deadline) {
+		p := selectPeer(asked, features)
+		if p == nil {
+			if len(asked) > 0 {
+				return err
+			}
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		asked[p] = true
+
+		var res response
+		if res, err = request(p, reqType, payload(), time.Until(deadline)); err != nil {
+			return err
+		}
+		if res.typeID == NOT_FOUND {
+			err = errors.New(fmt.Sprintf("Not found by %v.", p.getIPPort()))
+			continue
+		}
 
-	p := peers.getRandomPeer(PEERTYPE_MINER)
-	if p == nil {
-		return errors.New("Couldn't get a connection, request not transmitted.")
+		var complete bool
+		if complete, err = handle(res.typeID, res.payload); err != nil {
+			penalize(p, PENALTY_WRONG_RESPONSE, err.Error())
+			continue
+		}
+		if !complete {
+			err = errors.New(fmt.Sprintf("Partially answered by %v.", p.getIPPort()))
+			continue
+		}
+
+		return nil
 	}
 
-	packet := BuildPacket(BLOCK_REQ, nil)
-	sendData(p, packet)
-	return nil
+	return errors.New("Request timed out.")
 }
 
-//Request specific transaction
-func TxReq(hash [32]byte, reqType uint8) error {
+//Random miner that supports the features and was not asked yet. Miners with fewer pending requests are preferred,
+//such that parallel requests are spread across miners.
+func selectPeer(asked map[*peer]bool, features uint32) *peer {
+	pending := make(map[*peer]int)
+	requestsMutex.Lock()
+	for _, req := range pendingRequests {
+		pending[req.p]++
+	}
+	requestsMutex.Unlock()
 
-	p := peers.getRandomPeer(PEERTYPE_MINER)
-	if p == nil {
-		return errors.New("Couldn't get a connection, request not transmitted.")
+	var candidates []*peer
+	for _, p := range peers.getAllPeers(PEERTYPE_MINER) {
+		if asked[p] || !p.hasFeature(features) {
+			continue
+		}
+		if len(candidates) > 0 && pending[p] > pending[candidates[0]] {
+			continue
+		}
+		if len(candidates) > 0 && pending[p] < pending[candidates[0]] {
+			candidates = nil
+		}
+		candidates = append(candidates, p)
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[rand.Intn(len(candidates))]
+}
+
+func request(p *peer, reqType uint8, payload []byte, timeout time.Duration) (response, error) {
+	id := atomic.AddUint32(&lastRequestID, 1)
+	req := &pendingRequest{p, make(chan response, 1)}
+
+	requestsMutex.Lock()
+	pendingRequests[id] = req
+	requestsMutex.Unlock()
+
+	defer func() {
+		requestsMutex.Lock()
+		delete(pendingRequests, id)
+		requestsMutex.Unlock()
+	}()
+
+	sendData(p, BuildPacket(reqType, append(encodeRequestID(id), payload...)))
+
+	select {
+	case res := <-req.response:
+		return res, nil
+	case <-time.After(timeout):
+		return response{}, errors.New(fmt.Sprintf("Request to %v timed out.", p.getIPPort()))
+	}
+}
+
+//Routes responses to the waiting request, responses to unknown (e.g., timed out) requests are dropped.
+func processRes(p *peer, typeID uint8, payload []byte) {
+	if !p.hasFeature(FEATURE_REQUEST_IDS) || len(payload) < REQUEST_ID_SIZE {
+		return
+	}
+	id := binary.BigEndian.Uint32(payload[:REQUEST_ID_SIZE])
+
+	requestsMutex.Lock()
+	req := pendingRequests[id]
+	if req != nil && req.p == p {
+		delete(pendingRequests, id)
+	}
+	requestsMutex.Unlock()
+
+	if req == nil || req.p != p {
+		logger.WithFields(logging.Fields{logging.PEER: p.getIPPort(), "type": LogMapping[typeID]}).Debugf("Dropped response to unknown request %v", id)
+		return
+	}
+
+	req.response <- response{typeID, payload[REQUEST_ID_SIZE:]}
+}
+
+func encodeRequestID(id uint32) []byte {
+	encoded := make([]byte, REQUEST_ID_SIZE)
+	binary.BigEndian.PutUint32(encoded, id)
+	return encoded
+}
+
+//Requests of peers that support request IDs start with the ID, it is nil for other peers. The ID is sent back with
+//the response.
+func splitRequestID(p *peer, payload []byte) (id []byte, rest []byte, err error) {
+	if !p.hasFeature(FEATURE_REQUEST_IDS) {
+		return nil, payload, nil
+	}
+	if len(payload) < REQUEST_ID_SIZE {
+		return nil, nil, errors.New("Request without ID.")
+	}
+
+	//The capacity is limited such that appending the response does not overwrite the request.
+	return payload[:REQUEST_ID_SIZE:REQUEST_ID_SIZE], payload[REQUEST_ID_SIZE:], nil
+}
+
+func decodeTxRes(typeID uint8, payload []byte) protocol.Transaction {
+	switch typeID {
+	case FUNDSTX_RES:
+		var fundsTx *protocol.FundsTx
+		if fundsTx = fundsTx.Decode(payload); fundsTx != nil {
+			return fundsTx
+		}
+	case ACCTX_RES:
+		var accTx *protocol.AccTx
+		if accTx = accTx.Decode(payload); accTx != nil {
+			return accTx
+		}
+	case CONFIGTX_RES:
+		var configTx *protocol.ConfigTx
+		if configTx = configTx.Decode(payload); configTx != nil {
+			return configTx
+		}
+	case STAKETX_RES:
+		var stakeTx *protocol.StakeTx
+		if stakeTx = stakeTx.Decode(payload); stakeTx != nil {
+			return stakeTx
+		}
 	}
 
-	packet := BuildPacket(reqType, hash[:])
-	sendData(p, packet)
 	return nil
 }
+
+func decodeBlockRes(typeID uint8, payload []byte) *protocol.Block {
+	if typeID != BLOCK_RES || len(payload) == 0 {
+		return nil
+	}
+
+	var block *protocol.Block
+	return block.Decode(payload)
+}
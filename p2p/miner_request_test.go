@@ -0,0 +1,134 @@
+package p2p
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Connects a miner that supports request IDs, its requests are answered by handle.
+func connectTestMiner(handle func(remote *peer, header *Header, payload []byte)) *peer {
+	local, remoteConn := net.Pipe()
+	p := newPeer(local, "8000", PEERTYPE_MINER)
+	p.features = FEATURES
+	//Scores are kept by node ID, every test miner gets its own.
+	p.identity, _, _ = ed25519.GenerateKey(rand.Reader)
+	remote := newPeer(remoteConn, "8000", PEERTYPE_MINER)
+	remote.features = FEATURES
+
+	go func() {
+		for {
+			header, payload, err := RcvData(remote)
+			if err != nil {
+				return
+			}
+			handle(remote, header, payload)
+		}
+	}()
+	go func() {
+		for {
+			header, payload, err := RcvData(p)
+			if err != nil {
+				return
+			}
+			processIncomingMsg(p, header, payload)
+		}
+	}()
+
+	peers.add(p)
+	return p
+}
+
+func disconnectTestMiner(p *peer) {
+	peers.delete(p)
+	p.conn.Close()
+}
+
+func notFound(remote *peer, header *Header, payload []byte) {
+	id, _, _ := splitRequestID(remote, payload)
+	sendData(remote, BuildPacket(NOT_FOUND, id))
+}
+
+func TestFetchBlock(t *testing.T) {
+	block := protocol.NewBlock([32]byte{}, 7)
+	block.Hash = [32]byte{7}
+	storage.WriteClosedBlock(block)
+	defer storage.DeleteClosedBlock(block.Hash)
+
+	//The block is found even if the first miner that is asked does not have it.
+	without := connectTestMiner(notFound)
+	defer disconnectTestMiner(without)
+	with := connectTestMiner(func(remote *peer, header *Header, payload []byte) {
+		blockRes(remote, payload)
+	})
+	defer disconnectTestMiner(with)
+
+	fetched, err := FetchBlock(block.Hash, 2*time.Second)
+	if err != nil || fetched.Height != 7 {
+		t.Fatalf("Could not fetch block: %v\n", err)
+	}
+
+	if _, err := FetchBlock([32]byte{8}, 2*time.Second); err == nil {
+		t.Error("Fetched a block that no miner has.")
+	}
+
+	if len(pendingRequests) != 0 {
+		t.Errorf("%v requests were not cleaned up.\n", len(pendingRequests))
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	requests := make(chan []byte, 1)
+	silent := connectTestMiner(func(remote *peer, header *Header, payload []byte) {
+		requests <- payload
+	})
+	defer disconnectTestMiner(silent)
+
+	start := time.Now()
+	if _, err := FetchBlock([32]byte{9}, 200*time.Millisecond); err == nil || time.Since(start) > time.Second {
+		t.Errorf("Request did not time out: %v\n", err)
+	}
+	if len(pendingRequests) != 0 {
+		t.Errorf("%v requests were not cleaned up.\n", len(pendingRequests))
+	}
+
+	//Late responses are dropped.
+	processRes(silent, NOT_FOUND, (<-requests)[:REQUEST_ID_SIZE])
+}
+
+func TestFetchWrongResponse(t *testing.T) {
+	other := protocol.NewBlock([32]byte{}, 3)
+	other.Hash = [32]byte{3}
+
+	wrong := connectTestMiner(func(remote *peer, header *Header, payload []byte) {
+		id, _, _ := splitRequestID(remote, payload)
+		sendData(remote, BuildPacket(BLOCK_RES, append(id, other.Encode()...)))
+	})
+	defer disconnectTestMiner(wrong)
+
+	if _, err := FetchBlock([32]byte{4}, 2*time.Second); err == nil {
+		t.Error("Wrong block accepted.")
+	}
+	if getTestScore(wrong) >= 0 {
+		t.Error("Miner that sent the wrong block was not penalized.")
+	}
+}
+
+func TestTxResWithoutRequestID(t *testing.T) {
+	conn1, conn2 := net.Pipe()
+	defer conn1.Close()
+	defer conn2.Close()
+
+	//Peers that do not support request IDs get the response without ID.
+	go txRes(newPeer(conn2, "", PEERTYPE_CLIENT), make([]byte, 32), FUNDSTX_REQ)
+
+	header, payload, err := RcvData(&peer{conn: conn1})
+	if err != nil || header.TypeID != NOT_FOUND || len(payload) != 0 {
+		t.Errorf("Wrong response to a request without ID: %v, %v\n", header, payload)
+	}
+}
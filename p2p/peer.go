@@ -1,10 +1,13 @@
 package p2p
 
 import (
+	"crypto/ed25519"
+	"encoding/hex"
 	"math/rand"
 	"net"
-	"strings"
 	"sync"
+
+	"github.com/bazo-blockchain/bazo-miner/logging"
 )
 
 const (
@@ -14,14 +17,26 @@ const (
 
 //The reason we use an additional listener port is because the port the miner connected to this peer
 //is not the same as the one it listens to for new connections. When we are queried for neighbors
-//we send the IP address in p.conn.RemotAddr() with the listenerPort.
+//we send the IP address in p.conn.RemotAddr() with the listenerPort. The identity is the public node key of peers
+//connected with TLS (see identity.go), it is empty for plain connections. Version, features and height are
+//announced in the handshake (see handshake.go). The peer is penalized when it misbehaves (see score.go). known
+//contains the hashes of the txs and blocks the peer has (see inventory.go). ch is the bounded outbound queue of the
+//broadcast service (see enqueue), l protects writes to the connection.
 type peer struct {
 	conn         net.Conn
 	ch           chan []byte
+	chClosed     bool
+	dropped      int
+	queueMutex   sync.Mutex
 	l            sync.Mutex
 	listenerPort string
 	time         int64
 	peerType     uint
+	identity     ed25519.PublicKey
+	version      uint16
+	features     uint32
+	height       uint32
+	known        *knownInv
 }
 
 //Block constructor, argument is the previous block in the blockchain.
@@ -33,6 +48,7 @@ func newPeer(conn net.Conn, listenerPort string, peerType uint) *peer {
 	p.listenerPort = listenerPort
 	p.time = 0
 	p.peerType = peerType
+	p.known = newKnownInv()
 
 	return p
 }
@@ -44,7 +60,10 @@ type peersStruct struct {
 	peerMutex   sync.Mutex
 }
 
-func (peers peersStruct) contains(ipport string, peerType uint) bool {
+func (peers *peersStruct) contains(ipport string, peerType uint) bool {
+	peers.peerMutex.Lock()
+	defer peers.peerMutex.Unlock()
+
 	var peerConns map[*peer]bool
 
 	if peerType == PEERTYPE_MINER {
@@ -64,14 +83,79 @@ func (peers peersStruct) contains(ipport string, peerType uint) bool {
 }
 
 func (p *peer) getIPPort() string {
-	ip := strings.Split(p.conn.RemoteAddr().String(), ":")
 	//Cut off original port.
-	port := p.listenerPort
+	return net.JoinHostPort(p.getHost(), p.listenerPort)
+}
+
+func (p *peer) getHost() string {
+	host, _, err := net.SplitHostPort(p.conn.RemoteAddr().String())
+	if err != nil {
+		host = p.conn.RemoteAddr().String()
+	}
+	return host
+}
 
-	return ip[0] + ":" + port
+//Hex encoded identity, empty for plain connections.
+func (p *peer) getID() string {
+	return hex.EncodeToString(p.identity)
 }
 
-func (peers peersStruct) add(p *peer) {
+//Miners are banned by their node ID, peers without identity by their IP address.
+func (p *peer) getBanID() string {
+	if p.identity != nil {
+		return p.getID()
+	}
+	return p.getHost()
+}
+
+func (p *peer) hasFeature(feature uint32) bool {
+	return p.features&feature == feature
+}
+
+//Queues a broadcast message without blocking. If the queue is full, the message is dropped. A peer that does not
+//consume its queue for MAX_DROPPED_MSGS messages in a row is stuck and gets disconnected. Returns false if the
+//message was dropped.
+func (p *peer) enqueue(msg []byte) bool {
+	p.queueMutex.Lock()
+	defer p.queueMutex.Unlock()
+
+	if p.ch == nil || p.chClosed {
+		return false
+	}
+
+	select {
+	case p.ch <- msg:
+		p.dropped = 0
+		return true
+	default:
+		p.dropped++
+		countDroppedMsg()
+		if p.dropped == MAX_DROPPED_MSGS {
+			logger.WithFields(logging.Fields{logging.PEER: p.getIPPort(), logging.NODE: p.getID()}).Warnf("Outbound queue full, disconnecting")
+			p.conn.Close()
+		}
+		return false
+	}
+}
+
+//Called once the peer is disconnected, peerBroadcast then stops.
+func (p *peer) closeQueue() {
+	p.queueMutex.Lock()
+	defer p.queueMutex.Unlock()
+
+	if p.ch != nil && !p.chClosed {
+		close(p.ch)
+		p.chClosed = true
+	}
+}
+
+func (p *peer) setHandshake(info *handshakeInfo) {
+	p.version = info.version
+	p.features = info.features
+	p.height = info.height
+}
+
+func (peers *peersStruct) add(p *peer) {
 	peers.peerMutex.Lock()
 	defer peers.peerMutex.Unlock()
 
@@ -83,7 +167,7 @@ func (peers peersStruct) add(p *peer) {
 	}
 }
 
-func (peers peersStruct) delete(p *peer) {
+func (peers *peersStruct) delete(p *peer) {
 	peers.peerMutex.Lock()
 	defer peers.peerMutex.Unlock()
 
@@ -95,7 +179,10 @@ func (peers peersStruct) delete(p *peer) {
 	}
 }
 
-func (peers peersStruct) len(peerType uint) (length int) {
+func (peers *peersStruct) len(peerType uint) (length int) {
+	peers.peerMutex.Lock()
+	defer peers.peerMutex.Unlock()
+
 	if peerType == PEERTYPE_MINER {
 		length = len(peers.minerConns)
 	}
@@ -106,7 +193,7 @@ func (peers peersStruct) len(peerType uint) (length int) {
 	return length
 }
 
-func (peers peersStruct) getRandomPeer(peerType uint) (p *peer) {
+func (peers *peersStruct) getRandomPeer(peerType uint) (p *peer) {
 	//Acquire list before locking, otherwise deadlock
 	peerList := peers.getAllPeers(peerType)
 
@@ -117,7 +204,7 @@ func (peers peersStruct) getRandomPeer(peerType uint) (p *peer) {
 	}
 }
 
-func (peers peersStruct) getAllPeers(peerType uint) []*peer {
+func (peers *peersStruct) getAllPeers(peerType uint) []*peer {
 	peers.peerMutex.Lock()
 	defer peers.peerMutex.Unlock()
 
@@ -137,7 +224,7 @@ func (peers peersStruct) getAllPeers(peerType uint) []*peer {
 	return peerList
 }
 
-func (peers peersStruct) getMinerTimes() (peerTimes []int64) {
+func (peers *peersStruct) getMinerTimes() (peerTimes []int64) {
 	peers.peerMutex.Lock()
 	defer peers.peerMutex.Unlock()
 
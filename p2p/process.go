@@ -2,8 +2,12 @@ package p2p
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/logging"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
+	"net"
 	"strconv"
 )
 
@@ -12,58 +16,110 @@ import (
 func processTxBrdcst(p *peer, payload []byte, brdcstType uint8) {
 	var tx protocol.Transaction
 
-	//Make sure the transaction can be properly decoded, verification is done at a later stage to reduce latency
+	//Make sure the transaction can be properly decoded
 	switch brdcstType {
 	case FUNDSTX_BRDCST:
 		var fTx *protocol.FundsTx
-		fTx = fTx.Decode(payload)
-		if fTx == nil {
-			return
+		if fTx = fTx.Decode(payload); fTx != nil {
+			tx = fTx
 		}
-		tx = fTx
 	case ACCTX_BRDCST:
 		var aTx *protocol.AccTx
-		aTx = aTx.Decode(payload)
-		if aTx == nil {
-			return
+		if aTx = aTx.Decode(payload); aTx != nil {
+			tx = aTx
 		}
-		tx = aTx
 	case CONFIGTX_BRDCST:
 		var cTx *protocol.ConfigTx
-		cTx = cTx.Decode(payload)
-		if cTx == nil {
-			return
+		if cTx = cTx.Decode(payload); cTx != nil {
+			tx = cTx
 		}
-		tx = cTx
 	case STAKETX_BRDCST:
 		var sTx *protocol.StakeTx
-		sTx = sTx.Decode(payload)
-		if sTx == nil {
-			return
+		if sTx = sTx.Decode(payload); sTx != nil {
+			tx = sTx
 		}
-		tx = sTx
 	}
 
-	//Response tx acknowledgment if the peer is a client
-	if !peers.minerConns[p] {
-		packet := BuildPacket(TX_BRDCST_ACK, nil)
-		sendData(p, packet)
+	if tx == nil {
+		rejectTx(p, NewTxError(TX_ERR_DECODE, "Tx could not be decoded."))
+		return
 	}
 
+	txLogger := logger.WithFields(logging.Fields{logging.TX: tx.Hash(), logging.PEER: p.getIPPort()})
+	p.known.add(tx.Hash())
+
 	if storage.ReadOpenTx(tx.Hash()) != nil {
-		logger.Printf("Received transaction (%x) already in the mempool.\n", tx.Hash())
+		txLogger.Debugf("Received transaction already in the mempool")
+		rejectTx(p, NewTxError(TX_ERR_DUPLICATE, "Tx already in the mempool."))
 		return
 	}
 	if storage.ReadClosedTx(tx.Hash()) != nil {
-		logger.Printf("Received transaction (%x) already validated.\n", tx.Hash())
+		txLogger.Debugf("Received transaction already validated")
+		rejectTx(p, NewTxError(TX_ERR_DUPLICATE, "Tx already validated."))
+		return
+	}
+
+	if p.peerType == PEERTYPE_MINER {
+		if !queueTx(tx, func(err error) { acceptTx(p, tx, brdcstType, err) }) {
+			txLogger.Debugf("Received transaction dropped, too many txs wait for admission")
+		}
+		return
+	}
+
+	acceptTx(p, tx, brdcstType, admitTx(tx))
+}
+
+//Writes the tx to the mempool and announces it if the miner admitted it.
+func acceptTx(p *peer, tx protocol.Transaction, brdcstType uint8, err error) {
+	txLogger := logger.WithFields(logging.Fields{logging.TX: tx.Hash(), logging.PEER: p.getIPPort()})
+
+	//Invalid txs must not be rebroadcast, otherwise every miner forwards them to all its neighbors.
+	if err != nil {
+		txLogger.Infof("Received transaction rejected: %v", err)
+		rejectTx(p, err)
 		return
 	}
 
 	//Write to mempool and rebroadcast
-	logger.Printf("Writing transaction (%x) in the mempool.\n", tx.Hash())
-	storage.WriteOpenTx(tx)
-	toBrdcst := BuildPacket(brdcstType, payload)
-	minerBrdcstMsg <- toBrdcst
+	txLogger.Debugf("Writing transaction in the mempool")
+	if err := storage.WriteOpenTx(tx); err != nil {
+		txLogger.Infof("Transaction rejected by the mempool: %v", err)
+		rejectTx(p, NewTxError(TX_ERR_MEMPOOL, "%v", err))
+		return
+	}
+
+	//Response tx acknowledgment if the peer is a client
+	if p.peerType != PEERTYPE_MINER {
+		packet := BuildPacket(TX_BRDCST_ACK, nil)
+		sendData(p, packet)
+	}
+
+	announceTx(tx, brdcstType)
+}
+
+//The sender knows the block, it is not announced back.
+func processBlockBrdcst(p *peer, payload []byte) {
+	var block *protocol.Block
+	if block = block.Decode(payload); block != nil {
+		p.known.add(block.Hash)
+	}
+
+	forwardBlockToMiner(p, payload)
+}
+
+//Clients are told why their tx was rejected, miners are not.
+func rejectTx(p *peer, err error) {
+	if p.peerType == PEERTYPE_MINER {
+		return
+	}
+
+	txErr, ok := err.(*TxError)
+	if !ok {
+		txErr = NewTxError(TX_ERR_STATE, "%v", err)
+	}
+
+	packet := BuildPacket(TX_BRDCST_ERR, append([]byte{txErr.Code}, txErr.Reason...))
+	sendData(p, packet)
 }
 
 func processTimeRes(p *peer, payload []byte) {
@@ -77,12 +133,21 @@ func processTimeRes(p *peer, payload []byte) {
 	p.time = time
 }
 
-func processNeighborRes(p *peer, payload []byte) {
-	//Parse the incoming ipv4 addresses.
-	ipportList := _processNeighborRes(payload)
+func processNeighborRes(p *peer, payload []byte, resType uint8) {
+	var ipportList []string
+	if resType == NEIGHBOR_RES_V2 {
+		var err error
+		//Addresses that were parsed before the error are still used.
+		if ipportList, err = _processNeighborResV2(payload); err != nil {
+			logger.WithFields(logging.Fields{logging.PEER: p.getIPPort()}).Warnf("Invalid neighbor list: %v", err)
+		}
+	} else {
+		//Parse the incoming ipv4 addresses.
+		ipportList = _processNeighborRes(payload)
+	}
 
 	for _, ipportIter := range ipportList {
-		logger.Printf("IP/Port received: %v\n", ipportIter)
+		logger.WithFields(logging.Fields{logging.PEER: p.getIPPort()}).Debugf("IP/Port received: %v", ipportIter)
 		//iplistChan is a buffered channel to handle ips asynchronously.
 		iplistChan <- ipportIter
 	}
@@ -111,3 +176,46 @@ func _processNeighborRes(payload []byte) (ipportList []string) {
 
 	return ipportList
 }
+
+func _processNeighborResV2(payload []byte) (ipportList []string, err error) {
+	for index := 0; index < len(payload); {
+		family := payload[index]
+		index++
+
+		var host string
+		switch family {
+		case ADDR_IPV4, ADDR_IPV6:
+			size := IPV4ADDR_SIZE
+			if family == ADDR_IPV6 {
+				size = IPV6ADDR_SIZE
+			}
+			if index+size > len(payload) {
+				return ipportList, errors.New("Truncated IP address.")
+			}
+			host = net.IP(payload[index : index+size]).String()
+			index += size
+		case ADDR_HOSTNAME:
+			if index >= len(payload) || index+1+int(payload[index]) > len(payload) {
+				return ipportList, errors.New("Truncated hostname.")
+			}
+			host = string(payload[index+1 : index+1+int(payload[index])])
+			index += 1 + int(payload[index])
+			if !isHostname(host) {
+				return ipportList, errors.New(fmt.Sprintf("Invalid hostname %q.", host))
+			}
+		default:
+			//The size of unknown entries is unknown, the rest of the list cannot be parsed.
+			return ipportList, errors.New(fmt.Sprintf("Unknown address family %v.", family))
+		}
+
+		if index+PORT_SIZE > len(payload) {
+			return ipportList, errors.New("Truncated port.")
+		}
+		port := binary.BigEndian.Uint16(payload[index : index+PORT_SIZE])
+		index += PORT_SIZE
+
+		ipportList = append(ipportList, net.JoinHostPort(host, strconv.Itoa(int(port))))
+	}
+
+	return ipportList, nil
+}
@@ -1,7 +1,12 @@
 package p2p
 
 import (
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
 )
 
 //Test the parsing of serialized ip addresses
@@ -30,3 +35,62 @@ func TestProcessNeighborRes(t *testing.T) {
 		t.Errorf("Parsing IP address failed: %v\n", ipportList[3])
 	}
 }
+
+func TestProcessNeighborResV2(t *testing.T) {
+	payload := []byte{
+		ADDR_IPV4, 23, 24, 122, 66, 31, 69,
+		ADDR_IPV6, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 31, 64,
+		ADDR_HOSTNAME, 5, 'b', 'a', 'z', 'o', '1', 156, 64,
+	}
+
+	ipportList, err := _processNeighborResV2(payload)
+	if err != nil || !reflect.DeepEqual(ipportList, []string{"23.24.122.66:8005", "[2001:db8::1]:8000", "bazo1:40000"}) {
+		t.Errorf("Parsing neighbor list failed: %v (%v)\n", ipportList, err)
+	}
+
+	//Entries before an invalid one are still returned.
+	invalid := [][]byte{
+		{ADDR_IPV4, 1, 2, 3},
+		{ADDR_HOSTNAME, 3, 'a', ' ', 'b', 31, 64},
+		{ADDR_HOSTNAME, 10, 'a'},
+		{7, 1, 2, 3, 4, 31, 64},
+		{ADDR_IPV6, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 31},
+	}
+	for _, entry := range invalid {
+		ipportList, err := _processNeighborResV2(append(payload[:7:7], entry...))
+		if err == nil || !reflect.DeepEqual(ipportList, []string{"23.24.122.66:8005"}) {
+			t.Errorf("Invalid entry %v not detected: %v\n", entry, ipportList)
+		}
+	}
+}
+
+//Txs relayed by miners wait for the miner's check without a timeout, the sender is not blocked meanwhile.
+func TestProcessTxBrdcstQueued(t *testing.T) {
+	tx := &protocol.FundsTx{Amount: 9, Fee: 1, TxCnt: 9}
+	defer storage.DeleteOpenTx(tx)
+
+	miner := connectTestMiner(func(remote *peer, header *Header, payload []byte) {})
+	defer disconnectTestMiner(miner)
+
+	processTxBrdcst(miner, tx.Encode(), FUNDSTX_BRDCST)
+
+	var admission *TxAdmission
+	select {
+	case admission = <-TxAdmissionChan:
+	default:
+		t.Fatal("Tx was not queued for admission.")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if storage.ReadOpenTx(tx.Hash()) != nil {
+		t.Fatal("Tx was written to the mempool before it was admitted.")
+	}
+
+	admission.Result <- nil
+	for i := 0; i < 20 && storage.ReadOpenTx(tx.Hash()) == nil; i++ {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if storage.ReadOpenTx(tx.Hash()) == nil {
+		t.Error("Admitted tx was not written to the mempool.")
+	}
+}
@@ -14,6 +14,7 @@ const (
 	BLOCK_BRDCST        = 6
 	BLOCK_HEADER_BRDCST = 7
 	TX_BRDCST_ACK       = 8
+	TX_BRDCST_ERR       = 9
 
 	FUNDSTX_REQ            = 10
 	ACCTX_REQ              = 11
@@ -24,6 +25,7 @@ const (
 	ACC_REQ                = 16
 	ROOTACC_REQ            = 17
 	INTERMEDIATE_NODES_REQ = 18
+	MEMPOOL_REQ            = 19
 
 	FUNDSTX_RES            = 20
 	ACCTX_RES              = 21
@@ -34,21 +36,90 @@ const (
 	ACC_RES                = 26
 	ROOTACC_RES            = 27
 	INTERMEDIATE_NODES_RES = 28
+	MEMPOOL_RES            = 29
 
-	NEIGHBOR_REQ = 30
-	NEIGHBOR_RES = 40
+	NEIGHBOR_REQ    = 30
+	NEIGHBOR_RES    = 40
+	NEIGHBOR_RES_V2 = 41
 
 	TIME_BRDCST = 50
+	//Sent instead of BLOCK_BRDCST to miners that support compact blocks (see compact.go)
+	CMPCT_BLOCK_BRDCST = 51
+	//Inventory announcements and requests (see inventory.go)
+	INV     = 52
+	GETDATA = 53
+
+	//Batch requests (see batch.go)
+	TXS_REQ      = 60
+	BLOCKS_REQ   = 61
+	HEADERS_REQ  = 62
+	BLOCKTXS_REQ = 63
+	TXS_RES      = 70
+	BLOCKS_RES   = 71
+	HEADERS_RES  = 72
+	BLOCKTXS_RES = 73
 
 	MINER_PING  = 100
 	MINER_PONG  = 101
 	CLIENT_PING = 102
 	CLIENT_PONG = 103
+	//Sent instead of a PONG if the handshake is rejected, the payload is a human-readable reason
+	HANDSHAKE_ERR = 104
 
 	//Used to signal error
 	NOT_FOUND = 110
 )
 
+//Error codes of TX_BRDCST_ERR. The payload is the error code followed by a human-readable reason.
+const (
+	TX_ERR_DECODE    = 1
+	TX_ERR_DUPLICATE = 2
+	TX_ERR_FEE       = 3
+	TX_ERR_SIGNATURE = 4
+	TX_ERR_TXCNT     = 5
+	TX_ERR_BALANCE   = 6
+	TX_ERR_STATE     = 7
+	TX_ERR_MEMPOOL   = 8
+	TX_ERR_TIMEOUT   = 9
+)
+
+//A NEIGHBOR_REQ without payload is answered with a NEIGHBOR_RES that only contains IPv4 addresses (6 bytes per
+//entry). Miners that understand the new encoding send NEIGHBOR_VERSION_2 as payload and get a NEIGHBOR_RES_V2, older
+//miners ignore the payload and still answer with a NEIGHBOR_RES.
+const (
+	NEIGHBOR_VERSION_2 = 2
+)
+
+//Exchanged in the handshake (see handshake.go). Peers accept each other if both run at least the minimum version of
+//the other side. Peers that only send their port are treated as version 0.
+const (
+	PROTOCOL_VERSION     = 1
+	MIN_PROTOCOL_VERSION = 0
+
+	DEFAULT_CHAIN_ID = 1
+)
+
+//Feature flags, FEATURES is announced in the handshake. Peers that lack one of the REQUIRED_FEATURES are rejected.
+const (
+	FEATURE_NEIGHBOR_V2  = 1 << 0
+	FEATURE_REQUEST_IDS  = 1 << 1
+	FEATURE_BATCH_REQ    = 1 << 2
+	FEATURE_HEADERS      = 1 << 3
+	FEATURE_CMPCT_BLOCKS = 1 << 4
+	FEATURE_INV          = 1 << 5
+
+	FEATURES          = FEATURE_NEIGHBOR_V2 | FEATURE_REQUEST_IDS | FEATURE_BATCH_REQ | FEATURE_HEADERS | FEATURE_CMPCT_BLOCKS | FEATURE_INV
+	REQUIRED_FEATURES = 0
+)
+
+//Address families of NEIGHBOR_RES_V2 entries. An entry is [family][address][port (2 bytes)], hostnames are prefixed
+//by their length (1 byte).
+const (
+	ADDR_IPV4     = 1
+	ADDR_IPV6     = 2
+	ADDR_HOSTNAME = 3
+)
+
 type Header struct {
 	Len    uint32
 	TypeID uint8
@@ -7,10 +7,10 @@ func neighborReq() {
 
 	p := peers.getRandomPeer(PEERTYPE_MINER)
 	if p == nil {
-		logger.Print("Could not fetch a random peer.\n")
+		logger.Warnf("Could not fetch a random peer.")
 		return
 	}
 
-	packet := BuildPacket(NEIGHBOR_REQ, nil)
+	packet := BuildPacket(NEIGHBOR_REQ, []byte{NEIGHBOR_VERSION_2})
 	sendData(p, packet)
 }
@@ -1,16 +1,20 @@
 package p2p
 
 import (
-	"bytes"
 	"encoding/binary"
+	"github.com/bazo-blockchain/bazo-miner/logging"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/bazo-blockchain/bazo-miner/storage"
-	"strconv"
-	"strings"
+	"net"
 )
 
 //This file responds to incoming requests from miners in a synchronous fashion
 func txRes(p *peer, payload []byte, txKind uint8) {
+	id, payload, err := splitRequestID(p, payload)
+	if err != nil || len(payload) < 32 {
+		return
+	}
+
 	var txHash [32]byte
 	copy(txHash[:], payload[0:32])
 
@@ -27,7 +31,7 @@ func txRes(p *peer, payload []byte, txKind uint8) {
 
 	//In case it was not found, send a corresponding message back
 	if tx == nil {
-		packet := BuildPacket(NOT_FOUND, nil)
+		packet := BuildPacket(NOT_FOUND, id)
 		sendData(p, packet)
 		return
 	}
@@ -35,13 +39,13 @@ func txRes(p *peer, payload []byte, txKind uint8) {
 	var packet []byte
 	switch txKind {
 	case FUNDSTX_REQ:
-		packet = BuildPacket(FUNDSTX_RES, tx.Encode())
+		packet = BuildPacket(FUNDSTX_RES, append(id, tx.Encode()...))
 	case ACCTX_REQ:
-		packet = BuildPacket(ACCTX_RES, tx.Encode())
+		packet = BuildPacket(ACCTX_RES, append(id, tx.Encode()...))
 	case CONFIGTX_REQ:
-		packet = BuildPacket(CONFIGTX_RES, tx.Encode())
+		packet = BuildPacket(CONFIGTX_RES, append(id, tx.Encode()...))
 	case STAKETX_REQ:
-		packet = BuildPacket(STAKETX_RES, tx.Encode())
+		packet = BuildPacket(STAKETX_RES, append(id, tx.Encode()...))
 	}
 
 	sendData(p, packet)
@@ -53,6 +57,11 @@ func blockRes(p *peer, payload []byte) {
 	var block *protocol.Block
 	var blockHash [32]byte
 
+	id, payload, err := splitRequestID(p, payload)
+	if err != nil {
+		return
+	}
+
 	//If no specific block is requested, send latest
 	if len(payload) > 0 {
 		copy(blockHash[:], payload[:32])
@@ -63,15 +72,104 @@ func blockRes(p *peer, payload []byte) {
 		block = storage.ReadLastClosedBlock()
 	}
 
+	//Pruned blocks only consist of the header, peers could not validate them.
+	if block != nil && block.Height > 0 && block.Height <= storage.ReadPrunedHeight() {
+		block = nil
+	}
+
 	if block != nil {
-		packet = BuildPacket(BLOCK_RES, block.Encode())
+		packet = BuildPacket(BLOCK_RES, append(id, block.Encode()...))
 	} else {
-		packet = BuildPacket(NOT_FOUND, nil)
+		packet = BuildPacket(NOT_FOUND, id)
 	}
 
 	sendData(p, packet)
 }
 
+//Answers with the requested txs that are in open or closed storage (see batch.go).
+func txsRes(p *peer, payload []byte) {
+	id, payload, err := splitRequestID(p, payload)
+	if err != nil || len(payload) < 1 || (len(payload)-1)%32 != 0 || (len(payload)-1)/32 > MAX_BATCH_TXS {
+		return
+	}
+
+	reqType := payload[0]
+	var encodedTxs [][]byte
+	var size int
+	for i := 1; i < len(payload); i += 32 {
+		var txHash [32]byte
+		copy(txHash[:], payload[i:i+32])
+
+		tx := storage.ReadOpenTx(txHash)
+		if tx == nil {
+			tx = storage.ReadClosedTx(txHash)
+		}
+		if tx == nil || !isTxOfType(tx, reqType) {
+			continue
+		}
+
+		encodedTx := tx.Encode()
+		if size += len(encodedTx); size > MAX_BATCH_SIZE {
+			break
+		}
+		encodedTxs = append(encodedTxs, encodedTx)
+	}
+
+	sendData(p, BuildPacket(TXS_RES, append(id, encodeBatch(encodedTxs)...)))
+}
+
+//Answers with the closed blocks of the requested height range up to the first block that is missing or pruned.
+func blocksRes(p *peer, payload []byte) {
+	prunedHeight := storage.ReadPrunedHeight()
+	heightRangeRes(p, payload, BLOCKS_RES, MAX_BATCH_BLOCKS, func(block *protocol.Block) []byte {
+		if block.Height > 0 && block.Height <= prunedHeight {
+			return nil
+		}
+		return block.Encode()
+	})
+}
+
+//Answers with the headers of the requested height range up to the first block that is missing. Headers of pruned
+//blocks are still available.
+func headersRes(p *peer, payload []byte) {
+	heightRangeRes(p, payload, HEADERS_RES, MAX_BATCH_HEADERS, encodeHeader)
+}
+
+//The response contains at least one entry if the first block is available. A block for which encode returns nil ends
+//the response.
+func heightRangeRes(p *peer, payload []byte, resType uint8, maxBlocks uint32, encode func(block *protocol.Block) []byte) {
+	id, payload, err := splitRequestID(p, payload)
+	if err != nil || len(payload) != 8 {
+		return
+	}
+
+	from := binary.BigEndian.Uint32(payload[0:4])
+	to := binary.BigEndian.Uint32(payload[4:8])
+	if to < from || to-from >= maxBlocks {
+		return
+	}
+
+	var entries [][]byte
+	var size int
+	for i := uint32(0); i <= to-from; i++ {
+		block := storage.ReadClosedBlockByHeight(from + i)
+		if block == nil {
+			break
+		}
+
+		entry := encode(block)
+		if entry == nil {
+			break
+		}
+		if size += len(entry); size > MAX_BATCH_SIZE && len(entries) > 0 {
+			break
+		}
+		entries = append(entries, entry)
+	}
+
+	sendData(p, BuildPacket(resType, append(id, encodeBatch(entries)...)))
+}
+
 //Response the requested block SPV header
 func blockHeaderRes(p *peer, payload []byte) {
 	var encodedHeader, packet []byte
@@ -112,6 +210,32 @@ func accRes(p *peer, payload []byte) {
 	sendData(p, packet)
 }
 
+//The payload is either empty (whole mempool) or the sender hash followed by the recipient hash, a zero hash matches
+//all accounts.
+func mempoolRes(p *peer, payload []byte) {
+	var packet []byte
+	if filter, ok := _mempoolReq(payload); ok {
+		packet = BuildPacket(MEMPOOL_RES, storage.QueryMempool(filter).Encode())
+	} else {
+		packet = BuildPacket(NOT_FOUND, nil)
+	}
+
+	sendData(p, packet)
+}
+
+func _mempoolReq(payload []byte) (filter storage.MempoolFilter, ok bool) {
+	if len(payload) == 0 {
+		return filter, true
+	}
+	if len(payload) != 64 {
+		return filter, false
+	}
+
+	copy(filter.Sender[:], payload[0:32])
+	copy(filter.Recipient[:], payload[32:64])
+	return filter, true
+}
+
 func rootAccRes(p *peer, payload []byte) {
 	var packet []byte
 	var hash [32]byte
@@ -125,29 +249,42 @@ func rootAccRes(p *peer, payload []byte) {
 
 //Completes the handshake with another miner.
 func pongRes(p *peer, payload []byte, peerType uint) {
-	//Payload consists of a 2 bytes array (port number [big endian encoded]).
-	port := _pongRes(payload)
-
-	if port != "" {
-		p.listenerPort = port
-	} else {
+	//Payload starts with the listener port (2 bytes, big endian encoded), see handshake.go.
+	info, err := decodeHandshake(payload)
+	if err != nil {
 		p.conn.Close()
 		return
 	}
 
+	p.listenerPort = info.port
+	if err := info.checkCompatibility(); err != nil {
+		logger.WithFields(logging.Fields{logging.PEER: p.getIPPort(), "version": info.version}).Warnf("Handshake rejected: %v", err)
+		rejectHandshake(p, err)
+		return
+	}
+	p.setHandshake(info)
+
 	//Restrict amount of connected miners
 	if peers.len(PEERTYPE_MINER) >= MAX_MINERS {
 		return
 	}
 
+	if peerType == MINER_PING {
+		if err := checkMinerIdentity(p); err != nil {
+			logger.WithFields(logging.Fields{logging.PEER: p.getIPPort(), logging.NODE: p.getID()}).Warnf("Miner handshake rejected: %v", err)
+			p.conn.Close()
+			return
+		}
+	}
+
 	//Complete handshake
 	var packet []byte
 	if peerType == MINER_PING {
 		p.peerType = PEERTYPE_MINER
-		packet = BuildPacket(MINER_PONG, nil)
+		packet = BuildPacket(MINER_PONG, localHandshake())
 	} else if peerType == CLIENT_PING {
 		p.peerType = PEERTYPE_CLIENT
-		packet = BuildPacket(CLIENT_PONG, nil)
+		packet = BuildPacket(CLIENT_PONG, localHandshake())
 	}
 
 	go peerConn(p)
@@ -155,19 +292,8 @@ func pongRes(p *peer, payload []byte, peerType uint) {
 	sendData(p, packet)
 }
 
-//Decouple the function for testing.
-func _pongRes(payload []byte) string {
-	if len(payload) == PORT_SIZE {
-		return strconv.Itoa(int(binary.BigEndian.Uint16(payload[0:PORT_SIZE])))
-	} else {
-		return ""
-	}
-}
-
-func neighborRes(p *peer) {
-	//only supporting ipv4 addresses for now, makes fixed-size structure easier
-	//in the future following structure is possible:
-	//1) nr of ipv4 addresses, 2) nr of ipv6 addresses, followed by list of both
+//Peers that do not send a version get IPv4 addresses only (see NEIGHBOR_VERSION_2).
+func neighborRes(p *peer, payload []byte) {
 	var packet []byte
 	var ipportList []string
 	peerList := peers.getAllPeers(PEERTYPE_MINER)
@@ -176,38 +302,59 @@ func neighborRes(p *peer) {
 		ipportList = append(ipportList, p.getIPPort())
 	}
 
-	packet = BuildPacket(NEIGHBOR_RES, _neighborRes(ipportList))
+	if len(payload) > 0 && payload[0] >= NEIGHBOR_VERSION_2 {
+		packet = BuildPacket(NEIGHBOR_RES_V2, _neighborResV2(ipportList))
+	} else {
+		packet = BuildPacket(NEIGHBOR_RES, _neighborRes(ipportList))
+	}
 	sendData(p, packet)
 }
 
-//Decouple functionality to facilitate testing
+//Decouple functionality to facilitate testing. Addresses that are not IPv4 are skipped.
 func _neighborRes(ipportList []string) (payload []byte) {
-
-	payload = make([]byte, len(ipportList)*6) //6 = size of ipv4 address + port
-	index := 0
 	for _, ipportIter := range ipportList {
-		ipport := strings.Split(ipportIter, ":")
-		split := strings.Split(ipport[0], ".")
+		host, port, err := splitIPPort(ipportIter)
+		if err != nil {
+			continue
+		}
+
+		ip := net.ParseIP(host).To4()
+		if ip == nil {
+			continue
+		}
 
 		//Serializing IP:Port addr tuples
-		for ipv4addr := 0; ipv4addr < 4; ipv4addr++ {
-			addrPart, err := strconv.Atoi(split[ipv4addr])
-			if err != nil {
-				return nil
-			}
-			payload[index] = byte(addrPart)
-			index++
+		payload = append(payload, ip...)
+		payload = append(payload, encodePort(port)...)
+	}
+
+	return payload
+}
+
+//Addresses that can neither be encoded as IP nor as hostname are skipped.
+func _neighborResV2(ipportList []string) (payload []byte) {
+	for _, ipportIter := range ipportList {
+		host, port, err := splitIPPort(ipportIter)
+		if err != nil {
+			continue
 		}
 
-		port, _ := strconv.ParseUint(ipport[1], 10, 16)
+		if ip := net.ParseIP(host); ip != nil {
+			if ipv4 := ip.To4(); ipv4 != nil {
+				payload = append(payload, ADDR_IPV4)
+				payload = append(payload, ipv4...)
+			} else {
+				payload = append(payload, ADDR_IPV6)
+				payload = append(payload, ip.To16()...)
+			}
+		} else if isHostname(host) {
+			payload = append(payload, ADDR_HOSTNAME, byte(len(host)))
+			payload = append(payload, host...)
+		} else {
+			continue
+		}
 
-		//serialize port number
-		var buf bytes.Buffer
-		binary.Write(&buf, binary.BigEndian, port)
-		payload[index] = buf.Bytes()[len(buf.Bytes())-2]
-		index++
-		payload[index] = buf.Bytes()[len(buf.Bytes())-1]
-		index++
+		payload = append(payload, encodePort(port)...)
 	}
 
 	return payload
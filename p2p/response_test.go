@@ -2,8 +2,11 @@ package p2p
 
 import (
 	"encoding/binary"
+	"reflect"
 	"strconv"
 	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/storage"
 )
 
 //Test serialization of request/responses
@@ -37,27 +40,41 @@ func Test_NeighborRes(t *testing.T) {
 	}
 }
 
-func Test_PongRes(t *testing.T) {
+func Test_MempoolReq(t *testing.T) {
 
-	//This corresponds to the IP:Port 8.8.8.8:8000
-	ipport := []byte{
-		31, 64,
+	if filter, ok := _mempoolReq(nil); !ok || filter != (storage.MempoolFilter{}) {
+		t.Error("Empty mempool request should match the whole mempool.")
 	}
 
-	//The IP address from the sender is 9.9.9.9:8000
-	ipportRet := _pongRes(ipport)
+	payload := make([]byte, 64)
+	payload[0], payload[63] = 1, 2
+	filter, ok := _mempoolReq(payload)
+	if !ok || filter.Sender[0] != 1 || filter.Recipient[31] != 2 {
+		t.Errorf("Mempool filter deserialization failed: %v\n", filter)
+	}
 
-	//A remote miner has the opportunity to send an additional IP:Port if he wishes to receive connection on this tuple
-	if ipportRet != "8000" {
-		t.Errorf("Failed to extract IP:Port: (%v) vs. (%v)\n", "8000", ipportRet)
+	if _, ok := _mempoolReq(payload[:40]); ok {
+		t.Error("Malformed mempool request was accepted.")
+	}
+}
+
+func Test_NeighborResV2(t *testing.T) {
+	ipportList := []string{
+		"127.0.0.1:8000",
+		"[2001:db8::1]:8001",
+		"miner.bazo.ch:8002",
+		"[fe80::1%eth0]:8003",
+		"no port",
 	}
 
-	ipport = []byte{
-		31, 64,
+	//Zoned IPv6 addresses and invalid addresses are skipped.
+	parsed, err := _processNeighborResV2(_neighborResV2(ipportList))
+	if err != nil || !reflect.DeepEqual(parsed, ipportList[:3]) {
+		t.Errorf("Neighbor list not correctly encoded: %v (%v)\n", parsed, err)
 	}
 
-	ipportRet = _pongRes(ipport)
-	if ipportRet != "8000" {
-		t.Errorf("Failed to extract IP:Port: (%v) vs. (%v)\n", "8000", ipportRet)
+	//Legacy responses contain IPv4 addresses only.
+	if payload := _neighborRes(ipportList); !reflect.DeepEqual(payload, []byte{127, 0, 0, 1, 31, 64}) {
+		t.Errorf("Legacy neighbor list contains non-IPv4 addresses: %v\n", payload)
 	}
 }
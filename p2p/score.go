@@ -0,0 +1,188 @@
+package p2p
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/logging"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Peers lose score when they misbehave, e.g., send invalid blocks, txs that were not requested or oversized messages.
+//Penalties decay over time, the score halves every SCORE_HALF_LIFE seconds. Peers whose score drops to BAN_SCORE are
+//disconnected and banned for BAN_DURATION seconds. Miners are banned by their node ID, other peers by their IP
+//address. Bans are persisted and loaded in Init. Scores are kept by the same ID, such that reconnecting does not reset
+//them.
+
+var (
+	bannedPeers = make(map[string]int64)
+	peerScores  = make(map[string]peerScore)
+	scoreMutex  = &sync.Mutex{}
+)
+
+//The score at the time of the last penalty.
+type peerScore struct {
+	score float64
+	time  time.Time
+}
+
+func loadBans() {
+	bans := storage.ReadBannedPeers()
+
+	scoreMutex.Lock()
+	defer scoreMutex.Unlock()
+
+	bannedPeers = bans
+}
+
+//Bans a node ID or IP address, the ban does not expire if duration is 0. Connected peers are disconnected.
+func BanPeer(id string, duration time.Duration) error {
+	id, err := normalizePeerID(id)
+	if err != nil {
+		return err
+	}
+
+	var until int64
+	if duration > 0 {
+		until = time.Now().Add(duration).Unix()
+	}
+	if err := storage.WriteBannedPeer(id, until); err != nil {
+		return err
+	}
+
+	scoreMutex.Lock()
+	bannedPeers[id] = until
+	delete(peerScores, id)
+	scoreMutex.Unlock()
+
+	for _, p := range append(peers.getAllPeers(PEERTYPE_MINER), peers.getAllPeers(PEERTYPE_CLIENT)...) {
+		if p.getBanID() == id || p.getHost() == id {
+			p.conn.Close()
+		}
+	}
+
+	return nil
+}
+
+func UnbanPeer(id string) error {
+	id, err := normalizePeerID(id)
+	if err != nil {
+		return err
+	}
+
+	scoreMutex.Lock()
+	delete(bannedPeers, id)
+	scoreMutex.Unlock()
+
+	return storage.DeleteBannedPeer(id)
+}
+
+//Returns the banned node IDs and IP addresses and the end of their bans (0 if the ban does not expire).
+func BannedPeers() map[string]int64 {
+	scoreMutex.Lock()
+	defer scoreMutex.Unlock()
+
+	bans := make(map[string]int64)
+	for id, until := range bannedPeers {
+		bans[id] = until
+	}
+	return bans
+}
+
+//Node IDs are hex encoded public node keys, IP addresses are stored in their canonical form.
+func normalizePeerID(id string) (string, error) {
+	if ip := net.ParseIP(id); ip != nil {
+		return ip.String(), nil
+	}
+
+	if key, err := hex.DecodeString(id); err == nil && len(key) == ed25519.PublicKeySize {
+		return strings.ToLower(id), nil
+	}
+
+	return "", errors.New(fmt.Sprintf("%v is neither an IP address nor a node ID.", id))
+}
+
+//Expired bans are removed, the database is updated outside of the lock.
+func isBanned(ids ...string) (banned bool) {
+	var expired []string
+
+	scoreMutex.Lock()
+	for _, id := range ids {
+		until, ok := bannedPeers[id]
+		if !ok || id == "" {
+			continue
+		}
+		if until == 0 || time.Now().Unix() < until {
+			banned = true
+			break
+		}
+
+		delete(bannedPeers, id)
+		expired = append(expired, id)
+	}
+	scoreMutex.Unlock()
+
+	for _, id := range expired {
+		storage.DeleteBannedPeer(id)
+	}
+
+	return banned
+}
+
+//Refuses connections from banned IP addresses and node IDs.
+func checkBan(conn net.Conn, identity []byte) error {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		host = conn.RemoteAddr().String()
+	}
+
+	if isBanned(host, hex.EncodeToString(identity)) {
+		return errors.New(fmt.Sprintf("Peer %v is banned.", conn.RemoteAddr()))
+	}
+
+	return nil
+}
+
+//Lowers the score of the peer, the peer is disconnected and banned if the score drops to BAN_SCORE.
+func penalize(p *peer, penalty float64, reason string) {
+	id := p.getBanID()
+	now := time.Now()
+
+	scoreMutex.Lock()
+	last := peerScores[id]
+	score := decayScore(last.score, now.Sub(last.time)) - penalty
+	peerScores[id] = peerScore{score, now}
+
+	//Scores that have almost decayed are dropped, such that the map does not grow with every peer ever penalized.
+	for other, s := range peerScores {
+		if decayScore(s.score, now.Sub(s.time)) > -1 {
+			delete(peerScores, other)
+		}
+	}
+	scoreMutex.Unlock()
+
+	peerLogger := logger.WithFields(logging.Fields{logging.PEER: p.getIPPort(), logging.NODE: p.getID(), "score": score})
+	peerLogger.Warnf("Peer misbehaved: %v", reason)
+
+	if score > BAN_SCORE {
+		return
+	}
+
+	if err := BanPeer(p.getBanID(), BAN_DURATION*time.Second); err != nil {
+		peerLogger.Errorf("Could not ban peer: %v", err)
+	} else {
+		peerLogger.Warnf("Peer banned for %v", BAN_DURATION*time.Second)
+	}
+	p.conn.Close()
+}
+
+func decayScore(score float64, elapsed time.Duration) float64 {
+	return score * math.Pow(0.5, elapsed.Seconds()/SCORE_HALF_LIFE)
+}
@@ -0,0 +1,75 @@
+package p2p
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestPeer(ip string) *peer {
+	conn, _ := net.Pipe()
+	return newPeer(remoteAddrConn{conn, &net.TCPAddr{IP: net.ParseIP(ip), Port: 51234}}, "8000", PEERTYPE_MINER)
+}
+
+//The score of the peer at the time of its last penalty.
+func getTestScore(p *peer) float64 {
+	scoreMutex.Lock()
+	defer scoreMutex.Unlock()
+
+	return peerScores[p.getBanID()].score
+}
+
+func TestPenalize(t *testing.T) {
+	p := newTestPeer("10.0.0.1")
+	defer UnbanPeer("10.0.0.1")
+
+	penalize(p, PENALTY_INVALID_BLOCK, "Test")
+	if getTestScore(p) != -PENALTY_INVALID_BLOCK || isBanned("10.0.0.1") {
+		t.Errorf("Wrong score after penalty: %v\n", getTestScore(p))
+	}
+
+	//Penalties decay over time.
+	scoreMutex.Lock()
+	last := peerScores["10.0.0.1"]
+	peerScores["10.0.0.1"] = peerScore{last.score, last.time.Add(-SCORE_HALF_LIFE * time.Second)}
+	scoreMutex.Unlock()
+	penalize(p, PENALTY_INVALID_BLOCK, "Test")
+	if math.Abs(getTestScore(p)-(-1.5*PENALTY_INVALID_BLOCK)) > 0.1 {
+		t.Errorf("Wrong score after decay: %v\n", getTestScore(p))
+	}
+
+	//Reconnecting does not reset the score.
+	p = newTestPeer("10.0.0.1")
+	if math.Abs(getTestScore(p)-(-1.5*PENALTY_INVALID_BLOCK)) > 0.1 {
+		t.Errorf("Score reset after reconnecting: %v\n", getTestScore(p))
+	}
+
+	penalize(p, PENALTY_OVERSIZED, "Test")
+	if !isBanned("10.0.0.1") {
+		t.Error("Peer not banned.")
+	}
+}
+
+func TestBanPeer(t *testing.T) {
+	if err := BanPeer("2001:db8:0::1", 0); err != nil {
+		t.Fatalf("Could not ban peer: %v\n", err)
+	}
+	if !isBanned("2001:db8::1") {
+		t.Error("Ban by IPv6 address not found.")
+	}
+	UnbanPeer("2001:db8::1")
+	if isBanned("2001:db8::1") {
+		t.Error("Peer still banned.")
+	}
+
+	//Expired bans are removed.
+	bannedPeers["10.0.0.2"] = time.Now().Unix() - 1
+	if isBanned("10.0.0.2") {
+		t.Error("Expired ban still active.")
+	}
+
+	if err := BanPeer("no peer", 0); err == nil {
+		t.Error("Invalid peer ID accepted.")
+	}
+}
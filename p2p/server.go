@@ -1,13 +1,12 @@
 package p2p
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/logging"
 	"github.com/bazo-blockchain/bazo-miner/storage"
 	"net"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -28,6 +27,8 @@ var (
 func Init(ipport string) {
 	Ipport = ipport
 	InitLogging()
+	logger.WithFields(logging.Fields{logging.NODE: NodeID()}).Infof("Node identity loaded")
+	loadBans()
 
 	//Initialize peer map
 	peers.minerConns = make(map[*peer]bool)
@@ -41,6 +42,7 @@ func Init(ipport string) {
 	go forwardBlockHeaderBrdcstToMiner()
 	go forwardVerifiedTxsToMiner()
 	go peerService()
+	go invRequestService()
 
 	if !IsBootstrap() {
 		bootstrap()
@@ -55,7 +57,7 @@ func bootstrap() {
 	//the future. initiateNewMinerConn(...) starts with MINER_PING to perform the initial handshake message
 	p, err := initiateNewMinerConnection(storage.Bootstrap_Server)
 	if err != nil {
-		logger.Printf("Initiating new miner connection failed: %v", err)
+		logger.Errorf("Initiating new miner connection failed: %v", err)
 	}
 
 	go peerConn(p)
@@ -73,16 +75,42 @@ func initiateNewMinerConnection(dial string) (*peer, error) {
 		return nil, errors.New(fmt.Sprintf("Cannot self-connect %v.", dial))
 	}
 
+	listenerPort := getPort(dial)
+	if listenerPort == "" {
+		return nil, errors.New(fmt.Sprintf("Invalid address %v.", dial))
+	}
+
+	if host, _, _ := net.SplitHostPort(dial); isBanned(host) {
+		return nil, errors.New(fmt.Sprintf("Peer %v is banned.", dial))
+	}
+
 	//Open up a tcp dial and instantiate a peer struct, wait for adding it to the peerStruct before we finalize
 	//the handshake
 	conn, err := net.Dial("tcp", dial)
-	p := newPeer(conn, strings.Split(dial, ":")[1], PEERTYPE_MINER)
 	if err != nil {
 		return nil, err
 	}
 
+	//The TLS handshake authenticates the peer before the miner handshake.
+	secured, identity, err := secureConn(conn, getTLSConfig())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := checkBan(secured, identity); err != nil {
+		secured.Close()
+		return nil, err
+	}
+
+	p := newPeer(secured, listenerPort, PEERTYPE_MINER)
+	p.identity = identity
+	if err := checkMinerIdentity(p); err != nil {
+		secured.Close()
+		return nil, err
+	}
+
 	//Extracts the port from our localConn variable (which is in the form IP:Port)
-	localPort, err := strconv.Atoi(strings.Split(Ipport, ":")[1])
+	localPort, err := strconv.Atoi(getPort(Ipport))
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("Parsing port failed: %v\n", err))
 	}
@@ -92,32 +120,48 @@ func initiateNewMinerConnection(dial string) (*peer, error) {
 		return nil, err
 	}
 
-	conn.Write(packet)
+	secured.Write(packet)
+	countBytesOut(packet)
 
 	//Wait for the other party to finish the handshake with the corresponding message
-	header, _, err := RcvData(p)
-	if err != nil || header.TypeID != MINER_PONG {
+	header, payload, err := RcvData(p)
+	if err == nil && header.TypeID == HANDSHAKE_ERR {
+		err = errors.New(string(payload))
+	} else if err == nil && header.TypeID != MINER_PONG {
+		err = errors.New(fmt.Sprintf("Unexpected message %v.", LogMapping[header.TypeID]))
+	}
+	if err != nil {
+		secured.Close()
 		return nil, errors.New(fmt.Sprintf("Failed to complete miner handshake: %v", err))
 	}
 
+	info, err := decodeHandshake(payload)
+	if err != nil {
+		secured.Close()
+		return nil, err
+	}
+	if err := info.checkCompatibility(); err != nil {
+		secured.Close()
+		return nil, errors.New(fmt.Sprintf("Incompatible miner %v: %v", dial, err))
+	}
+	p.setHandshake(info)
+
 	return p, nil
 }
 
 func PrepareHandshake(pingType uint8, localPort int) ([]byte, error) {
 	//We need to additionally send our local listening port in order to construct a valid first message
 	//This will be the only time we need it so we don't save it
-	portBuf := make([]byte, PORT_SIZE)
-	binary.BigEndian.PutUint16(portBuf[:], uint16(localPort))
-	packet := BuildPacket(pingType, portBuf)
+	packet := BuildPacket(pingType, encodeHandshake(localPort))
 
 	return packet, nil
 }
 
 func listener(ipport string) {
 	//Listen on all interfaces, this NAT stuff easier
-	listener, err := net.Listen("tcp", ":"+strings.Split(ipport, ":")[1])
+	listener, err := net.Listen("tcp", net.JoinHostPort("", getPort(ipport)))
 	if err != nil {
-		logger.Printf("%v\n", err)
+		logger.Errorf("Could not listen on %v: %v", ipport, err)
 		return
 	}
 
@@ -128,21 +172,36 @@ func listener(ipport string) {
 		conn.(*net.TCPConn).SetKeepAlivePeriod(1 * time.Minute)
 
 		if err != nil {
-			logger.Printf("%v\n", err)
+			logger.Warnf("Could not accept connection: %v", err)
 			continue
 		}
 
-		p := newPeer(conn, "", 0)
-		go handleNewConn(p)
+		go handleNewConn(conn)
 	}
 }
 
-func handleNewConn(p *peer) {
-	logger.Printf("New incoming connection: %v\n", p.conn.RemoteAddr().String())
+func handleNewConn(conn net.Conn) {
+	connLogger := logger.WithFields(logging.Fields{logging.PEER: conn.RemoteAddr().String()})
+	connLogger.Debugf("New incoming connection")
+
+	secured, identity, err := acceptConn(conn, getTLSConfig())
+	if err != nil {
+		connLogger.Warnf("Failed to handle incoming connection: %v", err)
+		conn.Close()
+		return
+	}
+	if err := checkBan(secured, identity); err != nil {
+		connLogger.Infof("Rejected connection: %v", err)
+		secured.Close()
+		return
+	}
+
+	p := newPeer(secured, "", 0)
+	p.identity = identity
 
 	header, payload, err := RcvData(p)
 	if err != nil {
-		logger.Printf("Failed to handle incoming connection: %v\n", err)
+		logger.Warnf("Failed to handle incoming connection: %v", err)
 		return
 	}
 
@@ -150,14 +209,15 @@ func handleNewConn(p *peer) {
 }
 
 func peerConn(p *peer) {
+	peerLogger := logger.WithFields(logging.Fields{logging.PEER: p.getIPPort(), logging.NODE: p.getID()})
 	if p.peerType == PEERTYPE_MINER {
-		logger.Printf("Adding a new miner: %v\n", p.getIPPort())
+		peerLogger.Infof("Adding a new miner")
 	} else if p.peerType == PEERTYPE_CLIENT {
-		logger.Printf("Adding a new client: %v\n", p.getIPPort())
+		peerLogger.Infof("Adding a new client")
 	}
 
-	//Give the peer a channel
-	p.ch = make(chan []byte)
+	//Give the peer a bounded outbound queue
+	p.ch = make(chan []byte, OUTBOUND_QUEUE_SIZE)
 
 	//Register withe the broadcast service and start the additional writer
 	register <- p
@@ -167,9 +227,9 @@ func peerConn(p *peer) {
 		header, payload, err := RcvData(p)
 		if err != nil {
 			if p.peerType == PEERTYPE_MINER {
-				logger.Printf("Miner disconnected: %v\n", err)
+				peerLogger.Infof("Miner disconnected: %v", err)
 			} else if p.peerType == PEERTYPE_CLIENT {
-				logger.Printf("Client disconnected: %v\n", err)
+				peerLogger.Infof("Client disconnected: %v", err)
 			}
 
 			//In case of a comm fail, disconnect cleanly from the broadcast service
@@ -40,7 +40,7 @@ func TestPrepareHandshake(t *testing.T) {
 		packet[0] != 0x00 ||
 		packet[1] != 0x00 ||
 		packet[2] != 0x00 ||
-		packet[3] != 0x02 || //payload size is 2 bytes, listener port
+		packet[3] != HANDSHAKE_SIZE || //listener port followed by version, features, chain ID and height
 		packet[4] != 0x64 || //dec(0x64) == 100, MINER_PING
 		packet[5] != 0x23 ||
 		packet[6] != 0x28 {
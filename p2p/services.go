@@ -14,7 +14,7 @@ func peerService() {
 			peers.add(p)
 		case p := <-disconnect:
 			peers.delete(p)
-			close(p.ch)
+			p.closeQueue()
 		}
 	}
 }
@@ -24,13 +24,21 @@ func broadcastService() {
 		select {
 		//Broadcasting all messages.
 		case msg := <-minerBrdcstMsg:
-			for p := range peers.minerConns {
-				//Write to the channel, which the peerBroadcast(*peer) running in a seperate goroutine consumes right away.
-				p.ch <- msg
+			for _, p := range peers.getAllPeers(PEERTYPE_MINER) {
+				//Write to the queue, which the peerBroadcast(*peer) running in a seperate goroutine consumes. Slow peers
+				//do not block the broadcast to other peers.
+				p.enqueue(msg)
+			}
+		//Txs and blocks are only announced to miners that do not know them yet.
+		case msg := <-minerInvBrdcst:
+			for _, p := range peers.getAllPeers(PEERTYPE_MINER) {
+				if packet := invPacket(p, msg); packet != nil {
+					p.enqueue(packet)
+				}
 			}
 		case msg := <-clientBrdcstMsg:
-			for p := range peers.clientConns {
-				p.ch <- msg
+			for _, p := range peers.getAllPeers(PEERTYPE_CLIENT) {
+				p.enqueue(msg)
 			}
 		}
 	}
@@ -51,14 +59,14 @@ func checkHealthService() {
 		if Ipport != storage.Bootstrap_Server && !peers.contains(storage.Bootstrap_Server, PEERTYPE_MINER) {
 			p, err := initiateNewMinerConnection(storage.Bootstrap_Server)
 			if p == nil || err != nil {
-				logger.Printf("%v\n", err)
+				logger.Warnf("Could not connect to the bootstrap server: %v", err)
 			} else {
 				go peerConn(p)
 			}
 		}
 
 		//Periodically check if we are well-connected
-		if len(peers.minerConns) >= MIN_MINERS {
+		if peers.len(PEERTYPE_MINER) >= MIN_MINERS {
 			continue
 		}
 
@@ -69,7 +77,7 @@ func checkHealthService() {
 		case ipaddr := <-iplistChan:
 			p, err := initiateNewMinerConnection(ipaddr)
 			if err != nil {
-				logger.Printf("%v\n", err)
+				logger.Debugf("Could not connect to neighbor: %v", err)
 			}
 			if p == nil || err != nil {
 				goto RETRY
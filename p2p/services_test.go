@@ -0,0 +1,89 @@
+package p2p
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+//Registers a miner with the broadcast service. The remote end of the connection is returned, it is up to the test
+//to read from it.
+func connectBrdcstMiner() (p *peer, remote net.Conn) {
+	local, remote := net.Pipe()
+	p = newPeer(local, "8000", PEERTYPE_MINER)
+	p.ch = make(chan []byte, OUTBOUND_QUEUE_SIZE)
+	peers.add(p)
+	go peerBroadcast(p)
+
+	return p, remote
+}
+
+func TestEnqueue(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+	p := newPeer(local, "8000", PEERTYPE_MINER)
+	p.ch = make(chan []byte, 1)
+
+	if !p.enqueue([]byte{1}) || p.enqueue([]byte{2}) {
+		t.Error("Message not dropped when the queue is full.")
+	}
+
+	//Peers that do not consume their queue are disconnected.
+	for i := 1; i < MAX_DROPPED_MSGS; i++ {
+		p.enqueue([]byte{2})
+	}
+	if _, err := local.Write([]byte{0}); err == nil {
+		t.Error("Stuck peer was not disconnected.")
+	}
+
+	p.closeQueue()
+	if p.enqueue([]byte{3}) {
+		t.Error("Message queued after the queue was closed.")
+	}
+}
+
+func TestBroadcastSlowPeer(t *testing.T) {
+	fast, fastRemote := connectBrdcstMiner()
+	defer disconnectTestMiner(fast)
+	//The slow miner never reads.
+	slow, slowRemote := connectBrdcstMiner()
+	defer disconnectTestMiner(slow)
+	defer slowRemote.Close()
+
+	var received int32
+	go func() {
+		for {
+			if _, _, err := RcvData(&peer{conn: fastRemote}); err != nil {
+				return
+			}
+			atomic.AddInt32(&received, 1)
+		}
+	}()
+
+	//Messages are sent in rounds that the fast miner can keep up with, while the queue of the slow miner fills up.
+	count := OUTBOUND_QUEUE_SIZE + MAX_DROPPED_MSGS + 10
+	for sent := 0; sent < count; {
+		for i := 0; i < 100 && sent < count; i++ {
+			select {
+			case minerBrdcstMsg <- BuildPacket(TIME_BRDCST, getTime()):
+				sent++
+			case <-time.After(2 * time.Second):
+				t.Fatal("Broadcast blocked by the slow miner.")
+			}
+		}
+
+		deadline := time.Now().Add(2 * time.Second)
+		for atomic.LoadInt32(&received) < int32(sent) && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if n := atomic.LoadInt32(&received); n != int32(sent) {
+			t.Fatalf("Fast miner received %v of %v messages.\n", n, sent)
+		}
+	}
+
+	//The slow miner dropped messages and was disconnected.
+	if _, err := slow.conn.Write([]byte{0}); err == nil {
+		t.Error("Slow miner was not disconnected.")
+	}
+}
@@ -5,19 +5,22 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"github.com/bazo-blockchain/bazo-miner/logging"
 	"github.com/bazo-blockchain/bazo-miner/storage"
 	"net"
-	"strings"
+	"strconv"
 	"time"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 )
 
+var errPayloadTooLarge = errors.New("Header: Payload exceeds MAX_BLOCK_SIZE")
+
 func Connect(connectionString string) *net.TCPConn {
 	tcpAddr, err := net.ResolveTCPAddr("tcp", connectionString)
 	conn, err := net.DialTCP("tcp", nil, tcpAddr)
 
 	if err != nil {
-		logger.Printf("Connection to %v failed.\n", connectionString)
+		logger.WithFields(logging.Fields{logging.PEER: connectionString}).Warnf("Connection failed: %v", err)
 		return nil
 	}
 
@@ -30,6 +33,9 @@ func Connect(connectionString string) *net.TCPConn {
 func RcvData(p *peer) (header *Header, payload []byte, err error) {
 	reader := bufio.NewReader(p.conn)
 	header, err = ReadHeader(reader)
+	if err == errPayloadTooLarge {
+		penalize(p, PENALTY_OVERSIZED, err.Error())
+	}
 	if err != nil {
 		p.conn.Close()
 		return nil, nil, errors.New(fmt.Sprintf("Connection to %v aborted: %v", p.getIPPort(), err))
@@ -45,7 +51,8 @@ func RcvData(p *peer) (header *Header, payload []byte, err error) {
 		}
 	}
 
-	logger.Printf("Receive message:\nSender: %v\nType: %v\nPayload length: %v\n", p.getIPPort(), LogMapping[header.TypeID], len(payload))
+	logger.WithFields(logging.Fields{logging.PEER: p.getIPPort(), "type": LogMapping[header.TypeID], "length": len(payload)}).Debugf("Receive message")
+	countBytesIn(header.TypeID, HEADER_LEN+len(payload))
 
 	return header, payload, nil
 }
@@ -66,16 +73,25 @@ func RcvData_(c net.Conn) (header *Header, payload []byte, err error) {
 			return nil, nil, errors.New(fmt.Sprintf("Connection to aborted: %v\n", err))
 		}
 	}
+	countBytesIn(header.TypeID, HEADER_LEN+len(payload))
 
 	return header, payload, nil
 }
 
 func sendData(p *peer, payload []byte) {
-	logger.Printf("Send message:\nReceiver: %v\nType: %v\nPayload length: %v\n", p.getIPPort(), LogMapping[payload[4]], len(payload)-HEADER_LEN)
+	logger.WithFields(logging.Fields{logging.PEER: p.getIPPort(), "type": LogMapping[payload[4]], "length": len(payload) - HEADER_LEN}).Debugf("Send message")
 
+	//A peer that does not read is disconnected, a partially written message cannot be recovered.
 	p.l.Lock()
-	p.conn.Write(payload)
+	p.conn.SetWriteDeadline(time.Now().Add(WRITE_TIMEOUT * time.Second))
+	_, err := p.conn.Write(payload)
 	p.l.Unlock()
+	if err != nil {
+		logger.WithFields(logging.Fields{logging.PEER: p.getIPPort()}).Debugf("Could not send message: %v", err)
+		p.conn.Close()
+		return
+	}
+	countBytesOut(payload)
 }
 
 //Tested in server_test.go
@@ -133,7 +149,7 @@ func ReadHeader(reader *bufio.Reader) (*Header, error) {
 
 	//Check if the payload length does not exceed the MAX_BLOCK_SIZE defined in configtx.go
 	if header.Len > protocol.MAX_BLOCK_SIZE {
-		return nil, errors.New("Header: Payload exceeds MAX_BLOCK_SIZE")
+		return nil, errPayloadTooLarge
 	}
 
 	return header, nil
@@ -154,10 +170,54 @@ func extractHeader(headerData []byte) *Header {
 
 func IsBootstrap() bool {
 	//Set thisPort global, this will be the listening port for incoming connection
-	bootstrapPort := strings.Split(storage.Bootstrap_Server, ":")[1]
-	thisPort := strings.Split(Ipport, ":")[1]
+	bootstrapPort := getPort(storage.Bootstrap_Server)
+	thisPort := getPort(Ipport)
 	if thisPort == bootstrapPort {
 		return true
 	}
 	return false
+}
+
+//Addresses are IPv4:Port, [IPv6]:Port or Hostname:Port.
+func splitIPPort(ipport string) (host string, port uint16, err error) {
+	host, portString, err := net.SplitHostPort(ipport)
+	if err != nil {
+		return "", 0, err
+	}
+
+	parsedPort, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return "", 0, errors.New(fmt.Sprintf("Invalid port in %v.", ipport))
+	}
+
+	return host, uint16(parsedPort), nil
+}
+
+//Returns an empty string if the address has no port.
+func getPort(ipport string) string {
+	_, port, err := net.SplitHostPort(ipport)
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
+func encodePort(port uint16) []byte {
+	encoded := make([]byte, PORT_SIZE)
+	binary.BigEndian.PutUint16(encoded, port)
+	return encoded
+}
+
+//Hostnames received from other peers are dialed, only letters, digits, hyphens and dots are allowed.
+func isHostname(host string) bool {
+	if len(host) == 0 || len(host) > MAX_HOSTNAME_LEN {
+		return false
+	}
+
+	for _, c := range host {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
 }
\ No newline at end of file
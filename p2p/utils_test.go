@@ -56,3 +56,31 @@ func TestRcvData(t *testing.T) {
 		t.Error("Receiving data routine failed\n")
 	}
 }
+
+type remoteAddrConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+func (c remoteAddrConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func TestGetIPPort(t *testing.T) {
+	for remoteAddr, expected := range map[string]string{
+		"127.0.0.1":   "127.0.0.1:8000",
+		"2001:db8::1": "[2001:db8::1]:8000",
+	} {
+		p := newPeer(remoteAddrConn{remoteAddr: &net.TCPAddr{IP: net.ParseIP(remoteAddr), Port: 51234}}, "8000", PEERTYPE_MINER)
+		if ipport := p.getIPPort(); ipport != expected {
+			t.Errorf("Wrong address of peer %v: %v, expected %v\n", remoteAddr, ipport, expected)
+		}
+	}
+
+	if _, _, err := splitIPPort("[2001:db8::1]:70000"); err == nil {
+		t.Error("Invalid port accepted.")
+	}
+	if port := getPort("[2001:db8::1]:8000"); port != "8000" {
+		t.Errorf("Wrong port: %v\n", port)
+	}
+}
@@ -0,0 +1,287 @@
+package rpc
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/logging"
+	"github.com/bazo-blockchain/bazo-miner/miner"
+	"github.com/bazo-blockchain/bazo-miner/p2p"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//The RPC server is a local HTTP interface that serves JSON. It is meant for scripts and services running next to the
+//miner and should not be exposed publicly.
+//
+//	GET  /account/<hash or address>
+//	GET  /block/latest
+//	GET  /block/<hash>
+//	GET  /block/height/<height>
+//	GET  /tx/<hash>
+//	GET  /mempool?sender=<hash>&recipient=<hash>
+//	POST /tx                    {"type": "funds|acc|config|stake", "data": "<hex encoded tx>"}
+//	GET  /subscribe             (see subscribe.go)
+//	GET  /bans
+//	POST /bans                  {"peer": "<node ID or IP address>", "duration": "24h"}
+//	DELETE /bans/<node ID or IP address>
+
+const (
+	MAX_REQUEST_SIZE = 1000000 //Byte
+)
+
+var logger = logging.New(logging.RPC)
+
+//Starts the RPC server at address in the background.
+func Init(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := http.Serve(listener, NewHandler()); err != nil {
+			logger.Errorf("RPC server stopped: %v", err)
+		}
+	}()
+
+	logger.Infof("RPC server listening on %v", listener.Addr())
+	return nil
+}
+
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/account/", getAccount)
+	mux.HandleFunc("/block/", getBlock)
+	mux.HandleFunc("/tx/", getTx)
+	mux.HandleFunc("/tx", submitTx)
+	mux.HandleFunc("/mempool", getMempool)
+	mux.HandleFunc("/subscribe", subscribe)
+	mux.HandleFunc("/bans", bans)
+	mux.HandleFunc("/bans/", unbanPeer)
+	return mux
+}
+
+func getAccount(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	hash, err := decodeAccountHash(strings.TrimPrefix(r.URL.Path, "/account/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	acc, ok := miner.ReadAccount(hash)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "Account not found."})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAccount(acc))
+}
+
+func getBlock(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/block/")
+	switch {
+	case path == "latest":
+		writeBlock(w, storage.ReadLastClosedBlock())
+	case strings.HasPrefix(path, "height/"):
+		height, err := strconv.ParseUint(strings.TrimPrefix(path, "height/"), 10, 32)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeBlock(w, storage.ReadClosedBlockByHeight(uint32(height)))
+	default:
+		hash, err := decodeHash(path)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeBlock(w, storage.ReadClosedBlock(hash))
+	}
+}
+
+func writeBlock(w http.ResponseWriter, b *protocol.Block) {
+	if b != nil {
+		writeJSON(w, http.StatusOK, newBlock(b))
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, errorResponse{Error: "Block not found."})
+}
+
+func getTx(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	hash, err := decodeHash(strings.TrimPrefix(r.URL.Path, "/tx/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if transaction := storage.ReadOpenTx(hash); transaction != nil {
+		writeJSON(w, http.StatusOK, newTx(transaction, "pending"))
+		return
+	}
+
+	if transaction := storage.ReadClosedTx(hash); transaction != nil {
+		result := newTx(transaction, "confirmed")
+		if location := storage.ReadTxLocation(hash); location != nil {
+			result.BlockHash = encodeHashes([][32]byte{location.BlockHash})[0]
+		}
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	writeJSON(w, http.StatusNotFound, errorResponse{Error: "Tx not found."})
+}
+
+func submitTx(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var request submitRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_REQUEST_SIZE)).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	transaction, err := request.decode()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := miner.SubmitTx(transaction); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	hash := transaction.Hash()
+	writeJSON(w, http.StatusOK, submitResponse{encodeHashes([][32]byte{hash})[0]})
+}
+
+func getMempool(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	var filter storage.MempoolFilter
+	var err error
+	if sender := r.URL.Query().Get("sender"); sender != "" {
+		if filter.Sender, err = decodeAccountHash(sender); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if recipient := r.URL.Query().Get("recipient"); recipient != "" {
+		if filter.Recipient, err = decodeAccountHash(recipient); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, newMempool(storage.QueryMempool(filter)))
+}
+
+func bans(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		banPeer(w, r)
+		return
+	}
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	var list []ban
+	for id, until := range p2p.BannedPeers() {
+		list = append(list, ban{id, until})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Peer < list[j].Peer
+	})
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+//Bans take effect immediately, connected peers are disconnected.
+func banPeer(w http.ResponseWriter, r *http.Request) {
+	var request banRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_REQUEST_SIZE)).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var duration time.Duration
+	if request.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(request.Duration); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	if err := p2p.BanPeer(request.Peer, duration); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ban{Peer: request.Peer})
+}
+
+func unbanPeer(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodDelete) {
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/bans/")
+	if err := p2p.UnbanPeer(id); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ban{Peer: id})
+}
+
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+
+	w.Header().Set("Allow", method)
+	writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "Method not allowed."})
+	return false
+}
+
+//Txs rejected by the miner carry the same error code as TX_BRDCST_ERR messages.
+func writeError(w http.ResponseWriter, status int, err error) {
+	response := errorResponse{Error: err.Error()}
+	if txErr, ok := err.(*p2p.TxError); ok {
+		response.Code = txErr.Code
+	}
+
+	writeJSON(w, status, response)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logger.Warnf("Could not write RPC response: %v", err)
+	}
+}
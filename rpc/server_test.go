@@ -0,0 +1,216 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+func request(t *testing.T, method, path string, body []byte, response interface{}) int {
+	recorder := httptest.NewRecorder()
+	NewHandler().ServeHTTP(recorder, httptest.NewRequest(method, path, bytes.NewReader(body)))
+
+	if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+		t.Fatalf("Invalid JSON response for %v: %v\n", path, err)
+	}
+
+	return recorder.Code
+}
+
+func TestGetAccount(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	acc := protocol.NewAccount(crypto.GetAddressFromPubKey(&key.PublicKey), [32]byte{}, 1000, false, [crypto.COMM_KEY_LENGTH]byte{}, nil, nil)
+	hash := protocol.SerializeHashContent(acc.Address)
+	storage.State[hash] = &acc
+	defer delete(storage.State, hash)
+
+	//By hash and by address
+	for _, id := range []string{hex.EncodeToString(hash[:]), hex.EncodeToString(acc.Address[:])} {
+		var result account
+		if code := request(t, http.MethodGet, "/account/"+id, nil, &result); code != http.StatusOK || result.Balance != 1000 {
+			t.Errorf("Account lookup failed: %v %+v\n", code, result)
+		}
+	}
+
+	var result errorResponse
+	if code := request(t, http.MethodGet, "/account/"+hex.EncodeToString(make([]byte, 32)), nil, &result); code != http.StatusNotFound {
+		t.Errorf("Unknown account: %v %+v\n", code, result)
+	}
+	if code := request(t, http.MethodGet, "/account/xyz", nil, &result); code != http.StatusBadRequest {
+		t.Errorf("Invalid account: %v %+v\n", code, result)
+	}
+}
+
+func TestGetBlockAndTx(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	from, to := protocol.SerializeHashContent([64]byte{1}), protocol.SerializeHashContent([64]byte{2})
+	confirmed, _ := protocol.ConstrFundsTx(0x01, 10, 1, 0, from, to, key, nil, nil)
+	pending, _ := protocol.ConstrFundsTx(0x01, 10, 1, 1, from, to, key, nil, nil)
+
+	b := new(protocol.Block)
+	b.Hash = [32]byte{'r', 'p', 'c'}
+	b.Height = 1
+	b.FundsTxData = [][32]byte{confirmed.Hash()}
+	storage.WriteClosedTx(confirmed)
+	storage.WriteClosedBlock(b)
+	storage.WriteLastClosedBlock(b)
+	storage.WriteOpenTx(pending)
+	defer storage.DeleteOpenTx(pending)
+
+	for _, path := range []string{"/block/latest", "/block/height/1", "/block/" + hex.EncodeToString(b.Hash[:])} {
+		var result block
+		if code := request(t, http.MethodGet, path, nil, &result); code != http.StatusOK || result.Height != 1 || len(result.FundsTxs) != 1 {
+			t.Errorf("Block lookup %v failed: %v %+v\n", path, code, result)
+		}
+	}
+
+	var notFound errorResponse
+	if code := request(t, http.MethodGet, "/block/height/2", nil, &notFound); code != http.StatusNotFound {
+		t.Errorf("Unknown block: %v %+v\n", code, notFound)
+	}
+
+	var result tx
+	confirmedHash, pendingHash := confirmed.Hash(), pending.Hash()
+	if code := request(t, http.MethodGet, "/tx/"+hex.EncodeToString(confirmedHash[:]), nil, &result); code != http.StatusOK ||
+		result.Status != "confirmed" || result.BlockHash != hex.EncodeToString(b.Hash[:]) || result.Amount != 10 {
+		t.Errorf("Confirmed tx lookup failed: %v %+v\n", code, result)
+	}
+	if code := request(t, http.MethodGet, "/tx/"+hex.EncodeToString(pendingHash[:]), nil, &result); code != http.StatusOK ||
+		result.Status != "pending" || result.TxCnt != 1 {
+		t.Errorf("Pending tx lookup failed: %v %+v\n", code, result)
+	}
+
+	var pool mempool
+	path := fmt.Sprintf("/mempool?sender=%x&recipient=%x", from, to)
+	if code := request(t, http.MethodGet, path, nil, &pool); code != http.StatusOK || pool.Count != 1 || len(pool.Txs) != 1 || pool.Txs[0].Hash != hex.EncodeToString(pendingHash[:]) {
+		t.Errorf("Mempool query failed: %v %+v\n", code, pool)
+	}
+	if code := request(t, http.MethodGet, fmt.Sprintf("/mempool?sender=%x", to), nil, &pool); code != http.StatusOK || pool.Count != 0 {
+		t.Errorf("Mempool filter failed: %v %+v\n", code, pool)
+	}
+}
+
+func TestSubmitTx(t *testing.T) {
+	var result errorResponse
+
+	if code := request(t, http.MethodGet, "/tx", nil, &result); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET on submit endpoint: %v %+v\n", code, result)
+	}
+
+	for _, invalid := range []submitRequest{{"funds", "zz"}, {"unknown", "00ff"}} {
+		body, _ := json.Marshal(invalid)
+		if code := request(t, http.MethodPost, "/tx", body, &result); code != http.StatusBadRequest {
+			t.Errorf("Invalid submit request was accepted: %v %+v\n", code, result)
+		}
+	}
+
+	//The miner is not running in this test
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	fundsTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, 0, [32]byte{1}, [32]byte{2}, key, nil, nil)
+	body, _ := json.Marshal(submitRequest{"funds", hex.EncodeToString(fundsTx.Encode())})
+	if code := request(t, http.MethodPost, "/tx", body, &result); code != http.StatusUnprocessableEntity || result.Code == 0 {
+		t.Errorf("Tx was accepted without a running miner: %v %+v\n", code, result)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	server := httptest.NewServer(NewHandler())
+	defer server.Close()
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	account, other := protocol.SerializeHashContent([64]byte{3}), protocol.SerializeHashContent([64]byte{4})
+	touching, _ := protocol.ConstrFundsTx(0x01, 10, 1, 0, other, account, key, nil, nil)
+	notTouching, _ := protocol.ConstrFundsTx(0x01, 10, 1, 1, other, other, key, nil, nil)
+
+	response, err := http.Get(fmt.Sprintf("%v/subscribe?events=block,tx&account=%x", server.URL, account))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	storage.WriteOpenTx(notTouching)
+	storage.WriteOpenTx(touching)
+	defer storage.DeleteOpenTx(notTouching)
+	defer storage.DeleteOpenTx(touching)
+
+	b := new(protocol.Block)
+	b.Hash = [32]byte{'s', 's', 'e'}
+	b.Height = 5
+	storage.Publish(storage.Event{Type: storage.EVENT_REORG, RolledBack: [][32]byte{{1}}})
+	storage.Publish(storage.Event{Type: storage.EVENT_BLOCK, Block: b, Txs: []protocol.Transaction{touching, notTouching}})
+
+	//The reorg is not subscribed, txs not touching the account are filtered
+	touchingHash := touching.Hash()
+	expected := []struct {
+		name   string
+		status string
+	}{{"tx", "pending"}, {"block", ""}, {"tx", "confirmed"}}
+
+	reader := bufio.NewReader(response.Body)
+	for _, e := range expected {
+		name, _ := reader.ReadString('\n')
+		data, _ := reader.ReadString('\n')
+		reader.ReadString('\n')
+
+		if name != "event: "+e.name+"\n" {
+			t.Fatalf("Expected %v event, got %q\n", e.name, name)
+		}
+
+		if e.name == "tx" {
+			var result tx
+			json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &result)
+			if result.Hash != hex.EncodeToString(touchingHash[:]) || result.Status != e.status {
+				t.Errorf("Wrong tx event: %+v\n", result)
+			}
+		} else {
+			var result block
+			json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &result)
+			if result.Height != 5 {
+				t.Errorf("Wrong block event: %+v\n", result)
+			}
+		}
+	}
+}
+
+func TestBans(t *testing.T) {
+	var result errorResponse
+	body, _ := json.Marshal(banRequest{"10.0.0.1", "1h"})
+	if code := request(t, http.MethodPost, "/bans", body, &result); code != http.StatusOK {
+		t.Fatalf("Could not ban peer: %v %+v\n", code, result)
+	}
+
+	for _, invalid := range []banRequest{{"peer", ""}, {"10.0.0.2", "forever"}} {
+		body, _ := json.Marshal(invalid)
+		if code := request(t, http.MethodPost, "/bans", body, &result); code != http.StatusBadRequest {
+			t.Errorf("Invalid ban request was accepted: %v %+v\n", code, result)
+		}
+	}
+
+	var list []ban
+	if code := request(t, http.MethodGet, "/bans", nil, &list); code != http.StatusOK || len(list) != 1 || list[0].Peer != "10.0.0.1" || list[0].Until == 0 {
+		t.Errorf("Wrong ban list: %v %+v\n", code, list)
+	}
+	if len(storage.ReadBannedPeers()) != 1 {
+		t.Error("Ban was not persisted.")
+	}
+
+	if code := request(t, http.MethodDelete, "/bans/10.0.0.1", nil, &result); code != http.StatusOK {
+		t.Errorf("Could not unban peer: %v %+v\n", code, result)
+	}
+	if code := request(t, http.MethodGet, "/bans", nil, &list); code != http.StatusOK || len(list) != 0 {
+		t.Errorf("Ban was not lifted: %v %+v\n", code, list)
+	}
+}
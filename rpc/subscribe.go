@@ -0,0 +1,157 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//Subscriptions are served as server-sent events (text/event-stream):
+//
+//	GET /subscribe?events=block,reorg,tx&account=<hash or address>
+//
+//All event types are sent if events is not set. Tx events are sent for txs added to the mempool (pending) and for
+//txs of validated blocks (confirmed). If account is set, only txs touching the account are sent.
+
+const (
+	SSE_KEEPALIVE_INTERVAL = 30 //Seconds
+)
+
+type reorg struct {
+	RolledBack []string `json:"rolledBack"`
+}
+
+type subscriptionFilter struct {
+	types   map[string]bool
+	account *[32]byte
+}
+
+type sseEvent struct {
+	name string
+	data interface{}
+}
+
+func newSubscriptionFilter(r *http.Request) (filter subscriptionFilter, err error) {
+	filter.types = make(map[string]bool)
+	if events := r.URL.Query().Get("events"); events != "" {
+		for _, eventType := range strings.Split(events, ",") {
+			if eventType != storage.EVENT_BLOCK && eventType != storage.EVENT_REORG && eventType != storage.EVENT_TX {
+				return filter, errors.New(fmt.Sprintf("Unknown event type %v.", eventType))
+			}
+			filter.types[eventType] = true
+		}
+	} else {
+		filter.types[storage.EVENT_BLOCK] = true
+		filter.types[storage.EVENT_REORG] = true
+		filter.types[storage.EVENT_TX] = true
+	}
+
+	if account := r.URL.Query().Get("account"); account != "" {
+		hash, err := decodeAccountHash(account)
+		if err != nil {
+			return filter, err
+		}
+		filter.account = &hash
+	}
+
+	return filter, nil
+}
+
+func (filter subscriptionFilter) apply(event storage.Event) (events []sseEvent) {
+	switch event.Type {
+	case storage.EVENT_BLOCK:
+		if filter.types[storage.EVENT_BLOCK] {
+			events = append(events, sseEvent{storage.EVENT_BLOCK, newBlock(event.Block)})
+		}
+	case storage.EVENT_REORG:
+		if filter.types[storage.EVENT_REORG] {
+			events = append(events, sseEvent{storage.EVENT_REORG, reorg{encodeHashes(event.RolledBack)}})
+		}
+	}
+
+	if !filter.types[storage.EVENT_TX] {
+		return events
+	}
+
+	for _, transaction := range event.Txs {
+		if filter.account != nil && !storage.TxTouchesAccount(transaction, *filter.account) {
+			continue
+		}
+
+		if event.Type == storage.EVENT_BLOCK {
+			confirmed := newTx(transaction, "confirmed")
+			confirmed.BlockHash = encodeHashes([][32]byte{event.Block.Hash})[0]
+			events = append(events, sseEvent{storage.EVENT_TX, confirmed})
+		} else {
+			events = append(events, sseEvent{storage.EVENT_TX, newTx(transaction, "pending")})
+		}
+	}
+
+	return events
+}
+
+func subscribe(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "Streaming not supported."})
+		return
+	}
+
+	filter, err := newSubscriptionFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	subscription := storage.Subscribe()
+	defer subscription.Unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(SSE_KEEPALIVE_INTERVAL * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event, ok := <-subscription.Events:
+			if !ok {
+				writeEvent(w, sseEvent{"error", errorResponse{Error: "Subscriber too slow, events were dropped."}})
+				flusher.Flush()
+				return
+			}
+			for _, e := range filter.apply(event) {
+				if err := writeEvent(w, e); err != nil {
+					return
+				}
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event sseEvent) error {
+	data, err := json.Marshal(event.data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %v\ndata: %s\n\n", event.name, data)
+	return err
+}
@@ -0,0 +1,252 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/bazo-blockchain/bazo-miner/storage"
+)
+
+//JSON representations of accounts, blocks and txs. Hashes, addresses and keys are hex encoded.
+
+type account struct {
+	Address   string `json:"address"`
+	Hash      string `json:"hash"`
+	Issuer    string `json:"issuer"`
+	Balance   uint64 `json:"balance"`
+	TxCnt     uint32 `json:"txCnt"`
+	IsStaking bool   `json:"isStaking"`
+	IsRoot    bool   `json:"isRoot"`
+}
+
+type block struct {
+	Hash        string   `json:"hash"`
+	PrevHash    string   `json:"prevHash"`
+	Height      uint32   `json:"height"`
+	Timestamp   int64    `json:"timestamp"`
+	Beneficiary string   `json:"beneficiary"`
+	MerkleRoot  string   `json:"merkleRoot"`
+	Pruned      bool     `json:"pruned"`
+	FundsTxs    []string `json:"fundsTxs"`
+	AccTxs      []string `json:"accTxs"`
+	ConfigTxs   []string `json:"configTxs"`
+	StakeTxs    []string `json:"stakeTxs"`
+}
+
+//Status is either "pending" (in the mempool) or "confirmed" (part of a closed block).
+type tx struct {
+	Hash      string `json:"hash"`
+	Type      string `json:"type"`
+	Status    string `json:"status"`
+	BlockHash string `json:"blockHash,omitempty"`
+	Fee       uint64 `json:"fee"`
+
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Amount    uint64 `json:"amount,omitempty"`
+	TxCnt     uint32 `json:"txCnt,omitempty"`
+	Issuer    string `json:"issuer,omitempty"`
+	PubKey    string `json:"pubKey,omitempty"`
+	Id        uint8  `json:"id,omitempty"`
+	Payload   uint64 `json:"payload,omitempty"`
+	Account   string `json:"account,omitempty"`
+	IsStaking bool   `json:"isStaking,omitempty"`
+}
+
+type mempool struct {
+	PoolTxs   uint32 `json:"poolTxs"`
+	PoolBytes uint64 `json:"poolBytes"`
+	Count     uint32 `json:"count"`
+	Bytes     uint64 `json:"bytes"`
+	MinFee    uint64 `json:"minFee"`
+	MaxFee    uint64 `json:"maxFee"`
+	MedianFee uint64 `json:"medianFee"`
+	TotalFee  uint64 `json:"totalFee"`
+	Txs       []tx   `json:"txs"`
+}
+
+//Request body of a tx submission. Data is the hex encoded tx as it is sent over the network.
+type submitRequest struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+type submitResponse struct {
+	Hash string `json:"hash"`
+}
+
+//Until is the unix time the ban ends, 0 if the ban does not expire.
+type ban struct {
+	Peer  string `json:"peer"`
+	Until int64  `json:"until"`
+}
+
+//Duration is a Go duration (e.g., "24h"), the ban does not expire if it is empty.
+type banRequest struct {
+	Peer     string `json:"peer"`
+	Duration string `json:"duration"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  uint8  `json:"code,omitempty"`
+}
+
+func newAccount(acc *protocol.Account) account {
+	hash := protocol.SerializeHashContent(acc.Address)
+	return account{
+		Address:   hex.EncodeToString(acc.Address[:]),
+		Hash:      hex.EncodeToString(hash[:]),
+		Issuer:    hex.EncodeToString(acc.Issuer[:]),
+		Balance:   acc.Balance,
+		TxCnt:     acc.TxCnt,
+		IsStaking: acc.IsStaking,
+		IsRoot:    storage.IsRootKey(hash),
+	}
+}
+
+func newBlock(b *protocol.Block) block {
+	return block{
+		Hash:        hex.EncodeToString(b.Hash[:]),
+		PrevHash:    hex.EncodeToString(b.PrevHash[:]),
+		Height:      b.Height,
+		Timestamp:   b.Timestamp,
+		Beneficiary: hex.EncodeToString(b.Beneficiary[:]),
+		MerkleRoot:  hex.EncodeToString(b.MerkleRoot[:]),
+		Pruned:      b.Height > 0 && b.Height <= storage.ReadPrunedHeight(),
+		FundsTxs:    encodeHashes(b.FundsTxData),
+		AccTxs:      encodeHashes(b.AccTxData),
+		ConfigTxs:   encodeHashes(b.ConfigTxData),
+		StakeTxs:    encodeHashes(b.StakeTxData),
+	}
+}
+
+func newTx(transaction protocol.Transaction, status string) tx {
+	hash := transaction.Hash()
+	result := tx{
+		Hash:   hex.EncodeToString(hash[:]),
+		Status: status,
+		Fee:    transaction.TxFee(),
+	}
+
+	switch t := transaction.(type) {
+	case *protocol.FundsTx:
+		result.Type = "funds"
+		result.From = hex.EncodeToString(t.From[:])
+		result.To = hex.EncodeToString(t.To[:])
+		result.Amount = t.Amount
+		result.TxCnt = t.TxCnt
+	case *protocol.AccTx:
+		result.Type = "acc"
+		result.Issuer = hex.EncodeToString(t.Issuer[:])
+		result.PubKey = hex.EncodeToString(t.PubKey[:])
+	case *protocol.ConfigTx:
+		result.Type = "config"
+		result.Id = t.Id
+		result.Payload = t.Payload
+		result.TxCnt = uint32(t.TxCnt)
+	case *protocol.StakeTx:
+		result.Type = "stake"
+		result.Account = hex.EncodeToString(t.Account[:])
+		result.IsStaking = t.IsStaking
+	}
+
+	return result
+}
+
+func newMempool(result *storage.MempoolQueryResult) mempool {
+	pool := mempool{
+		PoolTxs:   result.PoolTxs,
+		PoolBytes: result.PoolBytes,
+		Count:     result.Count,
+		Bytes:     result.Bytes,
+		MinFee:    result.MinFee,
+		MaxFee:    result.MaxFee,
+		MedianFee: result.MedianFee,
+		TotalFee:  result.TotalFee,
+		Txs:       []tx{},
+	}
+
+	for _, transaction := range result.Txs() {
+		pool.Txs = append(pool.Txs, newTx(transaction, "pending"))
+	}
+
+	return pool
+}
+
+func (request submitRequest) decode() (protocol.Transaction, error) {
+	data, err := hex.DecodeString(request.Data)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid tx data: %v", err))
+	}
+
+	var transaction protocol.Transaction
+	switch request.Type {
+	case "funds":
+		var fundsTx *protocol.FundsTx
+		if fundsTx = fundsTx.Decode(data); fundsTx != nil {
+			transaction = fundsTx
+		}
+	case "acc":
+		var accTx *protocol.AccTx
+		if accTx = accTx.Decode(data); accTx != nil {
+			transaction = accTx
+		}
+	case "config":
+		var configTx *protocol.ConfigTx
+		if configTx = configTx.Decode(data); configTx != nil {
+			transaction = configTx
+		}
+	case "stake":
+		var stakeTx *protocol.StakeTx
+		if stakeTx = stakeTx.Decode(data); stakeTx != nil {
+			transaction = stakeTx
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("Unknown tx type %v.", request.Type))
+	}
+
+	if transaction == nil {
+		return nil, errors.New("Tx could not be decoded.")
+	}
+
+	return transaction, nil
+}
+
+func encodeHashes(hashes [][32]byte) []string {
+	encoded := []string{}
+	for _, hash := range hashes {
+		encoded = append(encoded, hex.EncodeToString(hash[:]))
+	}
+	return encoded
+}
+
+//Accepts 32 byte hashes.
+func decodeHash(encoded string) (hash [32]byte, err error) {
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil || len(decoded) != 32 {
+		return hash, errors.New(fmt.Sprintf("Invalid hash %v.", encoded))
+	}
+
+	copy(hash[:], decoded)
+	return hash, nil
+}
+
+//Accounts are identified by their hash or by their 64 byte address.
+func decodeAccountHash(encoded string) (hash [32]byte, err error) {
+	decoded, err := hex.DecodeString(encoded)
+	if err != nil || (len(decoded) != 32 && len(decoded) != 64) {
+		return hash, errors.New(fmt.Sprintf("Invalid account %v.", encoded))
+	}
+
+	if len(decoded) == 64 {
+		var address [64]byte
+		copy(address[:], decoded)
+		return protocol.SerializeHashContent(address), nil
+	}
+
+	copy(hash[:], decoded)
+	return hash, nil
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+//A chain archive starts with ARCHIVE_MAGIC and the archive version, followed by records of the form
+//[type (1 Byte)][payload length (4 Byte)][payload]. Blocks are written in height order, each block is preceded by
+//its txs, such that the archive can be streamed without keeping the chain in memory.
+const (
+	ARCHIVE_MAGIC   = "BAZOCHAIN"
+	ARCHIVE_VERSION = 1
+
+	ARCHIVE_BLOCK    = 1
+	ARCHIVE_FUNDSTX  = 2
+	ARCHIVE_ACCTX    = 3
+	ARCHIVE_CONFIGTX = 4
+	ARCHIVE_STAKETX  = 5
+
+	ARCHIVE_MAX_RECORD = 10000000 //Byte
+)
+
+//Writes the closed blocks from height from to to (inclusive) of the current chain together with their txs.
+func ExportChain(w io.Writer, from, to uint32) (count int, err error) {
+	if from > to {
+		return 0, errors.New(fmt.Sprintf("Invalid height range %v-%v.", from, to))
+	}
+
+	if prunedHeight := ReadPrunedHeight(); prunedHeight > 0 && from <= prunedHeight {
+		return 0, errors.New(fmt.Sprintf("Blocks up to height %v are pruned.", prunedHeight))
+	}
+
+	writer := bufio.NewWriter(w)
+	if _, err := writer.WriteString(ARCHIVE_MAGIC); err != nil {
+		return 0, err
+	}
+	if err := writer.WriteByte(ARCHIVE_VERSION); err != nil {
+		return 0, err
+	}
+
+	for height := from; height <= to; height++ {
+		block := ReadClosedBlockByHeight(height)
+		if block == nil {
+			return count, errors.New(fmt.Sprintf("Block with height %v not found.", height))
+		}
+
+		for _, txHash := range blockTxHashes(block) {
+			tx := ReadClosedTx(txHash)
+			if tx == nil {
+				return count, errors.New(fmt.Sprintf("Tx %x of block with height %v not found.", txHash[0:8], height))
+			}
+
+			if err := writeRecord(writer, txRecordType(tx), tx.Encode()); err != nil {
+				return count, err
+			}
+		}
+
+		if err := writeRecord(writer, ARCHIVE_BLOCK, block.Encode()); err != nil {
+			return count, err
+		}
+		count++
+
+		//Avoid an overflow if the chain is exported up to the maximum height.
+		if height == to {
+			break
+		}
+	}
+
+	return count, writer.Flush()
+}
+
+func writeRecord(w *bufio.Writer, recordType byte, payload []byte) error {
+	var header [5]byte
+	header[0] = recordType
+	binary.BigEndian.PutUint32(header[1:5], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+type ArchiveReader struct {
+	reader *bufio.Reader
+}
+
+func NewArchiveReader(r io.Reader) (*ArchiveReader, error) {
+	reader := bufio.NewReader(r)
+
+	header := make([]byte, len(ARCHIVE_MAGIC)+1)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not read archive header: %v", err))
+	}
+	if string(header[:len(ARCHIVE_MAGIC)]) != ARCHIVE_MAGIC {
+		return nil, errors.New("Not a chain archive.")
+	}
+	if header[len(ARCHIVE_MAGIC)] != ARCHIVE_VERSION {
+		return nil, errors.New(fmt.Sprintf("Unsupported archive version %v.", header[len(ARCHIVE_MAGIC)]))
+	}
+
+	return &ArchiveReader{reader}, nil
+}
+
+//Returns the next block and the txs that precede it. Returns io.EOF if the archive has been read completely.
+func (archive *ArchiveReader) Next() (block *protocol.Block, txs []protocol.Transaction, err error) {
+	for {
+		var header [5]byte
+		if _, err := io.ReadFull(archive.reader, header[:]); err != nil {
+			if err == io.EOF && len(txs) > 0 {
+				return nil, nil, errors.New("Archive ends with txs that do not belong to a block.")
+			}
+			return nil, nil, err
+		}
+
+		length := binary.BigEndian.Uint32(header[1:5])
+		if length > ARCHIVE_MAX_RECORD {
+			return nil, nil, errors.New(fmt.Sprintf("Archive record too large (%v Byte).", length))
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(archive.reader, payload); err != nil {
+			return nil, nil, errors.New(fmt.Sprintf("Truncated archive record: %v", err))
+		}
+
+		if header[0] == ARCHIVE_BLOCK {
+			if block = block.Decode(payload); block == nil {
+				return nil, nil, errors.New("Could not decode block.")
+			}
+			return block, txs, nil
+		}
+
+		tx := decodeTxRecord(header[0], payload)
+		if tx == nil {
+			return nil, nil, errors.New(fmt.Sprintf("Unknown archive record type %v.", header[0]))
+		}
+
+		txs = append(txs, tx)
+	}
+}
+
+//The tx record types are also used by the mempool journal.
+func txRecordType(tx protocol.Transaction) (recordType byte) {
+	switch tx.(type) {
+	case *protocol.FundsTx:
+		recordType = ARCHIVE_FUNDSTX
+	case *protocol.AccTx:
+		recordType = ARCHIVE_ACCTX
+	case *protocol.ConfigTx:
+		recordType = ARCHIVE_CONFIGTX
+	case *protocol.StakeTx:
+		recordType = ARCHIVE_STAKETX
+	}
+
+	return recordType
+}
+
+func decodeTxRecord(recordType byte, payload []byte) protocol.Transaction {
+	switch recordType {
+	case ARCHIVE_FUNDSTX:
+		var fundsTx *protocol.FundsTx
+		return fundsTx.Decode(payload)
+	case ARCHIVE_ACCTX:
+		var accTx *protocol.AccTx
+		return accTx.Decode(payload)
+	case ARCHIVE_CONFIGTX:
+		var configTx *protocol.ConfigTx
+		return configTx.Decode(payload)
+	case ARCHIVE_STAKETX:
+		var stakeTx *protocol.StakeTx
+		return stakeTx.Decode(payload)
+	}
+
+	return nil
+}
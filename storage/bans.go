@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"encoding/binary"
+
+	"github.com/boltdb/bolt"
+)
+
+//Peers that are not allowed to connect, keyed by node ID (miners) or IP address. The value is the end of the ban in
+//unix time (seconds), 0 if the ban does not expire.
+
+func WriteBannedPeer(id string, until int64) (err error) {
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		var encoded [8]byte
+		binary.BigEndian.PutUint64(encoded[:], uint64(until))
+		return tx.Bucket([]byte("bannedpeers")).Put([]byte(id), encoded[:])
+	})
+
+	return err
+}
+
+func ReadBannedPeers() (bans map[string]int64) {
+
+	bans = make(map[string]int64)
+	db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("bannedpeers")).ForEach(func(k, v []byte) error {
+			if len(v) == 8 {
+				bans[string(k)] = int64(binary.BigEndian.Uint64(v))
+			}
+			return nil
+		})
+	})
+
+	return bans
+}
+
+func DeleteBannedPeer(id string) (err error) {
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("bannedpeers")).Delete([]byte(id))
+	})
+
+	return err
+}
@@ -0,0 +1,301 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+	"golang.org/x/crypto/sha3"
+)
+
+//Result of a consistency check. Problems that could be fixed in repair mode are listed in Repaired as well.
+type CheckReport struct {
+	Blocks   int
+	Problems []string
+	Repaired []string
+}
+
+func (report *CheckReport) problem(format string, a ...interface{}) {
+	report.Problems = append(report.Problems, fmt.Sprintf(format, a...))
+}
+
+func (report *CheckReport) repaired(format string, a ...interface{}) {
+	report.Repaired = append(report.Repaired, fmt.Sprintf(format, a...))
+}
+
+//Walks the chain from the last closed block to genesis and verifies block hashes, merkle roots, tx presence, height
+//continuity and the indexes. Orphaned open blocks are reported as well. In repair mode, everything that can be
+//restored from the local database is fixed. Missing txs or broken blocks can only be fixed by syncing the chain again.
+//Must not be called while the miner is running.
+func CheckConsistency(repair bool) (report *CheckReport, err error) {
+	report = new(CheckReport)
+
+	lastBlock, err := checkLastClosedBlock(report, repair)
+	if err != nil || lastBlock == nil {
+		return report, err
+	}
+
+	prunedHeight := ReadPrunedHeight()
+	visited := make(map[[32]byte]bool)
+	for block := lastBlock; block != nil; {
+		if visited[block.Hash] {
+			report.problem("Block (%x) is part of a cycle", block.Hash[0:8])
+			break
+		}
+		visited[block.Hash] = true
+		report.Blocks++
+
+		pruned := block.Height > 0 && block.Height <= prunedHeight
+		if err := checkBlock(report, block, pruned, repair); err != nil {
+			return report, err
+		}
+
+		if block.Hash == [32]byte{} {
+			if block.Height != 0 {
+				report.problem("Genesis block has height %v", block.Height)
+			}
+			break
+		}
+
+		prevBlock := ReadClosedBlock(block.PrevHash)
+		if prevBlock == nil {
+			report.problem("Previous block (%x) of block (%x) with height %v not found, chain is broken", block.PrevHash[0:8], block.Hash[0:8], block.Height)
+			break
+		}
+		if prevBlock.Height+1 != block.Height {
+			report.problem("Block (%x) has height %v but its previous block has height %v", block.Hash[0:8], block.Height, prevBlock.Height)
+		}
+
+		block = prevBlock
+	}
+
+	if err := checkHeightIndex(report, lastBlock.Height, repair); err != nil {
+		return report, err
+	}
+
+	return report, checkOpenBlocks(report, repair)
+}
+
+//After a crash, lastclosedblock might hold more than one entry. The one with the highest height is kept.
+func checkLastClosedBlock(report *CheckReport, repair bool) (lastBlock *protocol.Block, err error) {
+	var entries []*protocol.Block
+	db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("lastclosedblock")).ForEach(func(k, v []byte) error {
+			var block *protocol.Block
+			if block = block.Decode(v); block != nil {
+				entries = append(entries, block)
+			}
+			return nil
+		})
+	})
+
+	if len(entries) == 0 {
+		report.problem("No last closed block found")
+		return nil, nil
+	}
+
+	lastBlock = entries[0]
+	for _, block := range entries[1:] {
+		if block.Height > lastBlock.Height {
+			lastBlock = block
+		}
+	}
+
+	if len(entries) > 1 {
+		report.problem("Found %v last closed blocks", len(entries))
+		if repair {
+			err = db.Update(func(tx *bolt.Tx) error {
+				b := tx.Bucket([]byte("lastclosedblock"))
+				for _, block := range entries {
+					if block.Hash == lastBlock.Hash {
+						continue
+					}
+					if err := b.Delete(block.Hash[:]); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			report.repaired("Kept block (%x) with height %v as last closed block", lastBlock.Hash[0:8], lastBlock.Height)
+		}
+	}
+
+	if ReadClosedBlock(lastBlock.Hash) == nil {
+		report.problem("Last closed block (%x) is not a closed block", lastBlock.Hash[0:8])
+		if repair {
+			if err := WriteClosedBlock(lastBlock); err != nil {
+				return nil, err
+			}
+			report.repaired("Wrote last closed block (%x) to the closed blocks", lastBlock.Hash[0:8])
+		}
+	}
+
+	return lastBlock, nil
+}
+
+func checkBlock(report *CheckReport, block *protocol.Block, pruned bool, repair bool) error {
+	//The genesis block is not finalized and has no hash.
+	if block.Hash != [32]byte{} && computeBlockHash(block) != block.Hash {
+		report.problem("Block (%x) with height %v has an invalid hash", block.Hash[0:8], block.Height)
+	}
+
+	//Pruned blocks only consist of the header.
+	if pruned {
+		return checkBlockIndex(report, block, repair)
+	}
+
+	if block.Hash != [32]byte{} && protocol.BuildMerkleTree(block).MerkleRoot() != block.MerkleRoot {
+		report.problem("Block (%x) with height %v has an invalid merkle root", block.Hash[0:8], block.Height)
+	}
+
+	for _, txHash := range blockTxHashes(block) {
+		transaction := ReadClosedTx(txHash)
+		if transaction == nil {
+			report.problem("Tx (%x) of block (%x) with height %v not found", txHash[0:8], block.Hash[0:8], block.Height)
+			continue
+		}
+		if err := checkTxIndex(report, transaction, repair); err != nil {
+			return err
+		}
+	}
+
+	return checkBlockIndex(report, block, repair)
+}
+
+//The partial hash is computed in finalizeBlock before the timestamp and the commitment proof are set.
+func computeBlockHash(block *protocol.Block) [32]byte {
+	partial := *block
+	partial.Timestamp = 0
+	partial.CommitmentProof = [crypto.COMM_PROOF_LENGTH]byte{}
+	partialHash := partial.HashBlock()
+
+	return sha3.Sum256(append(block.Nonce[:], partialHash[:]...))
+}
+
+func checkBlockIndex(report *CheckReport, block *protocol.Block, repair bool) error {
+	consistent := true
+	db.View(func(tx *bolt.Tx) error {
+		if hash := tx.Bucket([]byte("blockheights")).Get(heightKey(block.Height)); !bytes.Equal(hash, block.Hash[:]) {
+			consistent = false
+		}
+		b := tx.Bucket([]byte("txlocations"))
+		for _, txHash := range blockTxHashes(block) {
+			if location := decodeTxLocation(b.Get(txHash[:])); location == nil || location.BlockHash != block.Hash {
+				consistent = false
+			}
+		}
+		return nil
+	})
+
+	if consistent {
+		return nil
+	}
+
+	report.problem("Indexes of block (%x) with height %v are inconsistent", block.Hash[0:8], block.Height)
+	if repair {
+		if err := db.Update(func(tx *bolt.Tx) error { return indexBlock(tx, block) }); err != nil {
+			return err
+		}
+		report.repaired("Rebuilt indexes of block (%x) with height %v", block.Hash[0:8], block.Height)
+	}
+
+	return nil
+}
+
+func checkTxIndex(report *CheckReport, transaction protocol.Transaction, repair bool) error {
+	consistent := true
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("accounttxs"))
+		for _, account := range txAccounts(transaction) {
+			if b.Get(accountTxKey(account, transaction.Hash())) == nil {
+				consistent = false
+			}
+		}
+		return nil
+	})
+
+	if consistent {
+		return nil
+	}
+
+	txHash := transaction.Hash()
+	report.problem("Account index of tx (%x) is incomplete", txHash[0:8])
+	if repair {
+		if err := db.Update(func(tx *bolt.Tx) error { return indexTx(tx, transaction) }); err != nil {
+			return err
+		}
+		report.repaired("Rebuilt account index of tx (%x)", txHash[0:8])
+	}
+
+	return nil
+}
+
+//Heights above the last closed block belong to blocks that have been rolled back.
+func checkHeightIndex(report *CheckReport, lastHeight uint32, repair bool) error {
+	var stale [][]byte
+	db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte("blockheights")).Cursor()
+		for k, _ := c.Seek(heightKey(lastHeight + 1)); k != nil; k, _ = c.Next() {
+			stale = append(stale, append([]byte{}, k...))
+		}
+		return nil
+	})
+
+	if len(stale) == 0 {
+		return nil
+	}
+
+	report.problem("Found %v height index entries above the last closed block", len(stale))
+	if repair {
+		err := db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte("blockheights"))
+			for _, k := range stale {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		report.repaired("Deleted %v stale height index entries", len(stale))
+	}
+
+	return nil
+}
+
+//Open blocks are orphaned if they have been closed already or if their previous block is unknown.
+func checkOpenBlocks(report *CheckReport, repair bool) error {
+	var orphaned [][32]byte
+	db.View(func(tx *bolt.Tx) error {
+		openBlocks := tx.Bucket([]byte("openblocks"))
+		closedBlocks := tx.Bucket([]byte("closedblocks"))
+		return openBlocks.ForEach(func(k, v []byte) error {
+			var block *protocol.Block
+			block = block.Decode(v)
+			if block == nil || closedBlocks.Get(k) != nil ||
+				(closedBlocks.Get(block.PrevHash[:]) == nil && openBlocks.Get(block.PrevHash[:]) == nil) {
+				var hash [32]byte
+				copy(hash[:], k)
+				orphaned = append(orphaned, hash)
+			}
+			return nil
+		})
+	})
+
+	for _, hash := range orphaned {
+		report.problem("Open block (%x) is orphaned", hash[0:8])
+		if repair {
+			DeleteOpenBlock(hash)
+			report.repaired("Deleted orphaned open block (%x)", hash[0:8])
+		}
+	}
+
+	return nil
+}
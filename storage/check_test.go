@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+func TestCheckConsistency(t *testing.T) {
+
+	DeleteAll()
+	defer DeleteAll()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	fundsTx, _ := protocol.ConstrFundsTx(0x01, 40, 1, 3, accAHash, accBHash, &PrivKeyA, nil, nil)
+
+	genesis := new(protocol.Block)
+
+	b1 := protocol.NewBlock(genesis.Hash, 1)
+	b1.FundsTxData = [][32]byte{fundsTx.Hash()}
+	b1.MerkleRoot = protocol.BuildMerkleTree(b1).MerkleRoot()
+	b1.Nonce = [8]byte{1}
+	b1.Hash = computeBlockHash(b1)
+
+	b2 := protocol.NewBlock(b1.Hash, 2)
+	b2.Nonce = [8]byte{2}
+	b2.Hash = computeBlockHash(b2)
+
+	WriteClosedTx(fundsTx)
+	WriteClosedBlock(genesis)
+	WriteClosedBlock(b1)
+	WriteClosedBlock(b2)
+	WriteLastClosedBlock(b2)
+
+	report, err := CheckConsistency(false)
+	if err != nil || report.Blocks != 3 || len(report.Problems) != 0 {
+		t.Fatalf("Consistent chain was reported as inconsistent (%v blocks): %v %v\n", report.Blocks, report.Problems, err)
+	}
+
+	//Simulate a crash: two last closed blocks, a missing tx and an orphaned open block
+	orphan := protocol.NewBlock([32]byte{'x'}, 5)
+	orphan.Hash = [32]byte{'o'}
+	WriteOpenBlock(orphan)
+	WriteLastClosedBlock(b1)
+	DeleteClosedTx(fundsTx)
+
+	report, _ = CheckConsistency(false)
+	if len(report.Problems) != 3 || len(report.Repaired) != 0 {
+		t.Errorf("Expected 3 problems, got %v\n", report.Problems)
+	}
+
+	report, _ = CheckConsistency(true)
+	if len(report.Repaired) != 2 {
+		t.Errorf("Expected 2 repairs, got %v\n", report.Repaired)
+	}
+
+	if ReadOpenBlock(orphan.Hash) != nil {
+		t.Error("Orphaned open block was not deleted.\n")
+	}
+	if block := ReadLastClosedBlock(); block == nil || block.Hash != b2.Hash {
+		t.Error("Wrong last closed block was kept.\n")
+	}
+
+	//The missing tx cannot be repaired locally
+	report, _ = CheckConsistency(true)
+	if len(report.Problems) != 1 || len(report.Repaired) != 0 {
+		t.Errorf("Expected the missing tx as only problem, got %v\n", report.Problems)
+	}
+
+	//A tampered block is detected
+	b2.Beneficiary = [32]byte{'b'}
+	WriteClosedBlock(b2)
+	WriteLastClosedBlock(b2)
+	report, _ = CheckConsistency(false)
+	if len(report.Problems) != 2 {
+		t.Errorf("Expected an invalid hash, got %v\n", report.Problems)
+	}
+}
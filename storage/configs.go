@@ -0,0 +1,12 @@
+package storage
+
+const (
+	//Default mempool limits
+	MEMPOOL_MAX_TXS            = 100000    //Txs
+	MEMPOOL_MAX_BYTES          = 100000000 //Byte
+	MEMPOOL_MAX_TXS_PER_SENDER = 5000      //Txs
+	MEMPOOL_JOURNAL_INTERVAL   = 1         //Sec
+
+	//Maximum number of txs returned by a mempool query
+	MEMPOOL_QUERY_MAX_TXS = 1000
+)
@@ -17,6 +17,12 @@ func DeleteOpenBlock(hash [32]byte) {
 func DeleteClosedBlock(hash [32]byte) {
 	db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte("closedblocks"))
+		var block *protocol.Block
+		if block = block.Decode(b.Get(hash[:])); block != nil {
+			if err := unindexBlock(tx, block); err != nil {
+				return err
+			}
+		}
 		err := b.Delete(hash[:])
 		return err
 	})
@@ -41,8 +47,21 @@ func DeleteAllLastClosedBlock() {
 	})
 }
 
+//Removes the pruned height and the state snapshot.
+func DeleteChainState() {
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("chainstate"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+}
+
 func DeleteOpenTx(transaction protocol.Transaction) {
-	delete(txMemPool, transaction.Hash())
+	txMemPool.Remove(transaction.Hash())
+	scheduleJournalFlush()
 }
 
 func DeleteClosedTx(transaction protocol.Transaction) {
@@ -61,6 +80,9 @@ func DeleteClosedTx(transaction protocol.Transaction) {
 	hash := transaction.Hash()
 	db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucket))
+		if err := unindexTx(tx, transaction); err != nil {
+			return err
+		}
 		err := b.Delete(hash[:])
 		return err
 	})
@@ -68,9 +90,10 @@ func DeleteClosedTx(transaction protocol.Transaction) {
 
 func DeleteAll() {
 	//Delete in-memory storage
-	for key := range txMemPool {
-		delete(txMemPool, key)
-	}
+	txMemPool.Clear()
+	journalMutex.Lock()
+	txMemPool.takeJournal()
+	journalMutex.Unlock()
 
 	//Delete disk-based storage
 	db.Update(func(tx *bolt.Tx) error {
@@ -129,4 +152,44 @@ func DeleteAll() {
 		})
 		return nil
 	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("blockheights"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("txlocations"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("accounttxs"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("chainstate"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
+	db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("mempool"))
+		b.ForEach(func(k, v []byte) error {
+			b.Delete(k)
+			return nil
+		})
+		return nil
+	})
 }
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+//Events are published when the chain or the mempool changes, e.g., to stream them to RPC subscribers. Txs added to
+//the mempool are published by WriteOpenTx, blocks and reorgs by the miner once the state has been updated.
+const (
+	EVENT_BLOCK = "block"
+	EVENT_REORG = "reorg"
+	EVENT_TX    = "tx"
+
+	//Events buffered per subscriber
+	EVENT_BUFFER = 1000
+)
+
+//Block events contain the validated block and its txs. Reorg events contain the hashes of the rolled back blocks
+//(latest first), they are published before the blocks of the new chain. Tx events contain a tx added to the mempool.
+type Event struct {
+	Type       string
+	Block      *protocol.Block
+	Txs        []protocol.Transaction
+	RolledBack [][32]byte
+}
+
+type Subscription struct {
+	Events chan Event
+}
+
+var (
+	subscriptions      = make(map[*Subscription]bool)
+	subscriptionsMutex = &sync.Mutex{}
+)
+
+func Subscribe() *Subscription {
+	subscriptionsMutex.Lock()
+	defer subscriptionsMutex.Unlock()
+
+	subscription := &Subscription{make(chan Event, EVENT_BUFFER)}
+	subscriptions[subscription] = true
+	return subscription
+}
+
+func (subscription *Subscription) Unsubscribe() {
+	subscriptionsMutex.Lock()
+	defer subscriptionsMutex.Unlock()
+
+	if subscriptions[subscription] {
+		delete(subscriptions, subscription)
+		close(subscription.Events)
+	}
+}
+
+//Publishing never blocks. A subscriber that does not keep up is unsubscribed (its channel is closed), otherwise it
+//would silently miss events.
+func Publish(event Event) {
+	subscriptionsMutex.Lock()
+	defer subscriptionsMutex.Unlock()
+
+	for subscription := range subscriptions {
+		select {
+		case subscription.Events <- event:
+		default:
+			delete(subscriptions, subscription)
+			close(subscription.Events)
+		}
+	}
+}
+
+//Returns true if the tx involves the account as sender, receiver, issuer or new account.
+func TxTouchesAccount(transaction protocol.Transaction, account [32]byte) bool {
+	for _, touched := range txAccounts(transaction) {
+		if touched == account {
+			return true
+		}
+	}
+	return false
+}
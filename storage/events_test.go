@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestPublish(t *testing.T) {
+
+	subscription := Subscribe()
+	slow := Subscribe()
+	defer subscription.Unsubscribe()
+
+	for i := 0; i < EVENT_BUFFER; i++ {
+		Publish(Event{Type: EVENT_REORG})
+		<-subscription.Events
+	}
+
+	//The buffer of slow is full now, it is unsubscribed instead of blocking the publisher
+	Publish(Event{Type: EVENT_REORG})
+	if event := <-subscription.Events; event.Type != EVENT_REORG {
+		t.Errorf("Wrong event: %v\n", event)
+	}
+
+	count := 0
+	for range slow.Events {
+		count++
+	}
+	if count != EVENT_BUFFER {
+		t.Errorf("Slow subscriber received %v events instead of %v\n", count, EVENT_BUFFER)
+	}
+
+	//Unsubscribing twice is a no-op
+	slow.Unsubscribe()
+}
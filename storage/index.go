@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+//The secondary indexes are maintained together with the closed blocks and closed txs they point to. They are written
+//and deleted within the same bolt transaction, so a rollback (which deletes closed blocks/txs) keeps them consistent.
+const (
+	TXLOCATION_LEN = 36 //32 Byte block hash + 4 Byte position
+)
+
+//Position of a closed tx inside the block it was validated in. The position corresponds to the order of the merkle
+//tree leaves (fundsTxs, accTxs, configTxs, stakeTxs).
+type TxLocation struct {
+	BlockHash [32]byte
+	Position  uint32
+}
+
+func heightKey(height uint32) []byte {
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+	return key[:]
+}
+
+func (location *TxLocation) encode() []byte {
+	encoded := make([]byte, TXLOCATION_LEN)
+	copy(encoded[0:32], location.BlockHash[:])
+	binary.BigEndian.PutUint32(encoded[32:36], location.Position)
+	return encoded
+}
+
+func decodeTxLocation(encoded []byte) *TxLocation {
+	if len(encoded) != TXLOCATION_LEN {
+		return nil
+	}
+
+	location := new(TxLocation)
+	copy(location.BlockHash[:], encoded[0:32])
+	location.Position = binary.BigEndian.Uint32(encoded[32:36])
+	return location
+}
+
+//All tx hashes of a block in merkle tree order.
+func blockTxHashes(block *protocol.Block) (txHashes [][32]byte) {
+	txHashes = append(txHashes, block.FundsTxData...)
+	txHashes = append(txHashes, block.AccTxData...)
+	txHashes = append(txHashes, block.ConfigTxData...)
+	txHashes = append(txHashes, block.StakeTxData...)
+	return txHashes
+}
+
+//Accounts touched by a tx. ConfigTxs are signed by a root account but do not reference it, so they are not indexed.
+func txAccounts(transaction protocol.Transaction) (accounts [][32]byte) {
+	switch tx := transaction.(type) {
+	case *protocol.FundsTx:
+		accounts = append(accounts, tx.From, tx.To)
+	case *protocol.AccTx:
+		accounts = append(accounts, tx.Issuer, protocol.SerializeHashContent(tx.PubKey))
+	case *protocol.StakeTx:
+		accounts = append(accounts, tx.Account)
+	}
+
+	return accounts
+}
+
+func accountTxKey(account, txHash [32]byte) []byte {
+	key := make([]byte, 64)
+	copy(key[0:32], account[:])
+	copy(key[32:64], txHash[:])
+	return key
+}
+
+func indexBlock(tx *bolt.Tx, block *protocol.Block) error {
+	if err := tx.Bucket([]byte("blockheights")).Put(heightKey(block.Height), block.Hash[:]); err != nil {
+		return err
+	}
+
+	b := tx.Bucket([]byte("txlocations"))
+	for position, txHash := range blockTxHashes(block) {
+		location := TxLocation{block.Hash, uint32(position)}
+		if err := b.Put(txHash[:], location.encode()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unindexBlock(tx *bolt.Tx, block *protocol.Block) error {
+	//Only remove the height entry if it still points to this block, another block at the same height might have
+	//been written in the meantime.
+	heights := tx.Bucket([]byte("blockheights"))
+	if hash := heights.Get(heightKey(block.Height)); bytes.Equal(hash, block.Hash[:]) {
+		if err := heights.Delete(heightKey(block.Height)); err != nil {
+			return err
+		}
+	}
+
+	b := tx.Bucket([]byte("txlocations"))
+	for _, txHash := range blockTxHashes(block) {
+		location := decodeTxLocation(b.Get(txHash[:]))
+		if location == nil || location.BlockHash != block.Hash {
+			continue
+		}
+		if err := b.Delete(txHash[:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func indexTx(tx *bolt.Tx, transaction protocol.Transaction) error {
+	b := tx.Bucket([]byte("accounttxs"))
+	for _, account := range txAccounts(transaction) {
+		if err := b.Put(accountTxKey(account, transaction.Hash()), []byte{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unindexTx(tx *bolt.Tx, transaction protocol.Transaction) error {
+	b := tx.Bucket([]byte("accounttxs"))
+	for _, account := range txAccounts(transaction) {
+		if err := b.Delete(accountTxKey(account, transaction.Hash())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
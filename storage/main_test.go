@@ -5,9 +5,9 @@ import (
 	"crypto/elliptic"
 	"crypto/rsa"
 	"github.com/bazo-blockchain/bazo-miner/crypto"
+	"github.com/bazo-blockchain/bazo-miner/logging"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"io/ioutil"
-	"log"
 	"math/big"
 	"os"
 	"testing"
@@ -54,7 +54,7 @@ func TestMain(m *testing.M) {
 	addTestingAccounts()
 	addRootAccounts()
 	//we don't want logging msgs when testing, designated messages
-	log.SetOutput(ioutil.Discard)
+	logging.SetOutput(ioutil.Discard)
 	retCode := m.Run()
 
 	TearDown()
@@ -0,0 +1,434 @@
+package storage
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+//The mempool is accessed concurrently by the p2p package (incoming txs) and the miner (block preparation and
+//validation). It is bounded by the number of txs, their total size and the number of txs per sender. If the mempool
+//is full, txs with the lowest fee rate (fee per byte) are evicted in favor of txs that pay more.
+//FundsTxs are additionally kept per sender and tx count. Only one tx per tx count is kept, a tx with the same tx count
+//replaces the existing one if it pays a higher fee. Only the fundsTx with the highest tx count of a sender can be
+//evicted, evicting a lower one would leave the higher ones waiting for a tx count that is gone.
+type Mempool struct {
+	mutex        sync.RWMutex
+	txs          map[[32]byte]protocol.Transaction
+	fundsTxs     map[[32]byte]map[uint32]*protocol.FundsTx
+	senders      map[[32]byte]int
+	evictable    feeRateHeap
+	entries      map[[32]byte]*mempoolEntry
+	tips         map[[32]byte][32]byte
+	journal      []journalOp
+	bytes        uint64
+	maxTxs       int
+	maxBytes     uint64
+	maxPerSender int
+	stats        MempoolStats
+}
+
+type MempoolStats struct {
+	Txs      int
+	Bytes    uint64
+	Added    uint64
+	Removed  uint64
+	Evicted  uint64
+	Replaced uint64
+	Rejected uint64
+}
+
+//Change of the mempool that has not been journaled yet, tx is nil if the tx was removed.
+type journalOp struct {
+	hash [32]byte
+	tx   protocol.Transaction
+}
+
+//A tx that can be evicted and its position in the heap.
+type mempoolEntry struct {
+	tx    protocol.Transaction
+	index int
+}
+
+//Evictable txs ordered by fee rate, the tx with the lowest fee rate is on top (see container/heap).
+type feeRateHeap []*mempoolEntry
+
+func (h feeRateHeap) Len() int {
+	return len(h)
+}
+
+func (h feeRateHeap) Less(i, j int) bool {
+	return lowerFeeRate(h[i].tx, h[j].tx)
+}
+
+func (h feeRateHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *feeRateHeap) Push(x interface{}) {
+	entry := x.(*mempoolEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *feeRateHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
+
+func NewMempool(maxTxs int, maxBytes uint64, maxPerSender int) *Mempool {
+	return &Mempool{
+		txs:          make(map[[32]byte]protocol.Transaction),
+		fundsTxs:     make(map[[32]byte]map[uint32]*protocol.FundsTx),
+		senders:      make(map[[32]byte]int),
+		entries:      make(map[[32]byte]*mempoolEntry),
+		tips:         make(map[[32]byte][32]byte),
+		maxTxs:       maxTxs,
+		maxBytes:     maxBytes,
+		maxPerSender: maxPerSender,
+	}
+}
+
+//Sender of a tx for the per-sender limit. ConfigTxs do not reference their (root) issuer and are not limited.
+func txSender(transaction protocol.Transaction) (sender [32]byte, ok bool) {
+	switch tx := transaction.(type) {
+	case *protocol.FundsTx:
+		return tx.From, true
+	case *protocol.AccTx:
+		return tx.Issuer, true
+	case *protocol.StakeTx:
+		return tx.Account, true
+	}
+
+	return sender, false
+}
+
+//Compares fee rates without floating point arithmetic: fee1/size1 < fee2/size2 <=> fee1*size2 < fee2*size1. The
+//products are compared as 128 bit integers, such that large fees do not overflow.
+func lowerFeeRate(tx1, tx2 protocol.Transaction) bool {
+	hi1, lo1 := bits.Mul64(tx1.TxFee(), tx2.Size())
+	hi2, lo2 := bits.Mul64(tx2.TxFee(), tx1.Size())
+	return hi1 < hi2 || (hi1 == hi2 && lo1 < lo2)
+}
+
+//Adds a tx to the mempool and returns the txs that were evicted or replaced to make room for it.
+func (pool *Mempool) Add(transaction protocol.Transaction) (evicted []protocol.Transaction, err error) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	hash := transaction.Hash()
+	if _, exists := pool.txs[hash]; exists {
+		return nil, errors.New(fmt.Sprintf("Tx (%x) already in the mempool.", hash[0:8]))
+	}
+
+	if transaction.Size() > pool.maxBytes {
+		pool.stats.Rejected++
+		return nil, errors.New(fmt.Sprintf("Tx (%x) exceeds the mempool size.", hash[0:8]))
+	}
+
+	//The replaced tx is only removed once the new tx is accepted.
+	var replaced *protocol.FundsTx
+	if fundsTx, ok := transaction.(*protocol.FundsTx); ok {
+		if replaced = pool.fundsTxs[fundsTx.From][fundsTx.TxCnt]; replaced != nil && fundsTx.Fee <= replaced.Fee {
+			pool.stats.Rejected++
+			return nil, errors.New(fmt.Sprintf("Tx with txCnt %v already in the mempool, replacement needs a higher fee than %v.", fundsTx.TxCnt, replaced.Fee))
+		}
+	}
+
+	sender, hasSender := txSender(transaction)
+	if hasSender && replaced == nil && pool.senders[sender] >= pool.maxPerSender {
+		pool.stats.Rejected++
+		return nil, errors.New(fmt.Sprintf("Sender %x has too many txs in the mempool (%v).", sender[0:8], pool.senders[sender]))
+	}
+
+	if replaced != nil {
+		pool.remove(replaced.Hash())
+	}
+
+	//Evict txs with a lower fee rate until the tx fits. If there are not enough such txs, the evicted txs are put back.
+	var candidates []protocol.Transaction
+	for len(pool.txs) >= pool.maxTxs || pool.bytes+transaction.Size() > pool.maxBytes {
+		if len(pool.evictable) == 0 || !lowerFeeRate(pool.evictable[0].tx, transaction) {
+			for _, tx := range candidates {
+				pool.insert(tx)
+			}
+			if replaced != nil {
+				pool.insert(replaced)
+			}
+			pool.stats.Rejected++
+			return nil, errors.New(fmt.Sprintf("Mempool is full, fee rate of tx (%x) too low.", hash[0:8]))
+		}
+
+		lowest := pool.evictable[0].tx
+		pool.remove(lowest.Hash())
+		candidates = append(candidates, lowest)
+	}
+
+	pool.stats.Evicted += uint64(len(candidates))
+	if replaced != nil {
+		pool.stats.Replaced++
+		candidates = append(candidates, replaced)
+	}
+
+	pool.insert(transaction)
+	pool.stats.Added++
+
+	for _, tx := range candidates {
+		pool.journal = append(pool.journal, journalOp{tx.Hash(), nil})
+	}
+	pool.journal = append(pool.journal, journalOp{hash, transaction})
+
+	return candidates, nil
+}
+
+func (pool *Mempool) insert(transaction protocol.Transaction) {
+	pool.txs[transaction.Hash()] = transaction
+	pool.bytes += transaction.Size()
+	if sender, hasSender := txSender(transaction); hasSender {
+		pool.senders[sender]++
+	}
+
+	if fundsTx, ok := transaction.(*protocol.FundsTx); ok {
+		if pool.fundsTxs[fundsTx.From] == nil {
+			pool.fundsTxs[fundsTx.From] = make(map[uint32]*protocol.FundsTx)
+		}
+		pool.fundsTxs[fundsTx.From][fundsTx.TxCnt] = fundsTx
+		pool.updateTip(fundsTx.From)
+	} else {
+		pool.pushEvictable(transaction)
+	}
+}
+
+func (pool *Mempool) remove(hash [32]byte) bool {
+	transaction, exists := pool.txs[hash]
+	if !exists {
+		return false
+	}
+
+	delete(pool.txs, hash)
+	pool.bytes -= transaction.Size()
+	pool.dropEvictable(hash)
+	if fundsTx, ok := transaction.(*protocol.FundsTx); ok {
+		if delete(pool.fundsTxs[fundsTx.From], fundsTx.TxCnt); len(pool.fundsTxs[fundsTx.From]) == 0 {
+			delete(pool.fundsTxs, fundsTx.From)
+		}
+		pool.updateTip(fundsTx.From)
+	}
+	if sender, hasSender := txSender(transaction); hasSender {
+		if pool.senders[sender]--; pool.senders[sender] <= 0 {
+			delete(pool.senders, sender)
+		}
+	}
+
+	return true
+}
+
+//Makes the fundsTx with the highest tx count of the sender the only evictable fundsTx of the sender.
+func (pool *Mempool) updateTip(sender [32]byte) {
+	var tip *protocol.FundsTx
+	for _, tx := range pool.fundsTxs[sender] {
+		if tip == nil || tx.TxCnt > tip.TxCnt {
+			tip = tx
+		}
+	}
+
+	if hash, exists := pool.tips[sender]; exists {
+		if tip != nil && hash == tip.Hash() {
+			return
+		}
+		pool.dropEvictable(hash)
+		delete(pool.tips, sender)
+	}
+	if tip != nil {
+		pool.pushEvictable(tip)
+		pool.tips[sender] = tip.Hash()
+	}
+}
+
+func (pool *Mempool) pushEvictable(transaction protocol.Transaction) {
+	entry := &mempoolEntry{tx: transaction}
+	heap.Push(&pool.evictable, entry)
+	pool.entries[transaction.Hash()] = entry
+}
+
+func (pool *Mempool) dropEvictable(hash [32]byte) {
+	if entry, exists := pool.entries[hash]; exists {
+		heap.Remove(&pool.evictable, entry.index)
+		delete(pool.entries, hash)
+	}
+}
+
+func (pool *Mempool) Remove(hash [32]byte) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if pool.remove(hash) {
+		pool.stats.Removed++
+	}
+	//Journaled txs that were not reloaded are removed as well.
+	pool.journal = append(pool.journal, journalOp{hash, nil})
+}
+
+//Returns the changes since the last call in the order they were made.
+func (pool *Mempool) takeJournal() (journal []journalOp) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	journal, pool.journal = pool.journal, nil
+	return journal
+}
+
+func (pool *Mempool) Get(hash [32]byte) protocol.Transaction {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	return pool.txs[hash]
+}
+
+func (pool *Mempool) All() (txs []protocol.Transaction) {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	for _, tx := range pool.txs {
+		txs = append(txs, tx)
+	}
+
+	return txs
+}
+
+func (pool *Mempool) Clear() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	pool.txs = make(map[[32]byte]protocol.Transaction)
+	pool.fundsTxs = make(map[[32]byte]map[uint32]*protocol.FundsTx)
+	pool.senders = make(map[[32]byte]int)
+	pool.evictable = nil
+	pool.entries = make(map[[32]byte]*mempoolEntry)
+	pool.tips = make(map[[32]byte][32]byte)
+	pool.bytes = 0
+}
+
+//Splits the fundsTxs by their sender's current tx count (returned by txCnt). Pending txs can be included in the next
+//block, they are ordered by sender and tx count. Queued txs wait for a missing tx count (nonce gap) and become
+//pending as soon as the gap is filled. Stale txs have a tx count that has already been used.
+func (pool *Mempool) FundsTxQueues(txCnt func(sender [32]byte) uint32) (pending, queued, stale []*protocol.FundsTx) {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	for sender, txs := range pool.fundsTxs {
+		first := txCnt(sender)
+		next := first
+		for ; txs[next] != nil; next++ {
+			pending = append(pending, txs[next])
+		}
+
+		for cnt, tx := range txs {
+			if cnt < first {
+				stale = append(stale, tx)
+			} else if cnt > next {
+				queued = append(queued, tx)
+			}
+		}
+	}
+
+	return pending, queued, stale
+}
+
+func (pool *Mempool) Stats() MempoolStats {
+	pool.mutex.RLock()
+	defer pool.mutex.RUnlock()
+
+	stats := pool.stats
+	stats.Txs = len(pool.txs)
+	stats.Bytes = pool.bytes
+	return stats
+}
+
+func ReadMempoolStats() MempoolStats {
+	return txMemPool.Stats()
+}
+
+//Every change of the mempool is journaled to the "mempool" bucket such that pending txs survive a restart. A journal
+//entry consists of the tx record type (see archive.go) followed by the encoded tx. The changes are written in batches
+//(one transaction and fsync per batch): after every validated block and MEMPOOL_JOURNAL_INTERVAL seconds after the
+//first change that is not journaled yet.
+
+var (
+	journalMutex     sync.Mutex
+	journalScheduled bool
+)
+
+//Writes the pending changes of the mempool to the journal.
+func FlushMempoolJournal() error {
+	journalMutex.Lock()
+	defer journalMutex.Unlock()
+
+	journalScheduled = false
+	journal := txMemPool.takeJournal()
+	if len(journal) == 0 {
+		return nil
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("mempool"))
+		for _, op := range journal {
+			if op.tx == nil {
+				if err := b.Delete(op.hash[:]); err != nil {
+					return err
+				}
+				continue
+			}
+
+			entry := append([]byte{txRecordType(op.tx)}, op.tx.Encode()...)
+			if err := b.Put(op.hash[:], entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func scheduleJournalFlush() {
+	journalMutex.Lock()
+	defer journalMutex.Unlock()
+
+	if journalScheduled {
+		return
+	}
+	journalScheduled = true
+
+	time.AfterFunc(MEMPOOL_JOURNAL_INTERVAL*time.Second, func() {
+		if err := FlushMempoolJournal(); err != nil {
+			logger.Errorf("Could not journal the mempool: %v", err)
+		}
+	})
+}
+
+//Returns the txs journaled during the last run. They are not added to the mempool, the miner has to check them
+//against the current state first (and call WriteOpenTx or DeleteOpenTx respectively).
+func ReadJournaledTxs() (txs []protocol.Transaction) {
+	db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("mempool")).ForEach(func(k, v []byte) error {
+			if len(v) < 1 {
+				return nil
+			}
+			if transaction := decodeTxRecord(v[0], v[1:]); transaction != nil {
+				txs = append(txs, transaction)
+			}
+			return nil
+		})
+	})
+
+	return txs
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+func TestMempoolLimits(t *testing.T) {
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	pool := NewMempool(3, 3*protocol.FUNDSTX_SIZE, 2)
+
+	txA1, _ := protocol.ConstrFundsTx(0x01, 10, 5, 0, accAHash, accBHash, &PrivKeyA, nil, nil)
+	txA2, _ := protocol.ConstrFundsTx(0x01, 10, 1, 1, accAHash, accBHash, &PrivKeyA, nil, nil)
+	txA3, _ := protocol.ConstrFundsTx(0x01, 10, 9, 2, accAHash, accBHash, &PrivKeyA, nil, nil)
+	txB1, _ := protocol.ConstrFundsTx(0x01, 10, 3, 0, accBHash, accAHash, &PrivKeyB, nil, nil)
+	txB2, _ := protocol.ConstrFundsTx(0x01, 10, 2, 1, accBHash, accAHash, &PrivKeyB, nil, nil)
+	txB3, _ := protocol.ConstrFundsTx(0x01, 10, 4, 2, accBHash, accAHash, &PrivKeyB, nil, nil)
+
+	for _, tx := range []*protocol.FundsTx{txA1, txA2, txB1} {
+		if _, err := pool.Add(tx); err != nil {
+			t.Fatalf("Could not add tx: %v\n", err)
+		}
+	}
+
+	if _, err := pool.Add(txA1); err == nil {
+		t.Error("Duplicate tx was accepted.\n")
+	}
+
+	if _, err := pool.Add(txA3); err == nil {
+		t.Error("Per-sender limit was not enforced.\n")
+	}
+
+	//The pool is full, txB2 pays more than txA2 (lowest fee rate) and replaces it
+	evicted, err := pool.Add(txB2)
+	if err != nil || len(evicted) != 1 || evicted[0].Hash() != txA2.Hash() {
+		t.Errorf("Tx with the lowest fee rate was not evicted: %v %v\n", evicted, err)
+	}
+	if pool.Get(txA2.Hash()) != nil {
+		t.Error("Evicted tx is still in the mempool.\n")
+	}
+
+	//Per-sender count is updated on eviction, txB3 is the third tx of B though
+	if _, err := pool.Add(txB3); err == nil {
+		t.Error("Per-sender limit was not enforced.\n")
+	}
+
+	//Fee rate too low to evict anything
+	txLow, _ := protocol.ConstrFundsTx(0x01, 10, 1, 3, accAHash, accBHash, &PrivKeyA, nil, nil)
+	if _, err := pool.Add(txLow); err == nil {
+		t.Error("Tx with a too low fee rate was accepted into a full mempool.\n")
+	}
+
+	pool.Remove(txA1.Hash())
+
+	stats := pool.Stats()
+	if stats.Txs != 2 || stats.Bytes != 2*protocol.FUNDSTX_SIZE || stats.Added != 4 || stats.Evicted != 1 || stats.Removed != 1 || stats.Rejected != 3 {
+		t.Errorf("Wrong mempool stats: %+v\n", stats)
+	}
+}
+
+//Only the tx with the highest tx count of a sender is evicted, the others would be stuck without it.
+func TestMempoolEvictionOrder(t *testing.T) {
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	pool := NewMempool(2, 2*protocol.FUNDSTX_SIZE, 2)
+
+	txA1, _ := protocol.ConstrFundsTx(0x01, 10, 1, 0, accAHash, accBHash, &PrivKeyA, nil, nil)
+	txA2, _ := protocol.ConstrFundsTx(0x01, 10, 9, 1, accAHash, accBHash, &PrivKeyA, nil, nil)
+	txB1, _ := protocol.ConstrFundsTx(0x01, 10, 5, 0, accBHash, accAHash, &PrivKeyB, nil, nil)
+	txB2, _ := protocol.ConstrFundsTx(0x01, 10, 10, 1, accBHash, accAHash, &PrivKeyB, nil, nil)
+
+	for _, tx := range []*protocol.FundsTx{txA1, txA2} {
+		if _, err := pool.Add(tx); err != nil {
+			t.Fatalf("Could not add tx: %v\n", err)
+		}
+	}
+
+	//txA1 has the lowest fee rate but txA2 depends on it.
+	if _, err := pool.Add(txB1); err == nil {
+		t.Error("Tx was accepted by evicting a tx another tx depends on.\n")
+	}
+	if pool.Get(txA1.Hash()) == nil || pool.Get(txA2.Hash()) == nil {
+		t.Error("Txs were evicted although the new tx was rejected.\n")
+	}
+
+	evicted, err := pool.Add(txB2)
+	if err != nil || len(evicted) != 1 || evicted[0].Hash() != txA2.Hash() {
+		t.Errorf("Tx with the highest tx count was not evicted: %v %v\n", evicted, err)
+	}
+
+	//txA1 is the highest tx count of A now.
+	evicted, err = pool.Add(txB1)
+	if err != nil || len(evicted) != 1 || evicted[0].Hash() != txA1.Hash() {
+		t.Errorf("Remaining tx of the sender was not evicted: %v %v\n", evicted, err)
+	}
+}
+
+func TestLowerFeeRate(t *testing.T) {
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	//The product of the high fee and the size does not fit into 64 bits.
+	low, _ := protocol.ConstrFundsTx(0x01, 10, 1, 0, accAHash, accBHash, &PrivKeyA, nil, nil)
+	high, _ := protocol.ConstrFundsTx(0x01, 10, math.MaxUint64/protocol.FUNDSTX_SIZE+1, 0, accBHash, accAHash, &PrivKeyB, nil, nil)
+
+	if !lowerFeeRate(low, high) || lowerFeeRate(high, low) || lowerFeeRate(high, high) {
+		t.Error("Fee rates with large fees compared wrongly.\n")
+	}
+}
+
+func TestMempoolQueues(t *testing.T) {
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	pool := NewMempool(MEMPOOL_MAX_TXS, MEMPOOL_MAX_BYTES, MEMPOOL_MAX_TXS_PER_SENDER)
+
+	tx0, _ := protocol.ConstrFundsTx(0x01, 10, 5, 0, accAHash, accBHash, &PrivKeyA, nil, nil)
+	tx1, _ := protocol.ConstrFundsTx(0x01, 10, 5, 1, accAHash, accBHash, &PrivKeyA, nil, nil)
+	tx2, _ := protocol.ConstrFundsTx(0x01, 10, 5, 2, accAHash, accBHash, &PrivKeyA, nil, nil)
+	tx4, _ := protocol.ConstrFundsTx(0x01, 10, 5, 4, accAHash, accBHash, &PrivKeyA, nil, nil)
+	for _, tx := range []*protocol.FundsTx{tx0, tx1, tx2, tx4} {
+		if _, err := pool.Add(tx); err != nil {
+			t.Fatalf("Could not add tx: %v\n", err)
+		}
+	}
+
+	//Replacement needs a higher fee
+	txSameFee, _ := protocol.ConstrFundsTx(0x01, 20, 5, 2, accAHash, accBHash, &PrivKeyA, nil, nil)
+	if _, err := pool.Add(txSameFee); err == nil {
+		t.Error("Replacement with the same fee was accepted.\n")
+	}
+
+	tx2Replacement, _ := protocol.ConstrFundsTx(0x01, 10, 6, 2, accAHash, accBHash, &PrivKeyA, nil, nil)
+	replaced, err := pool.Add(tx2Replacement)
+	if err != nil || len(replaced) != 1 || replaced[0].Hash() != tx2.Hash() {
+		t.Errorf("Tx was not replaced: %v %v\n", replaced, err)
+	}
+	if pool.Get(tx2.Hash()) != nil {
+		t.Error("Replaced tx is still in the mempool.\n")
+	}
+
+	//State txCnt 1: tx0 is stale, tx1 and the replacement are pending, tx4 is queued because txCnt 3 is missing
+	txCnt := func(sender [32]byte) uint32 { return 1 }
+	pending, queued, stale := pool.FundsTxQueues(txCnt)
+	if len(pending) != 2 || pending[0].Hash() != tx1.Hash() || pending[1].Hash() != tx2Replacement.Hash() {
+		t.Errorf("Wrong pending txs: %v\n", pending)
+	}
+	if len(queued) != 1 || queued[0].Hash() != tx4.Hash() {
+		t.Errorf("Wrong queued txs: %v\n", queued)
+	}
+	if len(stale) != 1 || stale[0].Hash() != tx0.Hash() {
+		t.Errorf("Wrong stale txs: %v\n", stale)
+	}
+
+	//Filling the gap promotes tx4
+	tx3, _ := protocol.ConstrFundsTx(0x01, 10, 5, 3, accAHash, accBHash, &PrivKeyA, nil, nil)
+	pool.Add(tx3)
+	if pending, queued, _ = pool.FundsTxQueues(txCnt); len(pending) != 4 || len(queued) != 0 {
+		t.Errorf("Queued tx was not promoted: %v pending, %v queued\n", len(pending), len(queued))
+	}
+
+	if stats := pool.Stats(); stats.Txs != 5 || stats.Replaced != 1 || stats.Rejected != 1 {
+		t.Errorf("Wrong mempool stats: %+v\n", stats)
+	}
+}
+
+func TestMempoolQuery(t *testing.T) {
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	pool := NewMempool(MEMPOOL_MAX_TXS, MEMPOOL_MAX_BYTES, MEMPOOL_MAX_TXS_PER_SENDER)
+
+	txA1, _ := protocol.ConstrFundsTx(0x01, 10, 1, 0, accAHash, accBHash, &PrivKeyA, nil, nil)
+	txA2, _ := protocol.ConstrFundsTx(0x01, 10, 3, 1, accAHash, accBHash, &PrivKeyA, nil, nil)
+	txA3, _ := protocol.ConstrFundsTx(0x01, 10, 8, 2, accAHash, accBHash, &PrivKeyA, nil, nil)
+	txB1, _ := protocol.ConstrFundsTx(0x01, 10, 5, 0, accBHash, accAHash, &PrivKeyB, nil, nil)
+	for _, tx := range []*protocol.FundsTx{txA1, txA2, txA3, txB1} {
+		pool.Add(tx)
+	}
+
+	result := pool.Query(MempoolFilter{Sender: accAHash}, 2)
+	if result.PoolTxs != 4 || result.Count != 3 || result.Bytes != 3*protocol.FUNDSTX_SIZE {
+		t.Errorf("Wrong counts: %+v\n", result)
+	}
+	if result.MinFee != 1 || result.MaxFee != 8 || result.MedianFee != 3 || result.TotalFee != 12 {
+		t.Errorf("Wrong fee stats: %+v\n", result)
+	}
+
+	//Only the txs with the highest fee are returned
+	if len(result.FundsTxs) != 2 || result.FundsTxs[0].Hash() != txA3.Hash() || result.FundsTxs[1].Hash() != txA2.Hash() {
+		t.Errorf("Wrong txs: %v\n", result.FundsTxs)
+	}
+
+	decoded := result.Decode(result.Encode())
+	if decoded == nil || decoded.Count != result.Count || len(decoded.Txs()) != 2 || decoded.Txs()[0].Hash() != txA3.Hash() {
+		t.Errorf("Query result encoding failed: %+v\n", decoded)
+	}
+
+	if result := pool.Query(MempoolFilter{Sender: accBHash, Recipient: accAHash}, 10); result.Count != 1 || result.FundsTxs[0].Hash() != txB1.Hash() {
+		t.Errorf("Sender and recipient filter failed: %+v\n", result)
+	}
+
+	if result := pool.Query(MempoolFilter{Sender: accBHash, Recipient: accBHash}, 10); result.Count != 0 || len(result.Txs()) != 0 {
+		t.Errorf("Non-matching filter returned txs: %+v\n", result)
+	}
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sort"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+)
+
+//Filter of a mempool query. A zero hash matches all txs, if both hashes are set a tx has to match both.
+type MempoolFilter struct {
+	Sender    [32]byte
+	Recipient [32]byte
+}
+
+//Result of a mempool query. The counts and fee statistics cover all matching txs, the tx lists only contain the
+//MEMPOOL_QUERY_MAX_TXS matching txs with the highest fee.
+type MempoolQueryResult struct {
+	PoolTxs   uint32
+	PoolBytes uint64
+	Count     uint32
+	Bytes     uint64
+	MinFee    uint64
+	MaxFee    uint64
+	MedianFee uint64
+	TotalFee  uint64
+	FundsTxs  []*protocol.FundsTx
+	AccTxs    []*protocol.AccTx
+	ConfigTxs []*protocol.ConfigTx
+	StakeTxs  []*protocol.StakeTx
+}
+
+func (result *MempoolQueryResult) Encode() []byte {
+	if result == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(result)
+	return buffer.Bytes()
+}
+
+func (*MempoolQueryResult) Decode(encoded []byte) *MempoolQueryResult {
+	if encoded == nil {
+		return nil
+	}
+
+	var decoded MempoolQueryResult
+	buffer := bytes.NewBuffer(encoded)
+	if err := gob.NewDecoder(buffer).Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+//All txs of the result, the ones with the highest fee first.
+func (result *MempoolQueryResult) Txs() (txs []protocol.Transaction) {
+	for _, tx := range result.FundsTxs {
+		txs = append(txs, tx)
+	}
+	for _, tx := range result.AccTxs {
+		txs = append(txs, tx)
+	}
+	for _, tx := range result.ConfigTxs {
+		txs = append(txs, tx)
+	}
+	for _, tx := range result.StakeTxs {
+		txs = append(txs, tx)
+	}
+
+	sort.SliceStable(txs, func(i, j int) bool { return txs[i].TxFee() > txs[j].TxFee() })
+	return txs
+}
+
+//Recipient of a tx for mempool queries, the new account in case of an accTx.
+func txRecipient(transaction protocol.Transaction) (recipient [32]byte, ok bool) {
+	switch tx := transaction.(type) {
+	case *protocol.FundsTx:
+		return tx.To, true
+	case *protocol.AccTx:
+		return protocol.SerializeHashContent(tx.PubKey), true
+	}
+
+	return recipient, false
+}
+
+func (filter MempoolFilter) matches(tx protocol.Transaction) bool {
+	if filter.Sender != [32]byte{} {
+		if sender, ok := txSender(tx); !ok || sender != filter.Sender {
+			return false
+		}
+	}
+
+	if filter.Recipient != [32]byte{} {
+		if recipient, ok := txRecipient(tx); !ok || recipient != filter.Recipient {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (pool *Mempool) Query(filter MempoolFilter, maxTxs int) *MempoolQueryResult {
+	pool.mutex.RLock()
+	result := &MempoolQueryResult{PoolTxs: uint32(len(pool.txs)), PoolBytes: pool.bytes}
+	var matching []protocol.Transaction
+	for _, tx := range pool.txs {
+		if filter.matches(tx) {
+			matching = append(matching, tx)
+		}
+	}
+	pool.mutex.RUnlock()
+
+	if len(matching) == 0 {
+		return result
+	}
+
+	sort.Slice(matching, func(i, j int) bool { return matching[i].TxFee() > matching[j].TxFee() })
+
+	result.Count = uint32(len(matching))
+	result.MaxFee = matching[0].TxFee()
+	result.MinFee = matching[len(matching)-1].TxFee()
+	result.MedianFee = matching[len(matching)/2].TxFee()
+	for i, transaction := range matching {
+		result.Bytes += transaction.Size()
+		result.TotalFee += transaction.TxFee()
+
+		if i >= maxTxs {
+			continue
+		}
+
+		switch tx := transaction.(type) {
+		case *protocol.FundsTx:
+			result.FundsTxs = append(result.FundsTxs, tx)
+		case *protocol.AccTx:
+			result.AccTxs = append(result.AccTxs, tx)
+		case *protocol.ConfigTx:
+			result.ConfigTxs = append(result.ConfigTxs, tx)
+		case *protocol.StakeTx:
+			result.StakeTxs = append(result.StakeTxs, tx)
+		}
+	}
+
+	return result
+}
+
+func QueryMempool(filter MempoolFilter) *MempoolQueryResult {
+	return txMemPool.Query(filter, MEMPOOL_QUERY_MAX_TXS)
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+//Pruned nodes no longer have the tx payloads to replay the chain from genesis. Instead, the state is persisted
+//together with the block it belongs to, and only blocks above that block are validated at startup.
+type StateSnapshot struct {
+	BlockHash [32]byte
+	Height    uint32
+	State     map[[32]byte]*protocol.Account
+	RootKeys  [][32]byte
+	//Consensus bookkeeping of the miner package (system parameters, difficulty history etc.), opaque to storage.
+	MinerState []byte
+}
+
+func (snapshot *StateSnapshot) Encode() []byte {
+	if snapshot == nil {
+		return nil
+	}
+
+	buffer := new(bytes.Buffer)
+	gob.NewEncoder(buffer).Encode(snapshot)
+	return buffer.Bytes()
+}
+
+func (*StateSnapshot) Decode(encoded []byte) *StateSnapshot {
+	if encoded == nil {
+		return nil
+	}
+
+	var decoded StateSnapshot
+	buffer := bytes.NewBuffer(encoded)
+	if err := gob.NewDecoder(buffer).Decode(&decoded); err != nil {
+		return nil
+	}
+	return &decoded
+}
+
+func WriteStateSnapshot(snapshot *StateSnapshot) (err error) {
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("chainstate"))
+		err := b.Put([]byte("snapshot"), snapshot.Encode())
+		return err
+	})
+
+	return err
+}
+
+//Returns nil if no snapshot has been written yet (the node never pruned).
+func ReadStateSnapshot() (snapshot *StateSnapshot) {
+
+	var encoded []byte
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("chainstate"))
+		if v := b.Get([]byte("snapshot")); v != nil {
+			encoded = make([]byte, len(v))
+			copy(encoded, v)
+		}
+		return nil
+	})
+
+	return snapshot.Decode(encoded)
+}
+
+//Returns the height up to which (inclusive) block bodies and txs have been pruned, 0 if nothing has been pruned.
+func ReadPrunedHeight() (height uint32) {
+
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("chainstate"))
+		if v := b.Get([]byte("prunedheight")); len(v) == 4 {
+			height = binary.BigEndian.Uint32(v)
+		}
+		return nil
+	})
+
+	return height
+}
+
+//Replaces the closed block with its header (tx hashes are dropped) and deletes all its closed txs. The height index
+//is kept such that headers can still be looked up. The pruned height is advanced in the same bolt transaction.
+func PruneBlock(block *protocol.Block) (err error) {
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		txLocations := tx.Bucket([]byte("txlocations"))
+		for _, txHash := range blockTxHashes(block) {
+			for _, bucket := range []string{"closedfunds", "closedaccs", "closedconfigs", "closedstakes"} {
+				b := tx.Bucket([]byte(bucket))
+				encodedTx := b.Get(txHash[:])
+				if encodedTx == nil {
+					continue
+				}
+
+				if transaction := decodeClosedTx(bucket, encodedTx); transaction != nil {
+					if err := unindexTx(tx, transaction); err != nil {
+						return err
+					}
+				}
+				if err := b.Delete(txHash[:]); err != nil {
+					return err
+				}
+			}
+			if err := txLocations.Delete(txHash[:]); err != nil {
+				return err
+			}
+		}
+
+		header := *block
+		header.AccTxData = nil
+		header.FundsTxData = nil
+		header.ConfigTxData = nil
+		header.StakeTxData = nil
+		if err := tx.Bucket([]byte("closedblocks")).Put(block.Hash[:], header.Encode()); err != nil {
+			return err
+		}
+
+		return tx.Bucket([]byte("chainstate")).Put([]byte("prunedheight"), heightKey(block.Height))
+	})
+
+	return err
+}
+
+func decodeClosedTx(bucket string, encodedTx []byte) protocol.Transaction {
+	switch bucket {
+	case "closedfunds":
+		var fundsTx *protocol.FundsTx
+		return fundsTx.Decode(encodedTx)
+	case "closedaccs":
+		var accTx *protocol.AccTx
+		return accTx.Decode(encodedTx)
+	case "closedconfigs":
+		var configTx *protocol.ConfigTx
+		return configTx.Decode(encodedTx)
+	case "closedstakes":
+		var stakeTx *protocol.StakeTx
+		return stakeTx.Decode(encodedTx)
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"bytes"
+
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/boltdb/bolt"
 )
@@ -77,16 +79,13 @@ func ReadAllClosedBlocks() (allClosedBlocks []*protocol.Block) {
 
 func ReadOpenTx(hash [32]byte) (transaction protocol.Transaction) {
 
-	return txMemPool[hash]
+	return txMemPool.Get(hash)
 }
 
 //Needed for the miner to prepare a new block
 func ReadAllOpenTxs() (allOpenTxs []protocol.Transaction) {
 
-	for key := range txMemPool {
-		allOpenTxs = append(allOpenTxs, txMemPool[key])
-	}
-	return
+	return txMemPool.All()
 }
 
 //Personally I like it better to test (which tx type it is) here, and get returned the interface. Simplifies the code
@@ -134,3 +133,64 @@ func ReadClosedTx(hash [32]byte) (transaction protocol.Transaction) {
 	}
 	return nil
 }
+
+//Returns the closed block at the given height of the current chain, nil if there is none.
+func ReadClosedBlockByHeight(height uint32) (block *protocol.Block) {
+
+	var blockHash [32]byte
+	var found bool
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("blockheights"))
+		if hash := b.Get(heightKey(height)); hash != nil {
+			copy(blockHash[:], hash)
+			found = true
+		}
+		return nil
+	})
+
+	if !found {
+		return nil
+	}
+
+	return ReadClosedBlock(blockHash)
+}
+
+//Returns the block hash and position of a closed tx, nil if the tx is not part of a closed block.
+func ReadTxLocation(hash [32]byte) (location *TxLocation) {
+
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("txlocations"))
+		location = decodeTxLocation(b.Get(hash[:]))
+		return nil
+	})
+
+	return location
+}
+
+//Returns the hashes of all closed txs the account is involved in (as sender, receiver, issuer or new account).
+func ReadAccountTxHashes(accountHash [32]byte) (txHashes [][32]byte) {
+
+	db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte("accounttxs")).Cursor()
+		for k, _ := c.Seek(accountHash[:]); k != nil && bytes.HasPrefix(k, accountHash[:]); k, _ = c.Next() {
+			var txHash [32]byte
+			copy(txHash[:], k[32:64])
+			txHashes = append(txHashes, txHash)
+		}
+		return nil
+	})
+
+	return txHashes
+}
+
+//Returns the fundsTxs of the mempool split into pending, queued and stale txs according to the tx counts of the
+//current state (see Mempool.FundsTxQueues).
+func ReadFundsTxQueues() (pending, queued, stale []*protocol.FundsTx) {
+
+	return txMemPool.FundsTxQueues(func(sender [32]byte) uint32 {
+		if acc := State[sender]; acc != nil {
+			return acc.TxCnt
+		}
+		return 0
+	})
+}
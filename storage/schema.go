@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+//Version of the on-disk format. Every change of the bucket layout or the encoding of stored values needs to increase
+//the version and add a migration that upgrades databases of the previous version.
+const (
+	SCHEMA_VERSION = 3
+)
+
+//All buckets of the current schema. Missing buckets are created at startup.
+var buckets = []string{
+	"meta",
+	"openblocks",
+	"closedblocks",
+	"closedfunds",
+	"closedaccs",
+	"closedstakes",
+	"closedconfigs",
+	"lastclosedblock",
+	"blockheights",
+	"txlocations",
+	"accounttxs",
+	"chainstate",
+	"mempool",
+	"bannedpeers",
+}
+
+//Upgrades a database from version-1 to version. Each migration runs in its own bolt transaction together with the
+//version update, a failed migration leaves the database at the previous version.
+type migration struct {
+	version     uint32
+	description string
+	migrate     func(tx *bolt.Tx) error
+}
+
+var migrations = []migration{
+	{2, "Build height, tx location and account indexes", migrateIndexes},
+	{3, "Add mempool journal and ban list buckets", migrateNewBuckets},
+}
+
+//Databases created before schema versioning have no meta bucket. They are version 1 if they contain blocks.
+func initSchema() error {
+	var version uint32
+	err := db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte("closedblocks")) != nil && tx.Bucket([]byte("meta")) == nil {
+			version = 1
+		} else if meta := tx.Bucket([]byte("meta")); meta != nil {
+			version = readSchemaVersion(meta)
+		}
+
+		if version > SCHEMA_VERSION {
+			return errors.New(fmt.Sprintf("Database schema version %v is newer than the supported version %v.", version, SCHEMA_VERSION))
+		}
+
+		for _, bucket := range buckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+				return errors.New(fmt.Sprintf("Create bucket %v: %v", bucket, err))
+			}
+		}
+
+		//New database, nothing to migrate.
+		if version == 0 {
+			version = SCHEMA_VERSION
+			return writeSchemaVersion(tx, version)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return migrate(version)
+}
+
+func migrate(version uint32) error {
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+
+		logger.Infof("Migrating database to schema version %v: %v", m.version, m.description)
+		err := db.Update(func(tx *bolt.Tx) error {
+			if err := m.migrate(tx); err != nil {
+				return err
+			}
+			return writeSchemaVersion(tx, m.version)
+		})
+		if err != nil {
+			return errors.New(fmt.Sprintf("Migration to schema version %v failed: %v", m.version, err))
+		}
+	}
+
+	return nil
+}
+
+func ReadSchemaVersion() (version uint32) {
+	db.View(func(tx *bolt.Tx) error {
+		version = readSchemaVersion(tx.Bucket([]byte("meta")))
+		return nil
+	})
+
+	return version
+}
+
+func readSchemaVersion(meta *bolt.Bucket) uint32 {
+	if v := meta.Get([]byte("version")); len(v) == 4 {
+		return binary.BigEndian.Uint32(v)
+	}
+	return 0
+}
+
+func writeSchemaVersion(tx *bolt.Tx, version uint32) error {
+	var encoded [4]byte
+	binary.BigEndian.PutUint32(encoded[:], version)
+	return tx.Bucket([]byte("meta")).Put([]byte("version"), encoded[:])
+}
+
+//Version 2 introduced the secondary indexes. Only the blocks of the current chain are indexed by height, the txs are
+//indexed by the accounts they touch.
+func migrateIndexes(tx *bolt.Tx) error {
+	var block *protocol.Block
+	_, encodedBlock := tx.Bucket([]byte("lastclosedblock")).Cursor().First()
+	closedBlocks := tx.Bucket([]byte("closedblocks"))
+	for block = block.Decode(encodedBlock); block != nil; block = block.Decode(closedBlocks.Get(block.PrevHash[:])) {
+		if err := indexBlock(tx, block); err != nil {
+			return err
+		}
+		if block.Height == 0 {
+			break
+		}
+	}
+
+	for _, bucket := range []string{"closedfunds", "closedaccs", "closedconfigs", "closedstakes"} {
+		err := tx.Bucket([]byte(bucket)).ForEach(func(k, v []byte) error {
+			if transaction := decodeClosedTx(bucket, v); transaction != nil {
+				return indexTx(tx, transaction)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//Version 3 added the "mempool" and "bannedpeers" buckets. Missing buckets are created at startup, nothing needs to be
+//converted.
+func migrateNewBuckets(tx *bolt.Tx) error {
+	return nil
+}
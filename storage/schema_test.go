@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bazo-blockchain/bazo-miner/protocol"
+	"github.com/boltdb/bolt"
+)
+
+const (
+	LegacyDBFileName = "legacy.db"
+)
+
+//Tests whether a database without schema version gets migrated and newer databases are refused
+func TestMigrateLegacyDB(t *testing.T) {
+
+	testDB := db
+	defer func() {
+		db.Close()
+		os.Remove(LegacyDBFileName)
+		db = testDB
+	}()
+
+	var err error
+	if db, err = bolt.Open(LegacyDBFileName, 0600, &bolt.Options{Timeout: 5 * time.Second}); err != nil {
+		t.Fatalf("Could not open legacy database: %v\n", err)
+	}
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+	fundsTx, _ := protocol.ConstrFundsTx(0x01, 30, 1, 2, accAHash, accBHash, &PrivKeyA, nil, nil)
+
+	genesis := new(protocol.Block)
+	b := new(protocol.Block)
+	b.Hash = [32]byte{'l'}
+	b.Height = 1
+	b.FundsTxData = [][32]byte{fundsTx.Hash()}
+
+	//Bucket layout before schema versioning
+	db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range []string{"openblocks", "closedblocks", "closedfunds", "closedaccs", "closedstakes", "closedconfigs", "lastclosedblock"} {
+			tx.CreateBucket([]byte(bucket))
+		}
+		txHash := fundsTx.Hash()
+		tx.Bucket([]byte("closedfunds")).Put(txHash[:], fundsTx.Encode())
+		tx.Bucket([]byte("closedblocks")).Put(genesis.Hash[:], genesis.Encode())
+		tx.Bucket([]byte("closedblocks")).Put(b.Hash[:], b.Encode())
+		tx.Bucket([]byte("lastclosedblock")).Put(b.Hash[:], b.Encode())
+		return nil
+	})
+
+	if err := initSchema(); err != nil {
+		t.Fatalf("Could not migrate legacy database: %v\n", err)
+	}
+
+	if version := ReadSchemaVersion(); version != SCHEMA_VERSION {
+		t.Errorf("Schema version should be %v but is %v\n", SCHEMA_VERSION, version)
+	}
+
+	if block := ReadClosedBlockByHeight(1); block == nil || block.Hash != b.Hash {
+		t.Error("Migration did not index blocks by height.\n")
+	}
+
+	if location := ReadTxLocation(fundsTx.Hash()); location == nil || location.BlockHash != b.Hash {
+		t.Errorf("Migration did not index tx locations: %v\n", location)
+	}
+
+	if txHashes := ReadAccountTxHashes(accBHash); len(txHashes) != 1 || txHashes[0] != fundsTx.Hash() {
+		t.Errorf("Migration did not index accounts: %x\n", txHashes)
+	}
+
+	//Reopening a database of the current version must not fail
+	if err := initSchema(); err != nil {
+		t.Errorf("Could not open database of the current version: %v\n", err)
+	}
+
+	db.Update(func(tx *bolt.Tx) error {
+		return writeSchemaVersion(tx, SCHEMA_VERSION+1)
+	})
+
+	if err := initSchema(); err == nil {
+		t.Error("Database of a newer schema version was accepted.\n")
+	}
+}
@@ -1,20 +1,19 @@
 package storage
 
 import (
-	"fmt"
-	"log"
 	"time"
 
+	"github.com/bazo-blockchain/bazo-miner/logging"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 	"github.com/boltdb/bolt"
 )
 
 var (
 	db                 *bolt.DB
-	logger             *log.Logger
+	logger             = logging.New(logging.STORAGE)
 	State              = make(map[[32]byte]*protocol.Account)
 	RootKeys           = make(map[[32]byte]*protocol.Account)
-	txMemPool          = make(map[[32]byte]protocol.Transaction)
+	txMemPool          = NewMempool(MEMPOOL_MAX_TXS, MEMPOOL_MAX_BYTES, MEMPOOL_MAX_TXS_PER_SENDER)
 	AllClosedBlocksAsc []*protocol.Block
 	Bootstrap_Server   string
 )
@@ -26,12 +25,11 @@ const (
 //Entry function for the storage package
 func Init(dbname string, bootstrapIpport string) {
 	Bootstrap_Server = bootstrapIpport
-	logger = InitLogger()
 
 	var err error
 	db, err = bolt.Open(dbname, 0600, &bolt.Options{Timeout: 5 * time.Second})
 	if err != nil {
-		logger.Fatal(ERROR_MSG, err)
+		logger.Fatalf("%v%v", ERROR_MSG, err)
 	}
 
 	//Check if db file is empty for all non-bootstraping miners
@@ -54,57 +52,14 @@ func Init(dbname string, bootstrapIpport string) {
 	//	}
 	//}
 
-	db.Update(func(tx *bolt.Tx) error {
-		_, err = tx.CreateBucket([]byte("openblocks"))
-		if err != nil {
-			return fmt.Errorf(ERROR_MSG+"Create bucket: %s", err)
-		}
-		return nil
-	})
-	db.Update(func(tx *bolt.Tx) error {
-		_, err = tx.CreateBucket([]byte("closedblocks"))
-		if err != nil {
-			return fmt.Errorf(ERROR_MSG+"Create bucket: %s", err)
-		}
-		return nil
-	})
-	db.Update(func(tx *bolt.Tx) error {
-		_, err = tx.CreateBucket([]byte("closedfunds"))
-		if err != nil {
-			return fmt.Errorf(ERROR_MSG+"Create bucket: %s", err)
-		}
-		return nil
-	})
-	db.Update(func(tx *bolt.Tx) error {
-		_, err = tx.CreateBucket([]byte("closedaccs"))
-		if err != nil {
-			return fmt.Errorf(ERROR_MSG+"Create bucket: %s", err)
-		}
-		return nil
-	})
-	db.Update(func(tx *bolt.Tx) error {
-		_, err = tx.CreateBucket([]byte("closedstakes"))
-		if err != nil {
-			return fmt.Errorf(ERROR_MSG+"Create bucket: %s", err)
-		}
-		return nil
-	})
-	db.Update(func(tx *bolt.Tx) error {
-		_, err = tx.CreateBucket([]byte("closedconfigs"))
-		if err != nil {
-			return fmt.Errorf(ERROR_MSG+"Create bucket: %s", err)
-		}
-		return nil
-	})
-	db.Update(func(tx *bolt.Tx) error {
-		_, err = tx.CreateBucket([]byte("lastclosedblock"))
-		if err != nil {
-			return fmt.Errorf(ERROR_MSG+"Create bucket: %s", err)
-		}
-		return nil
-	})
+	if err = initSchema(); err != nil {
+		logger.Fatalf("%v%v", ERROR_MSG, err)
+	}
 }
 
 func TearDown() {
+	if err := FlushMempoolJournal(); err != nil {
+		logger.Errorf("Could not journal the mempool: %v", err)
+	}
 	db.Close()
 }
@@ -295,4 +295,166 @@ func TestReadWriteDeleteBlock(t *testing.T) {
 	if ReadLastClosedBlock() != nil {
 		t.Error("Failed to delete last closed block from storage.\n")
 	}
-}
\ No newline at end of file
+}
+
+//Secondary indexes are written and deleted together with closed blocks and txs
+func TestIndexes(t *testing.T) {
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	fundsTx, _ := protocol.ConstrFundsTx(0x01, 10, 1, 0, accAHash, accBHash, &PrivKeyA, nil, nil)
+	stakeTx, _ := protocol.ConstrStakeTx(0, 1, true, accAHash, &PrivKeyA, &CommitmentKeyA.PublicKey)
+
+	b := new(protocol.Block)
+	b.Hash = [32]byte{'i'}
+	b.Height = 42
+	b.FundsTxData = [][32]byte{fundsTx.Hash()}
+	b.StakeTxData = [][32]byte{stakeTx.Hash()}
+
+	WriteClosedTx(fundsTx)
+	WriteClosedTx(stakeTx)
+	WriteClosedBlock(b)
+
+	if block := ReadClosedBlockByHeight(42); block == nil || block.Hash != b.Hash {
+		t.Error("Failed to read block by height.\n")
+	}
+
+	location := ReadTxLocation(stakeTx.Hash())
+	if location == nil || location.BlockHash != b.Hash || location.Position != 1 {
+		t.Errorf("Failed to read tx location: %v\n", location)
+	}
+
+	if txHashes := ReadAccountTxHashes(accAHash); len(txHashes) != 2 {
+		t.Errorf("Account A should be involved in 2 txs, got %v\n", len(txHashes))
+	}
+
+	if txHashes := ReadAccountTxHashes(accBHash); len(txHashes) != 1 || txHashes[0] != fundsTx.Hash() {
+		t.Errorf("Account B should be involved in the fundsTx only, got %x\n", txHashes)
+	}
+
+	DeleteClosedBlock(b.Hash)
+	DeleteClosedTx(fundsTx)
+	DeleteClosedTx(stakeTx)
+
+	if ReadClosedBlockByHeight(42) != nil || ReadTxLocation(fundsTx.Hash()) != nil || ReadTxLocation(stakeTx.Hash()) != nil {
+		t.Error("Failed to delete block indexes.\n")
+	}
+
+	if len(ReadAccountTxHashes(accAHash)) != 0 || len(ReadAccountTxHashes(accBHash)) != 0 {
+		t.Error("Failed to delete account indexes.\n")
+	}
+}
+
+func TestPruneBlock(t *testing.T) {
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	fundsTx, _ := protocol.ConstrFundsTx(0x01, 20, 1, 1, accAHash, accBHash, &PrivKeyA, nil, nil)
+
+	b := new(protocol.Block)
+	b.Hash = [32]byte{'p'}
+	b.Height = 7
+	b.FundsTxData = [][32]byte{fundsTx.Hash()}
+
+	WriteClosedTx(fundsTx)
+	WriteClosedBlock(b)
+
+	if err := PruneBlock(b); err != nil {
+		t.Fatalf("Failed to prune block: %v\n", err)
+	}
+
+	if ReadClosedTx(fundsTx.Hash()) != nil || ReadTxLocation(fundsTx.Hash()) != nil {
+		t.Error("Failed to delete the txs of a pruned block.\n")
+	}
+
+	if len(ReadAccountTxHashes(accAHash)) != 0 {
+		t.Error("Failed to delete the account indexes of a pruned block.\n")
+	}
+
+	if header := ReadClosedBlockByHeight(7); header == nil || header.Hash != b.Hash || len(header.FundsTxData) != 0 {
+		t.Errorf("Failed to keep the header of a pruned block: %v\n", header)
+	}
+
+	if ReadPrunedHeight() != 7 {
+		t.Errorf("Pruned height should be 7 but is %v\n", ReadPrunedHeight())
+	}
+
+	snapshot := &StateSnapshot{
+		BlockHash: b.Hash,
+		Height:    b.Height,
+		State:     map[[32]byte]*protocol.Account{accAHash: accA},
+		RootKeys:  [][32]byte{accAHash},
+	}
+	WriteStateSnapshot(snapshot)
+
+	if read := ReadStateSnapshot(); read == nil || read.BlockHash != b.Hash || read.State[accAHash].Balance != accA.Balance {
+		t.Errorf("Failed to read state snapshot: %v\n", read)
+	}
+
+	DeleteChainState()
+	if ReadStateSnapshot() != nil || ReadPrunedHeight() != 0 {
+		t.Error("Failed to delete the chain state.\n")
+	}
+
+	DeleteClosedBlock(b.Hash)
+	DeleteAll()
+}
+
+func TestMempoolJournal(t *testing.T) {
+
+	DeleteAll()
+
+	accAHash := protocol.SerializeHashContent(accA.Address)
+	accBHash := protocol.SerializeHashContent(accB.Address)
+
+	fundsTx, _ := protocol.ConstrFundsTx(0x01, 50, 1, 4, accAHash, accBHash, &PrivKeyA, nil, nil)
+	stakeTx, _ := protocol.ConstrStakeTx(0, 2, true, accAHash, &PrivKeyA, &CommitmentKeyA.PublicKey)
+
+	WriteOpenTx(fundsTx)
+	WriteOpenTx(stakeTx)
+	DeleteOpenTx(stakeTx)
+
+	//Changes are journaled in batches.
+	if len(ReadJournaledTxs()) != 0 {
+		t.Error("Changes were journaled before the batch was flushed.\n")
+	}
+	if err := FlushMempoolJournal(); err != nil {
+		t.Fatalf("Could not flush the journal: %v\n", err)
+	}
+
+	//Simulate a restart, only the journal is left
+	txMemPool.Clear()
+
+	txs := ReadJournaledTxs()
+	if len(txs) != 1 || txs[0].Hash() != fundsTx.Hash() {
+		t.Errorf("Journal should only contain the fundsTx, got %v\n", txs)
+	}
+
+	if ReadOpenTx(fundsTx.Hash()) != nil {
+		t.Error("Journaled txs must not be added to the mempool before they are checked.\n")
+	}
+
+	DeleteAll()
+	if len(ReadJournaledTxs()) != 0 {
+		t.Error("Failed to clear the journal.\n")
+	}
+}
+
+func TestBannedPeers(t *testing.T) {
+
+	WriteBannedPeer("127.0.0.1", 0)
+	WriteBannedPeer("8b2a", 1500000000)
+
+	bans := ReadBannedPeers()
+	if len(bans) != 2 || bans["127.0.0.1"] != 0 || bans["8b2a"] != 1500000000 {
+		t.Errorf("Wrong bans read: %v\n", bans)
+	}
+
+	DeleteBannedPeer("127.0.0.1")
+	DeleteBannedPeer("8b2a")
+	if bans := ReadBannedPeers(); len(bans) != 0 {
+		t.Errorf("Bans not deleted: %v\n", bans)
+	}
+}
@@ -20,8 +20,10 @@ func WriteClosedBlock(block *protocol.Block) (err error) {
 
 	err = db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte("closedblocks"))
-		err := b.Put(block.Hash[:], block.Encode())
-		return err
+		if err := b.Put(block.Hash[:], block.Encode()); err != nil {
+			return err
+		}
+		return indexBlock(tx, block)
 	})
 
 	return err
@@ -39,9 +41,17 @@ func WriteLastClosedBlock(block *protocol.Block) (err error) {
 }
 
 //Changing the "tx" shortcut here and using "transaction" to distinguish between bolt's transactions
-func WriteOpenTx(transaction protocol.Transaction) {
+//Returns an error if the tx is rejected by the mempool (already known, limits exceeded).
+func WriteOpenTx(transaction protocol.Transaction) error {
 
-	txMemPool[transaction.Hash()] = transaction
+	if _, err := txMemPool.Add(transaction); err != nil {
+		return err
+	}
+	scheduleJournalFlush()
+
+	Publish(Event{Type: EVENT_TX, Txs: []protocol.Transaction{transaction}})
+
+	return nil
 }
 
 func WriteClosedTx(transaction protocol.Transaction) (err error) {
@@ -61,8 +71,10 @@ func WriteClosedTx(transaction protocol.Transaction) (err error) {
 	hash := transaction.Hash()
 	err = db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucket))
-		err := b.Put(hash[:], transaction.Encode())
-		return err
+		if err := b.Put(hash[:], transaction.Encode()); err != nil {
+			return err
+		}
+		return indexTx(tx, transaction)
 	})
 
 	return err
@@ -3,7 +3,6 @@ package vm
 import (
 	"bytes"
 	"errors"
-	"log"
 )
 
 type Map []byte
@@ -38,7 +37,7 @@ func (m *Map) setSize(ba []byte) {
 func (m *Map) IncrementSize() {
 	s, err := m.getSize()
 	if err != nil {
-		log.Fatal("could not increment size")
+		logger.Fatalf("could not increment size: %v", err)
 	}
 	s++
 	m.setSize(UInt16ToByteArray(s))
@@ -47,7 +46,7 @@ func (m *Map) IncrementSize() {
 func (m *Map) DecrementSize() error {
 	s, err := m.getSize()
 	if err != nil {
-		log.Fatal("could not decrement size")
+		logger.Fatalf("could not decrement size: %v", err)
 	}
 
 	if s <= 0 {
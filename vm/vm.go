@@ -8,11 +8,14 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/bazo-blockchain/bazo-miner/logging"
 	"github.com/bazo-blockchain/bazo-miner/protocol"
 
 	"golang.org/x/crypto/sha3"
 )
 
+var logger = logging.New(logging.VM)
+
 type Context interface {
 	GetContract() []byte
 	GetContractVariable(index int) ([]byte, error)
@@ -58,7 +61,7 @@ func NewTestVM(byteCode []byte) VM {
 	}
 }
 
-// Private function, that can be activated by Exec call, useful for debugging
+// Private function, that can be activated by Exec call, useful for debugging. The trace is logged at debug level.
 func (vm *VM) trace() {
 	stack := vm.evaluationStack
 	addr := vm.pc
@@ -70,6 +73,10 @@ func (vm *VM) trace() {
 	}
 	opCode := OpCodes[byteCode]
 
+	if !logger.Enabled(logging.DEBUG) {
+		return
+	}
+
 	var args []byte
 	var formattedArgs string
 	var counter int
@@ -116,10 +123,7 @@ func (vm *VM) trace() {
 		reversedStack[maxIndex-i] = stack.Stack[i]
 	}
 
-	fmt.Printf("\t  Stack: %v \n", reversedStack)
-	fmt.Printf("\t  %v of max. %v Bytes in use \n", stack.memoryUsage, stack.memoryMax)
-	fmt.Printf("⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅⋅\n")
-	fmt.Printf("%04d: %-6s %v \n", addr, opCode.Name, formattedArgs)
+	logger.Debugf("Stack: %v, %v of max. %v Bytes in use\n%04d: %-6s %v", reversedStack, stack.memoryUsage, stack.memoryMax, addr, opCode.Name, formattedArgs)
 }
 
 func (vm *VM) Exec(trace bool) bool {